@@ -102,3 +102,54 @@ func TestCmdAddRejectsUnknownType(t *testing.T) {
 		t.Fatalf("expected no parts inserted on error, got %d", count)
 	}
 }
+
+func TestCreatePartWithIsAtomic(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+
+	donor, err := CreateDonor(db, "imprimante", "HP", "LaserJet 1020", "")
+	if err != nil {
+		t.Fatalf("create donor: %v", err)
+	}
+	value := 4.5
+	id, err := CreatePartWith(db, NewPart{
+		Name:      "Moteur pas à pas",
+		PropsJSON: `{"d_int":6.35}`,
+		Units:     map[string]PropUnit{"d_int": {Original: "1/4 inch", Unit: "mm"}},
+		Quantity:  2,
+		DonorID:   &donor.ID,
+		UnitValue: &value,
+		Currency:  "EUR",
+	})
+	if err != nil {
+		t.Fatalf("create part: %v", err)
+	}
+
+	meta, err := GetPartMeta(db, int(id))
+	if err != nil {
+		t.Fatalf("get part: %v", err)
+	}
+	if meta.PropUnits["d_int"].Original != "1/4 inch" || meta.DonorID.Int64 != int64(donor.ID) || meta.UnitValue.Float64 != 4.5 {
+		t.Fatalf("expected units, donor and value set at creation: %+v", meta)
+	}
+	entries, err := ListHistory(db, HistoryEntityPart, int(id))
+	if err != nil {
+		t.Fatalf("list history: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Action != "create" {
+		t.Fatalf("expected a single create entry, got %+v", entries)
+	}
+
+	// Appareil inconnu: aucune pièce créée
+	unknown := 999
+	if _, err := CreatePartWith(db, NewPart{Name: "Orpheline", PropsJSON: "{}", Quantity: 1, DonorID: &unknown}); err == nil {
+		t.Fatalf("expected unknown donor to be rejected")
+	}
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM parts").Scan(&count); err != nil {
+		t.Fatalf("count parts: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("expected no part inserted on error, got %d", count)
+	}
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/smtp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// noLocationLabel regroupe les alertes des pièces sans localisation
+const noLocationLabel = "(sans localisation)"
+
+// StockAlert représente une pièce dont le stock est passé sous son seuil minimum
+type StockAlert struct {
+	PartID   int    `json:"part_id"`
+	Type     string `json:"type"`
+	Name     string `json:"name"`
+	Quantity int    `json:"quantity"`
+	MinStock int    `json:"min_stock"`
+	Missing  int    `json:"missing"` // Quantité à réapprovisionner pour atteindre le seuil
+}
+
+// AlertGroup regroupe les alertes d'une même localisation
+type AlertGroup struct {
+	Location string       `json:"location"`
+	Alerts   []StockAlert `json:"alerts"`
+}
+
+// EffectiveMinStock retourne le seuil d'une pièce: le sien s'il est défini, sinon celui du template
+func EffectiveMinStock(typeName string, partMin sql.NullInt64) int {
+	if partMin.Valid {
+		return int(partMin.Int64)
+	}
+	if tmpl, ok := Templates.Get(typeName); ok {
+		return tmpl.MinStock
+	}
+	return 0
+}
+
+// SetPartMinStock définit le seuil minimum propre à une pièce (nil: revenir au seuil du template)
+func SetPartMinStock(db *sql.DB, partID int, minStock *int) error {
+	var value interface{}
+	if minStock != nil {
+		if *minStock < 0 {
+			return fmt.Errorf("seuil invalide: %d", *minStock)
+		}
+		value = *minStock
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := snapshotPart(tx, partID)
+	if err != nil {
+		return err
+	}
+	if before == nil || before.DeletedAt != nil {
+		return fmt.Errorf("pièce ID %d introuvable", partID)
+	}
+
+	if _, err := tx.Exec("UPDATE parts SET min_stock = ? WHERE id = ?", value, partID); err != nil {
+		return err
+	}
+
+	after, err := snapshotPart(tx, partID)
+	if err != nil {
+		return err
+	}
+	if err := recordHistory(tx, HistoryEntityPart, partID, "min_stock", before, after); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ListStockAlerts retourne les pièces sous leur seuil, regroupées par localisation (chemin complet)
+func ListStockAlerts(db *sql.DB) ([]AlertGroup, error) {
+	rows, err := db.Query(`
+		SELECT id, type, name, quantity, location_id, min_stock
+		FROM parts
+		WHERE deleted_at IS NULL
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	type pending struct {
+		alert      StockAlert
+		locationID sql.NullInt64
+	}
+	var found []pending
+	for rows.Next() {
+		var a StockAlert
+		var locationID, minStock sql.NullInt64
+		if err := rows.Scan(&a.PartID, &a.Type, &a.Name, &a.Quantity, &locationID, &minStock); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		a.MinStock = EffectiveMinStock(a.Type, minStock)
+		if a.MinStock <= 0 || a.Quantity >= a.MinStock {
+			continue
+		}
+		a.Missing = a.MinStock - a.Quantity
+		found = append(found, pending{alert: a, locationID: locationID})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	byLocation := make(map[string][]StockAlert)
+	for _, p := range found {
+		path := noLocationLabel
+		if p.locationID.Valid {
+			if full, err := GetFullPath(db, int(p.locationID.Int64)); err == nil && full != "" {
+				path = full
+			}
+		}
+		byLocation[path] = append(byLocation[path], p.alert)
+	}
+
+	groups := make([]AlertGroup, 0, len(byLocation))
+	for path, alerts := range byLocation {
+		groups = append(groups, AlertGroup{Location: path, Alerts: alerts})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		// Les pièces sans localisation en dernier
+		if (groups[i].Location == noLocationLabel) != (groups[j].Location == noLocationLabel) {
+			return groups[j].Location == noLocationLabel
+		}
+		return groups[i].Location < groups[j].Location
+	})
+
+	return groups, nil
+}
+
+// countAlerts retourne le nombre total d'alertes
+func countAlerts(groups []AlertGroup) int {
+	n := 0
+	for _, g := range groups {
+		n += len(g.Alerts)
+	}
+	return n
+}
+
+// FormatAlerts produit le texte des alertes (console, log, e-mail)
+func FormatAlerts(groups []AlertGroup) string {
+	var b strings.Builder
+	for _, g := range groups {
+		fmt.Fprintf(&b, "📍 %s\n", g.Location)
+		for _, a := range g.Alerts {
+			fmt.Fprintf(&b, "  [%d] %s (%s): %d en stock, minimum %d → manque %d\n",
+				a.PartID, a.Name, displayTypeName(a.Type), a.Quantity, a.MinStock, a.Missing)
+		}
+	}
+	return b.String()
+}
+
+// PrintAlerts affiche les pièces à réapprovisionner
+func PrintAlerts(db *sql.DB) error {
+	groups, err := ListStockAlerts(db)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("\n🔔 Pièces sous le stock minimum:")
+	fmt.Println(strings.Repeat("─", 60))
+
+	if len(groups) == 0 {
+		fmt.Println("  Aucune alerte")
+		fmt.Println()
+		return nil
+	}
+
+	fmt.Print(FormatAlerts(groups))
+	fmt.Printf("\n%d pièce(s) à réapprovisionner\n\n", countAlerts(groups))
+	return nil
+}
+
+// Notifier envoie les alertes de stock vers une destination (log, webhook, e-mail)
+type Notifier interface {
+	Notify(groups []AlertGroup) error
+}
+
+// LogNotifier écrit les alertes dans le journal du serveur
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(groups []AlertGroup) error {
+	log.Printf("🔔 %d pièce(s) sous le stock minimum:\n%s", countAlerts(groups), FormatAlerts(groups))
+	return nil
+}
+
+// WebhookNotifier envoie les alertes en JSON (POST) vers une URL
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+func (n WebhookNotifier) Notify(groups []AlertGroup) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"generated_at": time.Now().UTC().Format(time.RFC3339),
+		"count":        countAlerts(groups),
+		"groups":       groups,
+	})
+	if err != nil {
+		return err
+	}
+
+	client := n.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Post(n.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s: statut %s", n.URL, resp.Status)
+	}
+	return nil
+}
+
+// SMTPNotifier envoie les alertes par e-mail via un relais SMTP (sans authentification)
+type SMTPNotifier struct {
+	Addr string // host:port du relais, ex: localhost:25
+	From string
+	To   []string
+}
+
+func (n SMTPNotifier) Notify(groups []AlertGroup) error {
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", n.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(n.To, ", "))
+	fmt.Fprintf(&msg, "Subject: [recycle] %d piece(s) sous le stock minimum\r\n", countAlerts(groups))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(FormatAlerts(groups), "\n", "\r\n"))
+
+	return smtp.SendMail(n.Addr, nil, n.From, n.To, []byte(msg.String()))
+}
+
+// NotifierConfig décrit le notifieur choisi pour le mode serveur
+type NotifierConfig struct {
+	Kind       string // log, webhook ou smtp
+	WebhookURL string
+	SMTPAddr   string
+	SMTPFrom   string
+	SMTPTo     []string
+}
+
+// NewNotifier construit un notifieur à partir de sa configuration
+func NewNotifier(cfg NotifierConfig) (Notifier, error) {
+	switch cfg.Kind {
+	case "", "log":
+		return LogNotifier{}, nil
+	case "webhook":
+		if cfg.WebhookURL == "" {
+			return nil, fmt.Errorf("URL du webhook requise (--notify-url)")
+		}
+		return WebhookNotifier{URL: cfg.WebhookURL}, nil
+	case "smtp":
+		if cfg.SMTPAddr == "" || cfg.SMTPFrom == "" || len(cfg.SMTPTo) == 0 {
+			return nil, fmt.Errorf("relais, expéditeur et destinataire(s) requis (--smtp-addr, --smtp-from, --smtp-to)")
+		}
+		return SMTPNotifier{Addr: cfg.SMTPAddr, From: cfg.SMTPFrom, To: cfg.SMTPTo}, nil
+	default:
+		return nil, fmt.Errorf("notifieur inconnu: %s (log|webhook|smtp)", cfg.Kind)
+	}
+}
+
+// checkStockAlerts envoie au notifieur les alertes nouvelles ou aggravées depuis le dernier envoi.
+// notified garde, par pièce, la quantité manquante déjà notifiée; il est mis à jour après un envoi réussi
+// (une pièce revenue au-dessus de son seuil en sort, et sera à nouveau notifiée si elle redescend).
+func checkStockAlerts(db *sql.DB, notifier Notifier, notified map[int]int) error {
+	groups, err := ListStockAlerts(db)
+	if err != nil {
+		return err
+	}
+
+	current := make(map[int]int)
+	var fresh []AlertGroup
+	for _, g := range groups {
+		var alerts []StockAlert
+		for _, a := range g.Alerts {
+			current[a.PartID] = a.Missing
+			if previous, ok := notified[a.PartID]; !ok || a.Missing > previous {
+				alerts = append(alerts, a)
+			}
+		}
+		if len(alerts) > 0 {
+			fresh = append(fresh, AlertGroup{Location: g.Location, Alerts: alerts})
+		}
+	}
+
+	if len(fresh) > 0 {
+		if err := notifier.Notify(fresh); err != nil {
+			return err
+		}
+	}
+	for id := range notified {
+		delete(notified, id)
+	}
+	for id, missing := range current {
+		notified[id] = missing
+	}
+	return nil
+}
+
+// watchStockAlerts vérifie les seuils au démarrage puis à intervalle régulier
+func watchStockAlerts(db *sql.DB, notifier Notifier, every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+
+	notified := make(map[int]int)
+	for {
+		if err := checkStockAlerts(db, notifier, notified); err != nil {
+			log.Printf("Warning: alertes de stock: %v", err)
+		}
+		<-ticker.C
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestListStockAlerts(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+
+	seedTemplates()
+	bearing, _ := Templates.Get("bearing")
+	bearing.MinStock = 5
+
+	shelf, err := CreateLocation(db, "Etagere A", nil, "SHELF", "")
+	if err != nil {
+		t.Fatalf("create location: %v", err)
+	}
+	low, _ := CreatePart(db, "bearing", "Roulement 6204", `{"d_int":20,"d_ext":47,"width":14}`, &shelf.ID, 2)
+	CreatePart(db, "bearing", "Roulement 608", `{"d_int":8,"d_ext":22,"width":7}`, &shelf.ID, 8)
+	override, _ := CreatePart(db, "bearing", "Roulement 6000", `{"d_int":10,"d_ext":26,"width":8}`, nil, 2)
+	screws, _ := CreatePart(db, "", "Vis M3", "{}", nil, 3)
+
+	// Seuil propre: 0 désactive l'alerte du template, 10 en ajoute une sur une pièce sans template
+	zero, ten := 0, 10
+	if err := SetPartMinStock(db, int(override), &zero); err != nil {
+		t.Fatalf("set min stock: %v", err)
+	}
+	if err := SetPartMinStock(db, int(screws), &ten); err != nil {
+		t.Fatalf("set min stock: %v", err)
+	}
+
+	groups, err := ListStockAlerts(db)
+	if err != nil {
+		t.Fatalf("list alerts: %v", err)
+	}
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 location groups, got %+v", groups)
+	}
+	if groups[0].Location != "Etagere A" || len(groups[0].Alerts) != 1 || groups[0].Alerts[0].PartID != int(low) {
+		t.Fatalf("unexpected first group: %+v", groups[0])
+	}
+	if groups[0].Alerts[0].Missing != 3 {
+		t.Fatalf("expected 3 missing, got %d", groups[0].Alerts[0].Missing)
+	}
+	if groups[1].Location != noLocationLabel || groups[1].Alerts[0].PartID != int(screws) {
+		t.Fatalf("unexpected second group: %+v", groups[1])
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var received struct {
+		Count  int          `json:"count"`
+		Groups []AlertGroup `json:"groups"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+	}))
+	defer srv.Close()
+
+	notifier, err := NewNotifier(NotifierConfig{Kind: "webhook", WebhookURL: srv.URL})
+	if err != nil {
+		t.Fatalf("new notifier: %v", err)
+	}
+	groups := []AlertGroup{{Location: "Etagere A", Alerts: []StockAlert{{PartID: 1, Name: "Roulement", Quantity: 1, MinStock: 4, Missing: 3}}}}
+	if err := notifier.Notify(groups); err != nil {
+		t.Fatalf("notify: %v", err)
+	}
+	if received.Count != 1 || len(received.Groups) != 1 || received.Groups[0].Alerts[0].Missing != 3 {
+		t.Fatalf("unexpected webhook payload: %+v", received)
+	}
+
+	if _, err := NewNotifier(NotifierConfig{Kind: "pigeon"}); err == nil {
+		t.Fatalf("expected unknown notifier to fail")
+	}
+}
+
+// recordingNotifier garde les alertes reçues à chaque envoi
+type recordingNotifier struct {
+	sent [][]AlertGroup
+}
+
+func (n *recordingNotifier) Notify(groups []AlertGroup) error {
+	n.sent = append(n.sent, groups)
+	return nil
+}
+
+func TestCheckStockAlertsNotifiesOnlyNewOrWorse(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+
+	seedTemplates()
+	bearing, _ := Templates.Get("bearing")
+	bearing.MinStock = 5
+
+	low, _ := CreatePart(db, "bearing", "Roulement 6204", `{"d_int":20,"d_ext":47,"width":14}`, nil, 3)
+	notifier := &recordingNotifier{}
+	notified := map[int]int{}
+
+	check := func() {
+		t.Helper()
+		if err := checkStockAlerts(db, notifier, notified); err != nil {
+			t.Fatalf("check: %v", err)
+		}
+	}
+
+	check()
+	check() // Inchangée: pas de nouvel envoi
+	if len(notifier.sent) != 1 {
+		t.Fatalf("expected a single notification for an unchanged alert, got %d", len(notifier.sent))
+	}
+
+	if _, err := RecordStockMovement(db, int(low), MovementOut, 1, ""); err != nil {
+		t.Fatalf("out: %v", err)
+	}
+	other, _ := CreatePart(db, "bearing", "Roulement 608", `{"d_int":8,"d_ext":22,"width":7}`, nil, 8)
+	check()
+	if len(notifier.sent) != 2 || countAlerts(notifier.sent[1]) != 1 || notifier.sent[1][0].Alerts[0].Missing != 3 {
+		t.Fatalf("expected the worsened alert to be notified, got %+v", notifier.sent)
+	}
+
+	// Nouvelle pièce sous le seuil: seule elle est envoyée
+	if _, err := RecordStockMovement(db, int(other), MovementOut, 4, ""); err != nil {
+		t.Fatalf("out: %v", err)
+	}
+	check()
+	if len(notifier.sent) != 3 || countAlerts(notifier.sent[2]) != 1 || notifier.sent[2][0].Alerts[0].PartID != int(other) {
+		t.Fatalf("expected only the new alert to be notified, got %+v", notifier.sent[2:])
+	}
+}
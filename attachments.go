@@ -64,7 +64,7 @@ func EnsureAssetsDir() error {
 func AttachFile(db *sql.DB, partID int, sourcePath string) (*Attachment, error) {
 	// Vérifier que la pièce existe
 	var partName string
-	err := db.QueryRow("SELECT name FROM parts WHERE id = ?", partID).Scan(&partName)
+	err := db.QueryRow("SELECT name FROM parts WHERE id = ? AND deleted_at IS NULL", partID).Scan(&partName)
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("pièce ID %d introuvable", partID)
 	}
@@ -133,6 +133,11 @@ func AttachFile(db *sql.DB, partID int, sourcePath string) (*Attachment, error)
 
 	attachID, _ := result.LastInsertId()
 
+	after := map[string]interface{}{"attachment_id": attachID, "filename": originalName, "filepath": destPath}
+	if err := recordHistory(db, HistoryEntityPart, partID, "attach", nil, after); err != nil {
+		return nil, fmt.Errorf("erreur historique: %v", err)
+	}
+
 	return &Attachment{
 		ID:       int(attachID),
 		PartID:   partID,
@@ -217,8 +222,9 @@ func GetAttachmentsForParts(db *sql.DB, partIDs []int) (map[int][]Attachment, er
 // DeleteAttachment supprime un fichier attaché
 func DeleteAttachment(db *sql.DB, attachID int) error {
 	// Récupérer le chemin du fichier
-	var filepath string
-	err := db.QueryRow("SELECT filepath FROM attachments WHERE id = ?", attachID).Scan(&filepath)
+	var filepath, filename string
+	var partID int
+	err := db.QueryRow("SELECT part_id, filename, filepath FROM attachments WHERE id = ?", attachID).Scan(&partID, &filename, &filepath)
 	if err == sql.ErrNoRows {
 		return fmt.Errorf("attachement ID %d introuvable", attachID)
 	}
@@ -230,15 +236,19 @@ func DeleteAttachment(db *sql.DB, attachID int) error {
 	os.Remove(filepath) // Ignorer l'erreur si le fichier n'existe plus
 
 	// Supprimer de la base
-	_, err = db.Exec("DELETE FROM attachments WHERE id = ?", attachID)
-	return err
+	if _, err := db.Exec("DELETE FROM attachments WHERE id = ?", attachID); err != nil {
+		return err
+	}
+
+	before := map[string]interface{}{"attachment_id": attachID, "filename": filename, "filepath": filepath}
+	return recordHistory(db, HistoryEntityPart, partID, "detach", before, nil)
 }
 
 // ListPartAttachments affiche les fichiers attachés à une pièce
 func ListPartAttachments(db *sql.DB, partID int) error {
 	// Vérifier que la pièce existe
 	var partName string
-	err := db.QueryRow("SELECT name FROM parts WHERE id = ?", partID).Scan(&partName)
+	err := db.QueryRow("SELECT name FROM parts WHERE id = ? AND deleted_at IS NULL", partID).Scan(&partName)
 	if err == sql.ErrNoRows {
 		return fmt.Errorf("pièce ID %d introuvable", partID)
 	}
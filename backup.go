@@ -3,6 +3,7 @@ package main
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -11,11 +12,18 @@ import (
 
 // BackupData représente la structure complète d'un backup
 type BackupData struct {
-	Version     string            `json:"version"`
-	GeneratedAt string            `json:"generated_at"`
-	Locations   []BackupLocation  `json:"locations"`
-	Parts       []BackupPart      `json:"parts"`
+	Version     string             `json:"version"`
+	GeneratedAt string             `json:"generated_at"`
+	Locations   []BackupLocation   `json:"locations"`
+	Donors      []BackupDonor      `json:"donors,omitempty"`
+	Templates   []BackupTemplate   `json:"templates,omitempty"`
+	Parts       []BackupPart       `json:"parts"`
 	Attachments []BackupAttachment `json:"attachments"`
+	Components  []BackupComponent  `json:"part_components,omitempty"`
+	Redirects   []BackupRedirect   `json:"part_redirects,omitempty"`
+	Loans       []BackupLoan       `json:"loans,omitempty"`
+	Movements   []BackupMovement   `json:"stock_movements,omitempty"`
+	History     []BackupHistory    `json:"history,omitempty"`
 }
 
 // BackupLocation représente une localisation dans le backup
@@ -30,12 +38,22 @@ type BackupLocation struct {
 
 // BackupPart représente une pièce dans le backup
 type BackupPart struct {
-	ID         int                    `json:"id"`
-	Type       string                 `json:"type"`
-	Name       string                 `json:"name"`
-	Props      map[string]interface{} `json:"props"`
-	LocationID *int                   `json:"location_id,omitempty"`
-	CreatedAt  string                 `json:"created_at"`
+	ID              int                    `json:"id"`
+	Type            string                 `json:"type"`
+	Name            string                 `json:"name"`
+	Props           map[string]interface{} `json:"props"`
+	PropUnits       map[string]PropUnit    `json:"props_units,omitempty"` // Unité de base et saisie d'origine des props
+	LocationID      *int                   `json:"location_id,omitempty"`
+	Quantity        *int                   `json:"quantity,omitempty"`
+	State           string                 `json:"state,omitempty"`
+	DonorID         *int                   `json:"donor_id,omitempty"`
+	MinStock        *int                   `json:"min_stock,omitempty"`
+	UnitValue       *float64               `json:"unit_value,omitempty"`
+	Currency        *string                `json:"currency,omitempty"`
+	TemplateVersion int                    `json:"template_version,omitempty"` // Version du template des props (absente: 1)
+	Tags            []string               `json:"tags,omitempty"`
+	DeletedAt       *string                `json:"deleted_at,omitempty"`
+	CreatedAt       string                 `json:"created_at"`
 }
 
 // BackupAttachment représente un fichier attaché dans le backup
@@ -49,6 +67,73 @@ type BackupAttachment struct {
 	CreatedAt string `json:"created_at"`
 }
 
+// BackupMovement représente un mouvement de stock dans le backup
+type BackupMovement struct {
+	ID        int    `json:"id"`
+	PartID    int    `json:"part_id"`
+	Kind      string `json:"kind"`
+	Delta     int    `json:"delta"`
+	Balance   int    `json:"balance"`
+	Reason    string `json:"reason"`
+	CreatedAt string `json:"created_at"`
+}
+
+// BackupDonor représente un appareil donneur dans le backup
+type BackupDonor struct {
+	ID        int    `json:"id"`
+	Category  string `json:"category"`
+	Brand     string `json:"brand"`
+	Model     string `json:"model"`
+	Notes     string `json:"notes"`
+	CreatedAt string `json:"created_at"`
+}
+
+// BackupTemplate représente un template enregistré en base dans le backup
+type BackupTemplate struct {
+	Name       string `json:"name"`
+	Definition string `json:"definition"`
+	CreatedAt  string `json:"created_at"`
+	UpdatedAt  string `json:"updated_at"`
+}
+
+// BackupComponent représente un lien de nomenclature dans le backup
+type BackupComponent struct {
+	ParentID int `json:"parent_id"`
+	ChildID  int `json:"child_id"`
+	Quantity int `json:"quantity"`
+}
+
+// BackupRedirect représente la redirection d'un ID fusionné dans le backup
+type BackupRedirect struct {
+	OldID     int    `json:"old_id"`
+	NewID     int    `json:"new_id"`
+	CreatedAt string `json:"created_at"`
+}
+
+// BackupLoan représente un prêt (en cours ou rendu) dans le backup
+type BackupLoan struct {
+	ID         int     `json:"id"`
+	PartID     int     `json:"part_id"`
+	Borrower   string  `json:"borrower"`
+	Quantity   int     `json:"quantity"`
+	DueDate    string  `json:"due_date"`
+	Notes      string  `json:"notes"`
+	OutAt      string  `json:"out_at"`
+	ReturnedAt *string `json:"returned_at,omitempty"`
+}
+
+// BackupHistory représente une ligne d'historique dans le backup
+type BackupHistory struct {
+	ID        int             `json:"id"`
+	Entity    string          `json:"entity"`
+	EntityID  int             `json:"entity_id"`
+	Action    string          `json:"action"`
+	Before    json.RawMessage `json:"before,omitempty"`
+	After     json.RawMessage `json:"after,omitempty"`
+	Actor     string          `json:"actor"`
+	CreatedAt string          `json:"created_at"`
+}
+
 // CreateBackup crée un fichier de sauvegarde complet
 func CreateBackup(db *sql.DB, filename string) error {
 	fmt.Printf("📦 Création de la sauvegarde: %s\n", filename)
@@ -64,16 +149,56 @@ func CreateBackup(db *sql.DB, filename string) error {
 		return fmt.Errorf("erreur export locations: %v", err)
 	}
 
+	// Exporter les appareils donneurs
+	if err := exportDonors(db, &backup); err != nil {
+		return fmt.Errorf("erreur export donors: %v", err)
+	}
+
+	// Exporter les templates enregistrés en base
+	if err := exportTemplates(db, &backup); err != nil {
+		return fmt.Errorf("erreur export templates: %v", err)
+	}
+
 	// Exporter les pièces
 	if err := exportParts(db, &backup); err != nil {
 		return fmt.Errorf("erreur export parts: %v", err)
 	}
 
+	// Exporter les tags des pièces
+	if err := exportPartTags(db, &backup); err != nil {
+		return fmt.Errorf("erreur export tags: %v", err)
+	}
+
 	// Exporter les attachments
 	if err := exportAttachments(db, &backup); err != nil {
 		return fmt.Errorf("erreur export attachments: %v", err)
 	}
 
+	// Exporter la nomenclature
+	if err := exportComponents(db, &backup); err != nil {
+		return fmt.Errorf("erreur export part_components: %v", err)
+	}
+
+	// Exporter les redirections des pièces fusionnées
+	if err := exportRedirects(db, &backup); err != nil {
+		return fmt.Errorf("erreur export part_redirects: %v", err)
+	}
+
+	// Exporter les prêts
+	if err := exportLoans(db, &backup); err != nil {
+		return fmt.Errorf("erreur export loans: %v", err)
+	}
+
+	// Exporter le registre des mouvements de stock
+	if err := exportMovements(db, &backup); err != nil {
+		return fmt.Errorf("erreur export stock_movements: %v", err)
+	}
+
+	// Exporter l'historique
+	if err := exportHistory(db, &backup); err != nil {
+		return fmt.Errorf("erreur export history: %v", err)
+	}
+
 	// Écrire le fichier JSON
 	file, err := os.Create(filename)
 	if err != nil {
@@ -135,6 +260,16 @@ func RestoreFromBackup(db *sql.DB, filename string) error {
 		return fmt.Errorf("erreur restauration locations: %v", err)
 	}
 
+	// Restaurer les appareils donneurs
+	if err := restoreDonors(tx, backup.Donors); err != nil {
+		return fmt.Errorf("erreur restauration donors: %v", err)
+	}
+
+	// Restaurer les templates enregistrés en base
+	if err := restoreTemplates(tx, backup.Templates); err != nil {
+		return fmt.Errorf("erreur restauration templates: %v", err)
+	}
+
 	// Restaurer les pièces
 	if err := restoreParts(tx, backup.Parts); err != nil {
 		return fmt.Errorf("erreur restauration parts: %v", err)
@@ -145,11 +280,44 @@ func RestoreFromBackup(db *sql.DB, filename string) error {
 		return fmt.Errorf("erreur restauration attachments: %v", err)
 	}
 
+	// Restaurer la nomenclature
+	if err := restoreComponents(tx, backup.Components); err != nil {
+		return fmt.Errorf("erreur restauration part_components: %v", err)
+	}
+
+	// Restaurer les redirections des pièces fusionnées
+	if err := restoreRedirects(tx, backup.Redirects); err != nil {
+		return fmt.Errorf("erreur restauration part_redirects: %v", err)
+	}
+
+	// Restaurer les prêts
+	if err := restoreLoans(tx, backup.Loans); err != nil {
+		return fmt.Errorf("erreur restauration loans: %v", err)
+	}
+
+	// Restaurer le registre des mouvements de stock
+	if err := restoreMovements(tx, backup.Movements); err != nil {
+		return fmt.Errorf("erreur restauration stock_movements: %v", err)
+	}
+
+	// Restaurer l'historique
+	if err := restoreHistory(tx, backup.History); err != nil {
+		return fmt.Errorf("erreur restauration history: %v", err)
+	}
+
 	// Commit
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("erreur commit: %v", err)
 	}
 
+	// Les types des pièces restaurées viennent des templates restaurés
+	if err := Templates.Reload(db); err != nil {
+		if !errors.Is(err, ErrTemplatesSkipped) {
+			return fmt.Errorf("erreur rechargement templates: %v", err)
+		}
+		fmt.Printf("⚠️  %v\n", err)
+	}
+
 	fmt.Printf("✓ Restauration terminée avec succès\n")
 	return nil
 }
@@ -195,7 +363,7 @@ func exportLocations(db *sql.DB, backup *BackupData) error {
 // exportParts exporte toutes les pièces
 func exportParts(db *sql.DB, backup *BackupData) error {
 	rows, err := db.Query(`
-		SELECT p.id, p.type, p.name, p.props, p.location_id,
+		SELECT p.id, p.type, p.name, p.props, p.location_id, p.quantity, p.state, p.donor_id, p.min_stock, p.unit_value, p.currency, p.deleted_at, p.template_version, p.props_units,
 			   COALESCE(strftime('%Y-%m-%dT%H:%M:%fZ', p.rowid, 'unixepoch'), 'unknown') as created_at
 		FROM parts p
 		ORDER BY p.id
@@ -208,12 +376,18 @@ func exportParts(db *sql.DB, backup *BackupData) error {
 	for rows.Next() {
 		var part BackupPart
 		var propsJSON string
-		var locationID sql.NullInt64
+		var locationID, donorID, minStock sql.NullInt64
+		var quantity int
+		var unitValue sql.NullFloat64
+		var currency, deletedAt, propUnits sql.NullString
 		var createdAt string
 
-		if err := rows.Scan(&part.ID, &part.Type, &part.Name, &propsJSON, &locationID, &createdAt); err != nil {
+		if err := rows.Scan(&part.ID, &part.Type, &part.Name, &propsJSON, &locationID, &quantity, &part.State, &donorID, &minStock, &unitValue, &currency, &deletedAt, &part.TemplateVersion, &propUnits, &createdAt); err != nil {
 			return err
 		}
+		if units := decodePropUnits(propUnits); len(units) > 0 {
+			part.PropUnits = units
+		}
 
 		// Parser les propriétés JSON
 		if err := json.Unmarshal([]byte(propsJSON), &part.Props); err != nil {
@@ -225,6 +399,29 @@ func exportParts(db *sql.DB, backup *BackupData) error {
 			part.LocationID = &lid
 		}
 
+		if donorID.Valid {
+			did := int(donorID.Int64)
+			part.DonorID = &did
+		}
+
+		if minStock.Valid {
+			ms := int(minStock.Int64)
+			part.MinStock = &ms
+		}
+
+		if unitValue.Valid {
+			part.UnitValue = &unitValue.Float64
+		}
+
+		if currency.Valid {
+			part.Currency = &currency.String
+		}
+
+		if deletedAt.Valid {
+			part.DeletedAt = &deletedAt.String
+		}
+
+		part.Quantity = &quantity
 		part.CreatedAt = createdAt
 		backup.Parts = append(backup.Parts, part)
 	}
@@ -232,6 +429,38 @@ func exportParts(db *sql.DB, backup *BackupData) error {
 	return nil
 }
 
+// exportPartTags ajoute les tags de chaque pièce déjà exportée
+func exportPartTags(db *sql.DB, backup *BackupData) error {
+	rows, err := db.Query(`
+		SELECT pt.part_id, t.name
+		FROM part_tags pt
+		JOIN tags t ON t.id = pt.tag_id
+		ORDER BY pt.part_id, t.name
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	tagsByPart := make(map[int][]string)
+	for rows.Next() {
+		var partID int
+		var name string
+
+		if err := rows.Scan(&partID, &name); err != nil {
+			return err
+		}
+
+		tagsByPart[partID] = append(tagsByPart[partID], name)
+	}
+
+	for i := range backup.Parts {
+		backup.Parts[i].Tags = tagsByPart[backup.Parts[i].ID]
+	}
+
+	return nil
+}
+
 // exportAttachments exporte tous les fichiers attachés
 func exportAttachments(db *sql.DB, backup *BackupData) error {
 	rows, err := db.Query(`
@@ -257,9 +486,196 @@ func exportAttachments(db *sql.DB, backup *BackupData) error {
 	return nil
 }
 
+// exportDonors exporte les appareils donneurs
+func exportDonors(db *sql.DB, backup *BackupData) error {
+	rows, err := db.Query(`
+		SELECT id, category, brand, model, notes, created_at
+		FROM donors
+		ORDER BY id
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var d BackupDonor
+
+		if err := rows.Scan(&d.ID, &d.Category, &d.Brand, &d.Model, &d.Notes, &d.CreatedAt); err != nil {
+			return err
+		}
+
+		backup.Donors = append(backup.Donors, d)
+	}
+
+	return nil
+}
+
+// exportTemplates exporte les templates enregistrés en base
+func exportTemplates(db *sql.DB, backup *BackupData) error {
+	rows, err := db.Query(`
+		SELECT name, definition, created_at, updated_at
+		FROM templates
+		ORDER BY name
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tmpl BackupTemplate
+
+		if err := rows.Scan(&tmpl.Name, &tmpl.Definition, &tmpl.CreatedAt, &tmpl.UpdatedAt); err != nil {
+			return err
+		}
+
+		backup.Templates = append(backup.Templates, tmpl)
+	}
+
+	return nil
+}
+
+// exportComponents exporte les liens de nomenclature
+func exportComponents(db *sql.DB, backup *BackupData) error {
+	rows, err := db.Query(`
+		SELECT parent_id, child_id, quantity
+		FROM part_components
+		ORDER BY parent_id, child_id
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c BackupComponent
+
+		if err := rows.Scan(&c.ParentID, &c.ChildID, &c.Quantity); err != nil {
+			return err
+		}
+
+		backup.Components = append(backup.Components, c)
+	}
+
+	return nil
+}
+
+// exportRedirects exporte les redirections des pièces fusionnées
+func exportRedirects(db *sql.DB, backup *BackupData) error {
+	rows, err := db.Query(`
+		SELECT old_id, new_id, created_at
+		FROM part_redirects
+		ORDER BY old_id
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rd BackupRedirect
+
+		if err := rows.Scan(&rd.OldID, &rd.NewID, &rd.CreatedAt); err != nil {
+			return err
+		}
+
+		backup.Redirects = append(backup.Redirects, rd)
+	}
+
+	return nil
+}
+
+// exportLoans exporte les prêts
+func exportLoans(db *sql.DB, backup *BackupData) error {
+	rows, err := db.Query(`
+		SELECT id, part_id, borrower, quantity, due_date, notes, out_at, returned_at
+		FROM loans
+		ORDER BY id
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var l BackupLoan
+		var returnedAt sql.NullString
+
+		if err := rows.Scan(&l.ID, &l.PartID, &l.Borrower, &l.Quantity, &l.DueDate, &l.Notes, &l.OutAt, &returnedAt); err != nil {
+			return err
+		}
+		if returnedAt.Valid {
+			l.ReturnedAt = &returnedAt.String
+		}
+
+		backup.Loans = append(backup.Loans, l)
+	}
+
+	return nil
+}
+
+// exportMovements exporte le registre des mouvements de stock
+func exportMovements(db *sql.DB, backup *BackupData) error {
+	rows, err := db.Query(`
+		SELECT id, part_id, kind, delta, balance, reason, created_at
+		FROM stock_movements
+		ORDER BY id
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m BackupMovement
+
+		if err := rows.Scan(&m.ID, &m.PartID, &m.Kind, &m.Delta, &m.Balance, &m.Reason, &m.CreatedAt); err != nil {
+			return err
+		}
+
+		backup.Movements = append(backup.Movements, m)
+	}
+
+	return nil
+}
+
+// exportHistory exporte l'historique des modifications
+func exportHistory(db *sql.DB, backup *BackupData) error {
+	rows, err := db.Query(`
+		SELECT id, entity, entity_id, action, before, after, actor, created_at
+		FROM history
+		ORDER BY id
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var h BackupHistory
+		var before, after sql.NullString
+
+		if err := rows.Scan(&h.ID, &h.Entity, &h.EntityID, &h.Action, &before, &after, &h.Actor, &h.CreatedAt); err != nil {
+			return err
+		}
+
+		if before.Valid {
+			h.Before = json.RawMessage(before.String)
+		}
+		if after.Valid {
+			h.After = json.RawMessage(after.String)
+		}
+
+		backup.History = append(backup.History, h)
+	}
+
+	return nil
+}
+
 // cleanTables nettoie toutes les tables avant la restauration
 func cleanTables(tx *sql.Tx) error {
-	tables := []string{"attachments", "parts", "locations"}
+	tables := []string{"history", "stock_movements", "loans", "part_redirects", "part_components", "part_tags", "tags", "attachments", "parts", "templates", "donors", "locations"}
 
 	for _, table := range tables {
 		if _, err := tx.Exec("DELETE FROM " + table); err != nil {
@@ -309,14 +725,68 @@ func restoreParts(tx *sql.Tx, parts []BackupPart) error {
 			locationID = nil
 		}
 
+		// Les sauvegardes antérieures aux quantités comptent un objet par ligne
+		quantity := 1
+		if part.Quantity != nil {
+			quantity = *part.Quantity
+		}
+
+		state := part.State
+		if state == "" {
+			state = StateUntested
+		}
+
+		var donorID interface{}
+		if part.DonorID != nil {
+			donorID = *part.DonorID
+		}
+
+		var minStock interface{}
+		if part.MinStock != nil {
+			minStock = *part.MinStock
+		}
+
+		var deletedAt interface{}
+		if part.DeletedAt != nil {
+			deletedAt = *part.DeletedAt
+		}
+
+		// Les sauvegardes antérieures au versionnage des templates sont en version 1
+		templateVersion := part.TemplateVersion
+		if templateVersion == 0 {
+			templateVersion = 1
+		}
+
+		var propUnits interface{}
+		if len(part.PropUnits) > 0 {
+			data, err := json.Marshal(part.PropUnits)
+			if err != nil {
+				return fmt.Errorf("erreur sérialisation unités ID %d: %v", part.ID, err)
+			}
+			propUnits = string(data)
+		}
+
 		_, err = tx.Exec(`
-			INSERT INTO parts (id, type, name, props, location_id)
-			VALUES (?, ?, ?, ?, ?)
-		`, part.ID, part.Type, part.Name, string(propsJSON), locationID)
+			INSERT INTO parts (id, type, name, props, props_units, location_id, quantity, state, donor_id, min_stock, unit_value, currency, deleted_at, template_version)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, part.ID, part.Type, part.Name, string(propsJSON), propUnits, locationID, quantity, state, donorID, minStock, part.UnitValue, part.Currency, deletedAt, templateVersion)
 
 		if err != nil {
 			return fmt.Errorf("erreur restauration pièce %d: %v", part.ID, err)
 		}
+
+		for _, tag := range NormalizeTags(part.Tags) {
+			if _, err := tx.Exec("INSERT OR IGNORE INTO tags (name) VALUES (?)", tag); err != nil {
+				return fmt.Errorf("erreur restauration tag '%s': %v", tag, err)
+			}
+			_, err := tx.Exec(`
+				INSERT OR IGNORE INTO part_tags (part_id, tag_id)
+				SELECT ?, id FROM tags WHERE name = ?
+			`, part.ID, tag)
+			if err != nil {
+				return fmt.Errorf("erreur restauration tag '%s': %v", tag, err)
+			}
+		}
 	}
 
 	return nil
@@ -338,6 +808,126 @@ func restoreAttachments(tx *sql.Tx, attachments []BackupAttachment) error {
 	return nil
 }
 
+// restoreDonors restaure les appareils donneurs
+func restoreDonors(tx *sql.Tx, donors []BackupDonor) error {
+	for _, d := range donors {
+		_, err := tx.Exec(`
+			INSERT INTO donors (id, category, brand, model, notes, created_at)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, d.ID, d.Category, d.Brand, d.Model, d.Notes, d.CreatedAt)
+
+		if err != nil {
+			return fmt.Errorf("erreur restauration appareil %d: %v", d.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// restoreTemplates restaure les templates enregistrés en base
+func restoreTemplates(tx *sql.Tx, templates []BackupTemplate) error {
+	for _, tmpl := range templates {
+		_, err := tx.Exec(`
+			INSERT INTO templates (name, definition, created_at, updated_at)
+			VALUES (?, ?, ?, ?)
+		`, tmpl.Name, tmpl.Definition, tmpl.CreatedAt, tmpl.UpdatedAt)
+
+		if err != nil {
+			return fmt.Errorf("erreur restauration template %s: %v", tmpl.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// restoreComponents restaure les liens de nomenclature
+func restoreComponents(tx *sql.Tx, components []BackupComponent) error {
+	for _, c := range components {
+		_, err := tx.Exec(`
+			INSERT INTO part_components (parent_id, child_id, quantity)
+			VALUES (?, ?, ?)
+		`, c.ParentID, c.ChildID, c.Quantity)
+
+		if err != nil {
+			return fmt.Errorf("erreur restauration composant %d/%d: %v", c.ParentID, c.ChildID, err)
+		}
+	}
+
+	return nil
+}
+
+// restoreRedirects restaure les redirections des pièces fusionnées
+func restoreRedirects(tx *sql.Tx, redirects []BackupRedirect) error {
+	for _, rd := range redirects {
+		_, err := tx.Exec(`
+			INSERT INTO part_redirects (old_id, new_id, created_at)
+			VALUES (?, ?, ?)
+		`, rd.OldID, rd.NewID, rd.CreatedAt)
+
+		if err != nil {
+			return fmt.Errorf("erreur restauration redirection %d: %v", rd.OldID, err)
+		}
+	}
+
+	return nil
+}
+
+// restoreLoans restaure les prêts
+func restoreLoans(tx *sql.Tx, loans []BackupLoan) error {
+	for _, l := range loans {
+		_, err := tx.Exec(`
+			INSERT INTO loans (id, part_id, borrower, quantity, due_date, notes, out_at, returned_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`, l.ID, l.PartID, l.Borrower, l.Quantity, l.DueDate, l.Notes, l.OutAt, l.ReturnedAt)
+
+		if err != nil {
+			return fmt.Errorf("erreur restauration prêt %d: %v", l.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// restoreMovements restaure le registre des mouvements de stock
+func restoreMovements(tx *sql.Tx, movements []BackupMovement) error {
+	for _, m := range movements {
+		_, err := tx.Exec(`
+			INSERT INTO stock_movements (id, part_id, kind, delta, balance, reason, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, m.ID, m.PartID, m.Kind, m.Delta, m.Balance, m.Reason, m.CreatedAt)
+
+		if err != nil {
+			return fmt.Errorf("erreur restauration mouvement %d: %v", m.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// restoreHistory restaure l'historique des modifications
+func restoreHistory(tx *sql.Tx, history []BackupHistory) error {
+	for _, h := range history {
+		var before, after interface{}
+		if len(h.Before) > 0 {
+			before = string(h.Before)
+		}
+		if len(h.After) > 0 {
+			after = string(h.After)
+		}
+
+		_, err := tx.Exec(`
+			INSERT INTO history (id, entity, entity_id, action, before, after, actor, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`, h.ID, h.Entity, h.EntityID, h.Action, before, after, h.Actor, h.CreatedAt)
+
+		if err != nil {
+			return fmt.Errorf("erreur restauration historique %d: %v", h.ID, err)
+		}
+	}
+
+	return nil
+}
+
 // getFileSize retourne la taille d'un fichier
 func getFileSize(filename string) int64 {
 	info, err := os.Stat(filename)
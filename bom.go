@@ -0,0 +1,266 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// maxBOMDepth limite la profondeur de la nomenclature (protection contre les cycles)
+const maxBOMDepth = 50
+
+// BOMNode représente une pièce dans l'arbre de nomenclature (sous-ensemble)
+type BOMNode struct {
+	ID       int       `json:"id"`
+	Type     string    `json:"type"`
+	Name     string    `json:"name"`
+	State    string    `json:"state"`
+	Stock    int       `json:"stock"`    // Quantité en stock de la pièce
+	Quantity int       `json:"quantity"` // Nombre d'unités dans le parent
+	Children []BOMNode `json:"children"`
+}
+
+// componentLink est l'état d'un lien parent/enfant enregistré dans l'historique
+type componentLink struct {
+	ChildID  int `json:"child_id"`
+	Quantity int `json:"quantity"`
+}
+
+// AddComponent déclare qu'une pièce (parent) contient quantity unités d'une autre (enfant).
+// Si le lien existe déjà, sa quantité est remplacée.
+func AddComponent(db *sql.DB, parentID, childID, quantity int) error {
+	if parentID == childID {
+		return fmt.Errorf("une pièce ne peut pas se contenir elle-même")
+	}
+	if quantity <= 0 {
+		return fmt.Errorf("quantité invalide: %d", quantity)
+	}
+
+	for _, id := range []int{parentID, childID} {
+		meta, err := GetPartMeta(db, id)
+		if err != nil {
+			return err
+		}
+		if !meta.Found {
+			return fmt.Errorf("pièce ID %d introuvable", id)
+		}
+	}
+
+	// Refuser les cycles: le parent ne doit pas déjà être un descendant de l'enfant
+	descendants, err := ListDescendantIDs(db, childID)
+	if err != nil {
+		return err
+	}
+	for _, id := range descendants {
+		if id == parentID {
+			return fmt.Errorf("cycle détecté: la pièce ID %d contient déjà la pièce ID %d", childID, parentID)
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var before *componentLink
+	var current int
+	err = tx.QueryRow("SELECT quantity FROM part_components WHERE parent_id = ? AND child_id = ?", parentID, childID).Scan(&current)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if err == nil {
+		before = &componentLink{ChildID: childID, Quantity: current}
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO part_components (parent_id, child_id, quantity)
+		VALUES (?, ?, ?)
+		ON CONFLICT (parent_id, child_id) DO UPDATE SET quantity = excluded.quantity
+	`, parentID, childID, quantity)
+	if err != nil {
+		return err
+	}
+
+	var beforeValue interface{}
+	if before != nil {
+		beforeValue = before
+	}
+	after := &componentLink{ChildID: childID, Quantity: quantity}
+	if err := recordHistory(tx, HistoryEntityPart, parentID, "component_add", beforeValue, after); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RemoveComponent supprime le lien entre un parent et un enfant
+func RemoveComponent(db *sql.DB, parentID, childID int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var quantity int
+	err = tx.QueryRow("SELECT quantity FROM part_components WHERE parent_id = ? AND child_id = ?", parentID, childID).Scan(&quantity)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("la pièce ID %d ne contient pas la pièce ID %d", parentID, childID)
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM part_components WHERE parent_id = ? AND child_id = ?", parentID, childID); err != nil {
+		return err
+	}
+
+	before := &componentLink{ChildID: childID, Quantity: quantity}
+	if err := recordHistory(tx, HistoryEntityPart, parentID, "component_rm", before, nil); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ListDescendantIDs retourne les IDs de tous les sous-composants d'une pièce (hors corbeille).
+// Un composant à la corbeille coupe la branche: ses propres composants ne sont pas repris.
+func ListDescendantIDs(db *sql.DB, partID int) ([]int, error) {
+	rows, err := db.Query(`
+		WITH RECURSIVE tree(id, depth) AS (
+			SELECT pc.child_id, 1
+			FROM part_components pc
+			JOIN parts p ON p.id = pc.child_id AND p.deleted_at IS NULL
+			WHERE pc.parent_id = ?
+			UNION
+			SELECT pc.child_id, tree.depth + 1
+			FROM part_components pc
+			JOIN tree ON pc.parent_id = tree.id
+			JOIN parts p ON p.id = pc.child_id AND p.deleted_at IS NULL
+			WHERE tree.depth < ?
+		)
+		SELECT DISTINCT id
+		FROM tree
+		ORDER BY id
+	`, partID, maxBOMDepth)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// GetBOMTree construit l'arbre de nomenclature d'une pièce
+func GetBOMTree(db *sql.DB, partID int) (*BOMNode, error) {
+	meta, err := GetPartMeta(db, partID)
+	if err != nil {
+		return nil, err
+	}
+	if !meta.Found {
+		return nil, fmt.Errorf("pièce ID %d introuvable", partID)
+	}
+
+	root := &BOMNode{
+		ID:       meta.ID,
+		Type:     meta.Type,
+		Name:     meta.Name,
+		State:    meta.State,
+		Stock:    meta.Quantity,
+		Quantity: 1,
+	}
+	children, err := listBOMChildren(db, partID, 1)
+	if err != nil {
+		return nil, err
+	}
+	root.Children = children
+	return root, nil
+}
+
+// listBOMChildren retourne les enfants directs d'une pièce, avec leurs propres enfants
+func listBOMChildren(db *sql.DB, parentID, depth int) ([]BOMNode, error) {
+	children := []BOMNode{}
+	if depth > maxBOMDepth {
+		return children, nil
+	}
+
+	rows, err := db.Query(`
+		SELECT p.id, p.type, p.name, p.state, p.quantity, pc.quantity
+		FROM part_components pc
+		JOIN parts p ON p.id = pc.child_id AND p.deleted_at IS NULL
+		WHERE pc.parent_id = ?
+		ORDER BY p.id
+	`, parentID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var n BOMNode
+		if err := rows.Scan(&n.ID, &n.Type, &n.Name, &n.State, &n.Stock, &n.Quantity); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		children = append(children, n)
+	}
+	rows.Close()
+
+	for i := range children {
+		sub, err := listBOMChildren(db, children[i].ID, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		children[i].Children = sub
+	}
+
+	return children, nil
+}
+
+// PrintBOM affiche l'arbre de nomenclature d'une pièce
+func PrintBOM(db *sql.DB, partID int) error {
+	root, err := GetBOMTree(db, partID)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("\n🧩 Nomenclature:")
+	fmt.Println(strings.Repeat("─", 60))
+	fmt.Printf("%s [#%d] (%s, %s)\n", root.Name, root.ID, displayTypeName(root.Type), StateLabel(root.State))
+
+	if len(root.Children) == 0 {
+		fmt.Println("  Aucun sous-composant")
+		fmt.Println()
+		return nil
+	}
+
+	printBOMChildren(root.Children, "")
+	fmt.Println()
+	return nil
+}
+
+func printBOMChildren(children []BOMNode, prefix string) {
+	for i, c := range children {
+		branch, next := "├─ ", "│  "
+		if i == len(children)-1 {
+			branch, next = "└─ ", "   "
+		}
+		fmt.Printf("%s%s%d× %s [#%d] (%s, %s)\n", prefix, branch, c.Quantity, c.Name, c.ID, displayTypeName(c.Type), StateLabel(c.State))
+		printBOMChildren(c.Children, prefix+next)
+	}
+}
+
+// displayTypeName retourne le type à afficher (les pièces sans type sont signalées)
+func displayTypeName(typeName string) string {
+	if typeName == "" {
+		return "sans type"
+	}
+	return typeName
+}
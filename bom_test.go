@@ -0,0 +1,101 @@
+package main
+
+import "testing"
+
+func TestBOMTreeAndCycles(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+
+	fan, _ := CreatePart(db, "", "Module ventilateur", "{}", nil, 1)
+	motor, _ := CreatePart(db, "", "Moteur 12V", "{}", nil, 1)
+	bearing, _ := CreatePart(db, "", "Roulement 608", "{}", nil, 4)
+	brush, _ := CreatePart(db, "", "Balai carbone", "{}", nil, 2)
+
+	if err := AddComponent(db, int(fan), int(motor), 1); err != nil {
+		t.Fatalf("add motor: %v", err)
+	}
+	if err := AddComponent(db, int(fan), int(bearing), 2); err != nil {
+		t.Fatalf("add bearings: %v", err)
+	}
+	if err := AddComponent(db, int(motor), int(brush), 2); err != nil {
+		t.Fatalf("add brushes: %v", err)
+	}
+
+	tree, err := GetBOMTree(db, int(fan))
+	if err != nil {
+		t.Fatalf("bom tree: %v", err)
+	}
+	if len(tree.Children) != 2 {
+		t.Fatalf("expected 2 direct children, got %d", len(tree.Children))
+	}
+	if tree.Children[1].ID != int(bearing) || tree.Children[1].Quantity != 2 {
+		t.Fatalf("unexpected bearing node: %+v", tree.Children[1])
+	}
+	if len(tree.Children[0].Children) != 1 || tree.Children[0].Children[0].ID != int(brush) {
+		t.Fatalf("expected brushes under the motor, got %+v", tree.Children[0].Children)
+	}
+
+	if err := AddComponent(db, int(brush), int(fan), 1); err == nil {
+		t.Fatalf("expected cycle to be rejected")
+	}
+	if err := AddComponent(db, int(fan), int(fan), 1); err == nil {
+		t.Fatalf("expected self reference to be rejected")
+	}
+
+	if err := RemoveComponent(db, int(fan), int(bearing)); err != nil {
+		t.Fatalf("remove component: %v", err)
+	}
+	ids, err := ListDescendantIDs(db, int(fan))
+	if err != nil {
+		t.Fatalf("descendants: %v", err)
+	}
+	if len(ids) != 2 {
+		t.Fatalf("expected motor and brushes as descendants, got %v", ids)
+	}
+
+	// Moteur à la corbeille: ses balais ne sont plus des descendants du module
+	if err := TrashPart(db, int(motor)); err != nil {
+		t.Fatalf("trash motor: %v", err)
+	}
+	ids, err = ListDescendantIDs(db, int(fan))
+	if err != nil {
+		t.Fatalf("descendants: %v", err)
+	}
+	if len(ids) != 0 {
+		t.Fatalf("expected no descendants below a trashed component, got %v", ids)
+	}
+}
+
+func TestSetPartLocationWithChildren(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+
+	loc, err := CreateLocation(db, "Boite Moteurs", nil, "BOX", "")
+	if err != nil {
+		t.Fatalf("create location: %v", err)
+	}
+
+	gearmotor, _ := CreatePart(db, "", "Motoréducteur", "{}", nil, 1)
+	motor, _ := CreatePart(db, "", "Moteur", "{}", nil, 1)
+	gearbox, _ := CreatePart(db, "", "Réducteur", "{}", nil, 1)
+	AddComponent(db, int(gearmotor), int(motor), 1)
+	AddComponent(db, int(gearmotor), int(gearbox), 1)
+
+	if err := SetPartLocation(db, int(gearmotor), loc.ID, false); err != nil {
+		t.Fatalf("set location: %v", err)
+	}
+	meta, _ := GetPartMeta(db, int(motor))
+	if meta.LocationID.Valid {
+		t.Fatalf("child should not move without withChildren")
+	}
+
+	if err := SetPartLocation(db, int(gearmotor), loc.ID, true); err != nil {
+		t.Fatalf("set location with children: %v", err)
+	}
+	for _, id := range []int64{motor, gearbox} {
+		meta, _ := GetPartMeta(db, int(id))
+		if !meta.LocationID.Valid || int(meta.LocationID.Int64) != loc.ID {
+			t.Fatalf("child %d should have moved with its parent", id)
+		}
+	}
+}
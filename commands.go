@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -13,16 +15,29 @@ import (
 func cmdAdd(db *sql.DB, args []string) error {
 	fs := flag.NewFlagSet("add", flag.ExitOnError)
 	typeName := fs.String("type", "", "Type de pièce (ex: roulement, moteur)")
-	name := fs.String("name", "", "Nom de la pièce")
+	name := fs.String("name", "", "Nom de la pièce (défaut: name_pattern du template)")
 	props := fs.String("props", "{}", "Propriétés JSON de la pièce")
 	locName := fs.String("loc", "", "Localisation (nom ou ID)")
+	qty := fs.Int("qty", 1, "Quantité initiale en stock")
+	donorID := fs.Int("donor", 0, "ID de l'appareil donneur d'origine (voir 'recycle harvest')")
+	value := fs.Float64("value", -1, "Valeur unitaire estimée (optionnel)")
+	currency := fs.String("currency", DefaultCurrency, "Devise de la valeur unitaire")
 
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
 
-	if *name == "" {
-		return fmt.Errorf("le nom est requis (--name)")
+	// Vérifier l'appareil d'origine avant de créer la pièce
+	var donor *Donor
+	if *donorID > 0 {
+		var err error
+		if donor, err = GetDonor(db, *donorID); err != nil {
+			return err
+		}
+	}
+
+	if _, err := NormalizeCurrency(*currency); err != nil {
+		return err
 	}
 
 	// Refuser les types inconnus (taxonomie standard)
@@ -44,11 +59,19 @@ func cmdAdd(db *sql.DB, args []string) error {
 	}
 
 	// Normaliser les unités
-	fieldUnits := GetFieldUnits(*typeName)
-	normalizedProps, err := NormalizeProps(propsMap, fieldUnits)
+	normalizedProps, propUnits, err := NormalizePartPropsWithUnits(*typeName, propsMap)
 	if err != nil {
 		return fmt.Errorf("erreur de normalisation: %v", err)
 	}
+	ComputeProps(*typeName, normalizedProps)
+
+	// Nom automatique selon le template s'il n'est pas fourni
+	if *name == "" {
+		*name = GeneratePartName(*typeName, normalizedProps)
+	}
+	if *name == "" {
+		return fmt.Errorf("le nom est requis (--name), le type n'a pas de name_pattern ou ses champs sont vides")
+	}
 
 	// Sérialiser les props normalisées
 	normalizedJSON, err := json.Marshal(normalizedProps)
@@ -73,7 +96,22 @@ func cmdAdd(db *sql.DB, args []string) error {
 		locationID = &loc.ID
 	}
 
-	id, err := CreatePart(db, *typeName, *name, string(normalizedJSON), locationID)
+	part := NewPart{
+		Type:       *typeName,
+		Name:       *name,
+		PropsJSON:  string(normalizedJSON),
+		Units:      propUnits,
+		LocationID: locationID,
+		Quantity:   *qty,
+		Currency:   *currency,
+	}
+	if donor != nil {
+		part.DonorID = &donor.ID
+	}
+	if *value >= 0 {
+		part.UnitValue = value
+	}
+	id, err := CreatePartWith(db, part)
 	if err != nil {
 		return err
 	}
@@ -82,10 +120,15 @@ func cmdAdd(db *sql.DB, args []string) error {
 		fmt.Printf("  Type: %s\n", *typeName)
 	}
 	fmt.Printf("  Nom: %s\n", *name)
+	fmt.Printf("  Quantité: %d\n", *qty)
+	if *value >= 0 {
+		code, _ := NormalizeCurrency(*currency)
+		fmt.Printf("  Valeur unitaire: %s\n", FormatValue(*value, code))
+	}
 
 	// Afficher les props normalisées avec indication des conversions
 	if *props != string(normalizedJSON) {
-		fmt.Printf("  Props (normalisées): %s\n", string(normalizedJSON))
+		fmt.Printf("  Props (normalisées): %s\n", FormatProps(*typeName, normalizedProps, propUnits, DisplayUnits{}))
 		fmt.Printf("  Props (originales):  %s\n", *props)
 	} else {
 		fmt.Printf("  Props: %s\n", *props)
@@ -97,15 +140,728 @@ func cmdAdd(db *sql.DB, args []string) error {
 		fmt.Printf("  📍 Localisation: %s\n", path)
 	}
 
+	if donor != nil {
+		fmt.Printf("  🔧 Récupérée sur: %s\n", donor.Label())
+	}
+
+	return nil
+}
+
+func cmdEdit(db *sql.DB, args []string) error {
+	fs := flag.NewFlagSet("edit", flag.ExitOnError)
+	partID := fs.Int("id", 0, "ID de la pièce")
+	typeName := fs.String("type", "", "Nouveau type de pièce")
+	name := fs.String("name", "", "Nouveau nom")
+	props := fs.String("props", "", "Propriétés JSON à fusionner (null supprime une clé)")
+	replace := fs.Bool("replace", false, "Remplacer toutes les propriétés au lieu de fusionner")
+	value := fs.Float64("value", -1, "Valeur unitaire estimée")
+	currency := fs.String("currency", DefaultCurrency, "Devise de la valeur unitaire")
+	noValue := fs.Bool("no-value", false, "Retirer la valeur unitaire")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *partID == 0 {
+		return fmt.Errorf("l'ID de la pièce est requis (--id)")
+	}
+
+	var upd PartUpdate
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "type":
+			upd.Type = typeName
+		case "name":
+			upd.Name = name
+		}
+	})
+	upd.ReplaceProps = *replace
+
+	if *props != "" {
+		if err := json.Unmarshal([]byte(*props), &upd.Props); err != nil {
+			return fmt.Errorf("props invalide: %v", err)
+		}
+	}
+
+	valueChanged := *value >= 0 || *noValue
+	partChanged := upd.Type != nil || upd.Name != nil || *props != ""
+	if !partChanged && !valueChanged {
+		return fmt.Errorf("rien à modifier (--name, --type, --props ou --value)")
+	}
+
+	if valueChanged {
+		var v *float64
+		if !*noValue {
+			v = value
+		}
+		if err := SetPartValue(db, *partID, v, *currency); err != nil {
+			return err
+		}
+	}
+
+	var meta *PartMeta
+	var err error
+	if partChanged {
+		meta, err = EditPart(db, *partID, upd)
+	} else {
+		meta, err = GetPartMeta(db, *partID)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Pièce modifiée [ID: %d]\n", meta.ID)
+	if meta.Type != "" {
+		fmt.Printf("  Type: %s\n", meta.Type)
+	}
+	fmt.Printf("  Nom: %s\n", meta.Name)
+	fmt.Printf("  Props: %s\n", meta.FormatProps(DisplayUnits{}))
+	if meta.UnitValue.Valid {
+		fmt.Printf("  Valeur unitaire: %s\n", FormatValue(meta.UnitValue.Float64, meta.Currency))
+	}
+
 	return nil
 }
 
+func cmdReport(db *sql.DB, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("type de rapport requis (value)")
+	}
+
+	subCmd := args[0]
+
+	switch subCmd {
+	case "value":
+		fs := flag.NewFlagSet("report value", flag.ExitOnError)
+		by := fs.String("by", ValueByType, "Regroupement (type, location, donor)")
+		format := fs.String("format", "text", "Format de sortie (text, csv, json)")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		report, err := BuildValueReport(db, *by)
+		if err != nil {
+			return err
+		}
+		return WriteValueReport(os.Stdout, report, *format)
+	default:
+		return fmt.Errorf("rapport inconnu: %s (value)", subCmd)
+	}
+}
+
+func cmdRm(db *sql.DB, args []string) error {
+	fs := flag.NewFlagSet("rm", flag.ExitOnError)
+	partID := fs.Int("id", 0, "ID de la pièce à mettre à la corbeille")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *partID == 0 {
+		return fmt.Errorf("l'ID de la pièce est requis (--id)")
+	}
+
+	if err := TrashPart(db, *partID); err != nil {
+		return err
+	}
+
+	fmt.Printf("🗑️  Pièce ID %d placée dans la corbeille\n", *partID)
+	fmt.Printf("  Restaurer: recycle trash restore --id=%d\n", *partID)
+	return nil
+}
+
+func cmdState(db *sql.DB, args []string) error {
+	fs := flag.NewFlagSet("state", flag.ExitOnError)
+	partID := fs.Int("id", 0, "ID de la pièce")
+	state := fs.String("set", "", "Nouvel état (untested, working, broken, spare)")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *partID == 0 {
+		return fmt.Errorf("l'ID de la pièce est requis (--id)")
+	}
+
+	meta, err := GetPartMeta(db, *partID)
+	if err != nil {
+		return err
+	}
+	if !meta.Found {
+		return fmt.Errorf("pièce ID %d introuvable", *partID)
+	}
+
+	// Sans --set, afficher l'état courant et les transitions possibles
+	if *state == "" {
+		fmt.Printf("État de la pièce ID %d: %s (%s)\n", meta.ID, StateLabel(meta.State), meta.State)
+		if next := StateTransitions[meta.State]; len(next) > 0 {
+			fmt.Printf("  Transitions possibles: %s\n", strings.Join(next, ", "))
+		} else {
+			fmt.Println("  État final, aucune transition possible")
+		}
+		return nil
+	}
+
+	if err := SetPartState(db, *partID, *state); err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Pièce ID %d: %s → %s\n", *partID, StateLabel(meta.State), StateLabel(*state))
+	return nil
+}
+
+func cmdTrash(db *sql.DB, args []string) error {
+	if len(args) == 0 {
+		return PrintTrash(db)
+	}
+
+	subCmd := args[0]
+
+	switch subCmd {
+	case "list", "ls":
+		return PrintTrash(db)
+	case "restore":
+		fs := flag.NewFlagSet("trash restore", flag.ExitOnError)
+		partID := fs.Int("id", 0, "ID de la pièce à restaurer")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *partID == 0 {
+			return fmt.Errorf("l'ID de la pièce est requis (--id)")
+		}
+		if err := RestorePart(db, *partID); err != nil {
+			return err
+		}
+		fmt.Printf("✓ Pièce ID %d restaurée\n", *partID)
+		return nil
+	case "purge":
+		fs := flag.NewFlagSet("trash purge", flag.ExitOnError)
+		partID := fs.Int("id", 0, "ID de la pièce à supprimer définitivement")
+		all := fs.Bool("all", false, "Vider toute la corbeille")
+		force := fs.Bool("force", false, "Ne pas demander confirmation")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *partID == 0 && !*all {
+			return fmt.Errorf("--id ou --all requis")
+		}
+
+		// Demander confirmation si pas --force
+		if !*force {
+			fmt.Print("⚠️  ATTENTION: suppression DÉFINITIVE (pièces et fichiers attachés)!\n")
+			fmt.Print("Tapez 'yes' pour continuer: ")
+			var response string
+			fmt.Scanln(&response)
+			if response != "yes" {
+				fmt.Println("Purge annulée.")
+				return nil
+			}
+		}
+
+		if *all {
+			count, err := PurgeTrash(db)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("✓ Corbeille vidée: %d pièce(s) supprimée(s)\n", count)
+			return nil
+		}
+		if err := PurgePart(db, *partID); err != nil {
+			return err
+		}
+		fmt.Printf("✓ Pièce ID %d supprimée définitivement\n", *partID)
+		return nil
+	default:
+		return fmt.Errorf("sous-commande inconnue: %s (list|restore|purge)", subCmd)
+	}
+}
+
+func cmdHarvest(db *sql.DB, args []string) error {
+	if len(args) == 0 {
+		return PrintDonors(db)
+	}
+
+	subCmd := args[0]
+
+	switch subCmd {
+	case "list", "ls":
+		return PrintDonors(db)
+	case "new":
+		fs := flag.NewFlagSet("harvest new", flag.ExitOnError)
+		category := fs.String("category", "", "Catégorie d'appareil (ex: imprimante, lecteur DVD, lave-linge)")
+		brand := fs.String("brand", "", "Marque")
+		model := fs.String("model", "", "Modèle")
+		notes := fs.String("notes", "", "Notes (provenance, état...)")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		donor, err := CreateDonor(db, *category, *brand, *model, *notes)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("✓ Appareil donneur créé [ID: %d] %s\n", donor.ID, donor.Label())
+		fmt.Printf("  Ajouter une pièce: recycle add --donor=%d --name=...\n", donor.ID)
+		return nil
+	case "show":
+		fs := flag.NewFlagSet("harvest show", flag.ExitOnError)
+		donorID := fs.Int("donor", 0, "ID de l'appareil donneur")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *donorID == 0 {
+			return fmt.Errorf("l'ID de l'appareil est requis (--donor)")
+		}
+		donor, err := GetDonor(db, *donorID)
+		if err != nil {
+			return err
+		}
+		parts, err := ListDonorParts(db, donor.ID)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("\n🔧 Pièces récupérées sur: %s (ID: %d)\n", donor.Label(), donor.ID)
+		if donor.Notes != "" {
+			fmt.Printf("   %s\n", donor.Notes)
+		}
+		fmt.Println()
+		return printPartsTableWithAttachments(db, parts, "Récupérées", DisplayUnits{})
+	case "link":
+		fs := flag.NewFlagSet("harvest link", flag.ExitOnError)
+		partID := fs.Int("part", 0, "ID de la pièce")
+		donorID := fs.Int("donor", 0, "ID de l'appareil donneur")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *partID == 0 || *donorID == 0 {
+			return fmt.Errorf("--part et --donor sont requis")
+		}
+		if err := SetPartDonor(db, *partID, *donorID); err != nil {
+			return err
+		}
+		fmt.Printf("✓ Pièce ID %d rattachée à l'appareil ID %d\n", *partID, *donorID)
+		return nil
+	case "unlink":
+		fs := flag.NewFlagSet("harvest unlink", flag.ExitOnError)
+		partID := fs.Int("part", 0, "ID de la pièce")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *partID == 0 {
+			return fmt.Errorf("l'ID de la pièce est requis (--part)")
+		}
+		if err := ClearPartDonor(db, *partID); err != nil {
+			return err
+		}
+		fmt.Printf("✓ Pièce ID %d détachée de son appareil d'origine\n", *partID)
+		return nil
+	case "stats":
+		fs := flag.NewFlagSet("harvest stats", flag.ExitOnError)
+		model := fs.String("model", "", "Filtrer par modèle (partiel)")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		return PrintDonorModelStats(db, *model)
+	default:
+		return fmt.Errorf("sous-commande inconnue: %s (list|new|show|link|unlink|stats)", subCmd)
+	}
+}
+
+func cmdBom(db *sql.DB, args []string) error {
+	subCmd := ""
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		subCmd = args[0]
+		args = args[1:]
+	}
+
+	switch subCmd {
+	case "", "show":
+		fs := flag.NewFlagSet("bom", flag.ExitOnError)
+		partID := fs.Int("id", 0, "ID de la pièce (assemblage)")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if *partID == 0 {
+			return fmt.Errorf("l'ID de la pièce est requis (--id)")
+		}
+		return PrintBOM(db, *partID)
+	case "add":
+		fs := flag.NewFlagSet("bom add", flag.ExitOnError)
+		parentID := fs.Int("parent", 0, "ID de l'assemblage")
+		childID := fs.Int("child", 0, "ID du sous-composant")
+		qty := fs.Int("qty", 1, "Nombre d'unités dans l'assemblage")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if *parentID == 0 || *childID == 0 {
+			return fmt.Errorf("--parent et --child sont requis")
+		}
+		if err := AddComponent(db, *parentID, *childID, *qty); err != nil {
+			return err
+		}
+		fmt.Printf("✓ Pièce ID %d: contient %d× pièce ID %d\n", *parentID, *qty, *childID)
+		return nil
+	case "rm":
+		fs := flag.NewFlagSet("bom rm", flag.ExitOnError)
+		parentID := fs.Int("parent", 0, "ID de l'assemblage")
+		childID := fs.Int("child", 0, "ID du sous-composant")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if *parentID == 0 || *childID == 0 {
+			return fmt.Errorf("--parent et --child sont requis")
+		}
+		if err := RemoveComponent(db, *parentID, *childID); err != nil {
+			return err
+		}
+		fmt.Printf("✓ Pièce ID %d retirée de l'assemblage ID %d\n", *childID, *parentID)
+		return nil
+	default:
+		return fmt.Errorf("sous-commande inconnue: %s (show|add|rm)", subCmd)
+	}
+}
+
+func cmdTag(db *sql.DB, args []string) error {
+	if len(args) == 0 {
+		return PrintTags(db)
+	}
+
+	subCmd := args[0]
+
+	switch subCmd {
+	case "list", "ls":
+		fs := flag.NewFlagSet("tag list", flag.ExitOnError)
+		partID := fs.Int("id", 0, "ID de la pièce (optionnel)")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *partID == 0 {
+			return PrintTags(db)
+		}
+		tags, err := GetPartTags(db, *partID)
+		if err != nil {
+			return err
+		}
+		if len(tags) == 0 {
+			fmt.Printf("Pièce ID %d: aucun tag\n", *partID)
+			return nil
+		}
+		fmt.Printf("Pièce ID %d: %s\n", *partID, strings.Join(tags, ", "))
+		return nil
+	case "add", "rm":
+		fs := flag.NewFlagSet("tag "+subCmd, flag.ExitOnError)
+		partID := fs.Int("id", 0, "ID de la pièce")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *partID == 0 {
+			return fmt.Errorf("l'ID de la pièce est requis (--id)")
+		}
+		if fs.NArg() == 0 {
+			return fmt.Errorf("au moins un tag est requis (ex: recycle tag %s --id=42 to-sort)", subCmd)
+		}
+
+		var err error
+		if subCmd == "add" {
+			err = AddPartTags(db, *partID, fs.Args())
+		} else {
+			err = RemovePartTags(db, *partID, fs.Args())
+		}
+		if err != nil {
+			return err
+		}
+
+		tags, err := GetPartTags(db, *partID)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("✓ Tags de la pièce ID %d: %s\n", *partID, strings.Join(tags, ", "))
+		return nil
+	default:
+		return fmt.Errorf("sous-commande inconnue: %s (list|add|rm)", subCmd)
+	}
+}
+
+func cmdLoan(db *sql.DB, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("sous-commande requise (out|return)")
+	}
+
+	subCmd := args[0]
+
+	switch subCmd {
+	case "out", "checkout":
+		fs := flag.NewFlagSet("loan out", flag.ExitOnError)
+		partID := fs.Int("id", 0, "ID de la pièce")
+		borrower := fs.String("to", "", "Nom de l'emprunteur")
+		due := fs.String("due", "14d", "Échéance (AAAA-MM-JJ ou nombre de jours, ex: 14d)")
+		qty := fs.Int("qty", 1, "Quantité prêtée")
+		notes := fs.String("notes", "", "Notes (projet, contact...)")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *partID == 0 {
+			return fmt.Errorf("l'ID de la pièce est requis (--id)")
+		}
+		dueDate, err := ParseDueDate(*due, time.Now())
+		if err != nil {
+			return err
+		}
+
+		loan, err := CheckoutPart(db, *partID, *borrower, *qty, dueDate, *notes)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("✓ Prêt ID %d: %s ×%d → %s, à rendre avant le %s\n", loan.ID, loan.PartName, loan.Quantity, loan.Borrower, loan.DueDate)
+		return nil
+	case "return", "in":
+		fs := flag.NewFlagSet("loan return", flag.ExitOnError)
+		loanID := fs.Int("loan", 0, "ID du prêt")
+		partID := fs.Int("id", 0, "ID de la pièce (si elle n'a qu'un prêt en cours)")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *loanID == 0 && *partID == 0 {
+			return fmt.Errorf("l'ID du prêt (--loan) ou de la pièce (--id) est requis")
+		}
+
+		if *loanID == 0 {
+			loans, err := ListLoans(db, *partID, false, false)
+			if err != nil {
+				return err
+			}
+			switch len(loans) {
+			case 0:
+				return fmt.Errorf("aucun prêt en cours pour la pièce ID %d", *partID)
+			case 1:
+				*loanID = loans[0].ID
+			default:
+				return fmt.Errorf("la pièce ID %d a %d prêts en cours, préciser --loan (voir: recycle loans)", *partID, len(loans))
+			}
+		}
+
+		loan, err := ReturnLoan(db, *loanID)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("✓ Prêt ID %d rendu: %s ×%d (%s)\n", loan.ID, loan.PartName, loan.Quantity, loan.Borrower)
+		return nil
+	default:
+		return fmt.Errorf("sous-commande inconnue: %s (out|return)", subCmd)
+	}
+}
+
+func cmdAlerts(db *sql.DB, args []string) error {
+	fs := flag.NewFlagSet("alerts", flag.ExitOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	return PrintAlerts(db)
+}
+
+func cmdLoans(db *sql.DB, args []string) error {
+	fs := flag.NewFlagSet("loans", flag.ExitOnError)
+	overdue := fs.Bool("overdue", false, "Uniquement les prêts en retard")
+	all := fs.Bool("all", false, "Inclure les prêts rendus")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	return PrintLoans(db, *overdue, *all)
+}
+
+func cmdDedupe(db *sql.DB, args []string) error {
+	fs := flag.NewFlagSet("dedupe", flag.ExitOnError)
+	typeName := fs.String("type", "", "Limiter à un type de pièce")
+	threshold := fs.Float64("threshold", DefaultDedupeThreshold, "Similarité minimale des noms (0..1)")
+	auto := fs.Bool("auto", false, "Fusionner automatiquement chaque groupe dans la pièce la plus ancienne")
+	dryRun := fs.Bool("dry-run", false, "Lister les doublons sans rien fusionner")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *threshold < 0 || *threshold > 1 {
+		return fmt.Errorf("--threshold doit être compris entre 0 et 1")
+	}
+
+	groups, err := FindDuplicates(db, *typeName, *threshold)
+	if err != nil {
+		return err
+	}
+
+	if len(groups) == 0 {
+		fmt.Println("✓ Aucun doublon détecté")
+		return nil
+	}
+
+	fmt.Printf("🔍 %d groupe(s) de doublons probables\n", len(groups))
+
+	merged := 0
+	for i, g := range groups {
+		PrintDuplicateGroup(g, i+1)
+		if *dryRun {
+			continue
+		}
+
+		survivorID := g.Parts[0].ID
+		if !*auto {
+			fmt.Printf("  Fusionner dans [%d] ? (o = oui, ID = autre survivant, Entrée = ignorer): ", survivorID)
+			var response string
+			fmt.Scanln(&response)
+			response = strings.ToLower(strings.TrimSpace(response))
+			if response == "" || response == "n" || response == "non" {
+				fmt.Println("  Groupe ignoré")
+				continue
+			}
+			if response != "o" && response != "oui" && response != "y" && response != "yes" {
+				id, err := strconv.Atoi(response)
+				if err != nil || !groupHasPart(g, id) {
+					fmt.Printf("  Réponse invalide: %s (groupe ignoré)\n", response)
+					continue
+				}
+				survivorID = id
+			}
+		}
+
+		var duplicateIDs []int
+		for _, p := range g.Parts {
+			if p.ID != survivorID {
+				duplicateIDs = append(duplicateIDs, p.ID)
+			}
+		}
+		if err := MergeParts(db, survivorID, duplicateIDs); err != nil {
+			return fmt.Errorf("fusion dans ID %d: %v", survivorID, err)
+		}
+		merged += len(duplicateIDs)
+		fmt.Printf("  ✓ Fusionné dans [%d] (les étiquettes PRT-%s redirigent vers PRT-%d)\n",
+			survivorID, joinInts(duplicateIDs, ", PRT-"), survivorID)
+	}
+
+	if !*dryRun {
+		fmt.Printf("\n%d pièce(s) fusionnée(s)\n", merged)
+	}
+	return nil
+}
+
+func groupHasPart(g DuplicateGroup, id int) bool {
+	for _, p := range g.Parts {
+		if p.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func joinInts(ids []int, sep string) string {
+	strs := make([]string, len(ids))
+	for i, id := range ids {
+		strs[i] = strconv.Itoa(id)
+	}
+	return strings.Join(strs, sep)
+}
+
+func cmdHistory(db *sql.DB, args []string) error {
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	partID := fs.Int("part", 0, "ID de la pièce")
+	locID := fs.Int("loc", 0, "ID de la localisation")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	switch {
+	case *partID > 0 && *locID > 0:
+		return fmt.Errorf("--part ou --loc, pas les deux")
+	case *partID > 0:
+		return PrintHistory(db, HistoryEntityPart, *partID)
+	case *locID > 0:
+		return PrintHistory(db, HistoryEntityLocation, *locID)
+	default:
+		return fmt.Errorf("--part=<id> ou --loc=<id> requis")
+	}
+}
+
 func cmdList(db *sql.DB) error {
 	parts, err := ListAllParts(db)
 	if err != nil {
 		return err
 	}
-	return printPartsTableWithAttachments(db, parts, "Total")
+	return printPartsTableWithAttachments(db, parts, "Total", DisplayUnits{})
+}
+
+func cmdStock(db *sql.DB, args []string) error {
+	fs := flag.NewFlagSet("stock", flag.ExitOnError)
+	partID := fs.Int("id", 0, "ID de la pièce")
+	in := fs.Int("in", 0, "Quantité entrée en stock")
+	out := fs.Int("out", 0, "Quantité sortie du stock")
+	set := fs.Int("set", -1, "Fixer la quantité (inventaire)")
+	reason := fs.String("reason", "", "Motif du mouvement")
+	minStock := fs.Int("min", -1, "Seuil de stock minimum propre à la pièce (0: aucune alerte)")
+	minReset := fs.Bool("min-reset", false, "Revenir au seuil minimum du template")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *partID == 0 {
+		return fmt.Errorf("l'ID de la pièce est requis (--id)")
+	}
+
+	if *minStock >= 0 || *minReset {
+		if *minStock >= 0 && *minReset {
+			return fmt.Errorf("--min et --min-reset sont incompatibles")
+		}
+		var value *int
+		if !*minReset {
+			value = minStock
+		}
+		if err := SetPartMinStock(db, *partID, value); err != nil {
+			return err
+		}
+		meta, err := GetPartMeta(db, *partID)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("✓ Seuil minimum de la pièce ID %d: %d\n", *partID, EffectiveMinStock(meta.Type, meta.MinStock))
+	}
+
+	var kind string
+	var qty int
+	ops := 0
+	if *in > 0 {
+		kind, qty = MovementIn, *in
+		ops++
+	}
+	if *out > 0 {
+		kind, qty = MovementOut, *out
+		ops++
+	}
+	if *set >= 0 {
+		kind, qty = MovementAdjust, *set
+		ops++
+	}
+
+	// Sans mouvement, afficher le registre (sauf si seul le seuil a été modifié)
+	if ops == 0 {
+		if *minStock >= 0 || *minReset {
+			return nil
+		}
+		return PrintStockMovements(db, *partID)
+	}
+	if ops > 1 {
+		return fmt.Errorf("un seul mouvement à la fois (--in, --out ou --set)")
+	}
+
+	balance, err := RecordStockMovement(db, *partID, kind, qty, *reason)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Mouvement '%s' enregistré pour la pièce ID %d\n", kind, *partID)
+	fmt.Printf("  Quantité en stock: %d\n", balance)
+	return nil
 }
 
 func cmdSearch(db *sql.DB, args []string) error {
@@ -113,6 +869,11 @@ func cmdSearch(db *sql.DB, args []string) error {
 	typeName := fs.String("type", "", "Filtrer par type de pièce")
 	propSearch := fs.String("prop", "", "Recherche par propriété (ex: d_int:10 ou d_int:10..10.5)")
 	nameSearch := fs.String("name", "", "Recherche par nom (partiel)")
+	state := fs.String("state", "", "Filtrer par état (untested, working, broken, spare)")
+	tags := fs.String("tag", "", "Filtrer par tag(s), séparés par des virgules (tous requis)")
+	subtypes := fs.Bool("subtypes", false, "Inclure les types qui héritent de --type (extends)")
+	units := fs.String("units", "", "Système d'unités d'affichage (metric, imperial)")
+	fieldUnits := fs.String("unit", "", "Unités d'affichage par champ (ex: d_int:cm,d_ext:in)")
 
 	if err := fs.Parse(args); err != nil {
 		return err
@@ -122,17 +883,130 @@ func cmdSearch(db *sql.DB, args []string) error {
 	if err != nil {
 		return err
 	}
+	display, err := ParseDisplayUnits(*units, []string{*fieldUnits})
+	if err != nil {
+		return err
+	}
 
-	parts, err := SearchPartsDB(db, *typeName, *nameSearch, criteria)
+	parts, err := SearchPartsDB(db, SearchFilters{
+		Type:     *typeName,
+		Name:     *nameSearch,
+		Criteria: criteria,
+		State:    *state,
+		Tags:     ParseTagList(*tags),
+
+		IncludeSubtypes: *subtypes,
+	})
 	if err != nil {
 		return err
 	}
 
-	return printPartsTableWithAttachments(db, parts, "Résultats")
+	return printPartsTableWithAttachments(db, parts, "Résultats", display)
+}
+
+func cmdTemplates(db *sql.DB, args []string) error {
+	subCmd := ""
+	if len(args) > 0 {
+		subCmd = args[0]
+	}
+
+	switch subCmd {
+	case "", "list":
+		return printTemplates()
+	case "show":
+		fs := flag.NewFlagSet("templates show", flag.ExitOnError)
+		name := fs.String("name", "", "Nom du template")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if _, exists := Templates.Get(*name); !exists {
+			return fmt.Errorf("template inconnu: %s", *name)
+		}
+		fmt.Printf("# %s (%s)\n", *name, templateSourceLabel(Templates.Source(*name)))
+		fmt.Print(Templates.Definition(*name))
+		return nil
+	case "add", "edit":
+		fs := flag.NewFlagSet("templates "+subCmd, flag.ExitOnError)
+		file := fs.String("file", "", "Fichier YAML de définition (- pour l'entrée standard)")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *file == "" {
+			return fmt.Errorf("la définition est requise (--file=capteur.yaml)")
+		}
+		definition, err := readTemplateDefinition(*file)
+		if err != nil {
+			return err
+		}
+
+		var tmpl *Template
+		if subCmd == "add" {
+			tmpl, err = AddTemplate(db, definition)
+		} else {
+			var parsed *Template
+			if parsed, err = ParseTemplate(definition); err == nil {
+				tmpl, err = UpdateTemplate(db, parsed.Name, definition)
+			}
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("✓ Template %s enregistré en base (%d champ(s))\n", tmpl.Name, len(tmpl.Fields))
+		return nil
+	case "rm":
+		fs := flag.NewFlagSet("templates rm", flag.ExitOnError)
+		name := fs.String("name", "", "Nom du template")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *name == "" {
+			return fmt.Errorf("le nom est requis (--name)")
+		}
+		if err := DeleteTemplate(db, *name); err != nil {
+			return err
+		}
+		if _, exists := Templates.Get(*name); exists {
+			fmt.Printf("✓ Template %s supprimé de la base (version de %s rétablie)\n", *name, templateSourceLabel(Templates.Source(*name)))
+		} else {
+			fmt.Printf("✓ Template %s supprimé\n", *name)
+		}
+		return nil
+	case "migrate":
+		fs := flag.NewFlagSet("templates migrate", flag.ExitOnError)
+		typeName := fs.String("type", "", "Ne migrer que les pièces de ce type")
+		dryRun := fs.Bool("dry-run", false, "Lister les pièces concernées sans rien modifier")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		parts, err := MigrateTemplates(db, *typeName, *dryRun)
+		if err != nil {
+			return err
+		}
+		PrintMigrationReport(parts, *dryRun)
+		return nil
+	default:
+		return fmt.Errorf("sous-commande inconnue: %s (list|show|add|edit|rm|migrate)", subCmd)
+	}
+}
+
+// readTemplateDefinition lit une définition de template depuis un fichier ou l'entrée standard
+func readTemplateDefinition(path string) (string, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
 }
 
-func cmdTemplates() error {
-	if len(Templates) == 0 {
+func printTemplates() error {
+	if Templates.Len() == 0 {
 		fmt.Println("Aucun template trouvé dans", templatesDir)
 		return nil
 	}
@@ -140,9 +1014,26 @@ func cmdTemplates() error {
 	fmt.Println("Templates disponibles:")
 	fmt.Println()
 
-	for name, tmpl := range Templates {
-		fmt.Printf("▸ %s\n", name)
+	for _, name := range Templates.Names() {
+		tmpl, _ := Templates.Get(name)
+		fmt.Printf("▸ %s", name)
+		if Templates.Source(name) == TemplateSourceDB {
+			fmt.Print(" (base)")
+		}
+		fmt.Println()
 		fmt.Printf("  %s\n", tmpl.Description)
+		if len(tmpl.Ancestors) > 0 {
+			fmt.Printf("  Hérite de: %s\n", strings.Join(tmpl.Ancestors, " → "))
+		}
+		if len(tmpl.Aliases) > 0 {
+			fmt.Printf("  Alias: %s\n", strings.Join(tmpl.Aliases, ", "))
+		}
+		if tmpl.version() > 1 {
+			fmt.Printf("  Version: %d\n", tmpl.version())
+		}
+		if tmpl.NamePattern != "" {
+			fmt.Printf("  Nom: %s\n", tmpl.NamePattern)
+		}
 		fmt.Printf("  Requis: %s\n", strings.Join(tmpl.Required, ", "))
 		if len(tmpl.Optional) > 0 {
 			fmt.Printf("  Optionnel: %s\n", strings.Join(tmpl.Optional, ", "))
@@ -153,6 +1044,43 @@ func cmdTemplates() error {
 	return nil
 }
 
+// cmdLint vérifie les pièces existantes contre leur template et rapporte chaque violation
+func cmdLint(db *sql.DB, args []string) error {
+	fs := flag.NewFlagSet("lint", flag.ExitOnError)
+	typeName := fs.String("type", "", "Ne vérifier que les pièces de ce type (et de ses sous-types)")
+	strict := fs.Bool("strict", false, "Appliquer le mode strict à tous les templates")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	issues, err := LintParts(db, *typeName, *strict)
+	if err != nil {
+		return err
+	}
+	PrintLintReport(issues)
+	if len(issues) > 0 {
+		return fmt.Errorf("%d pièce(s) non conforme(s)", len(issues))
+	}
+	return nil
+}
+
+// cmdRename régénère les noms des pièces selon le name_pattern de leur template
+func cmdRename(db *sql.DB, args []string) error {
+	fs := flag.NewFlagSet("rename", flag.ExitOnError)
+	typeName := fs.String("type", "", "Ne renommer que les pièces de ce type (et de ses sous-types)")
+	apply := fs.Bool("apply", false, "Écrire les nouveaux noms (sinon simulation)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	parts, err := RenameParts(db, *typeName, *apply)
+	if err != nil {
+		return err
+	}
+	PrintRenameReport(parts, *apply)
+	return nil
+}
+
 func cmdImport(db *sql.DB, args []string) error {
 	fs := flag.NewFlagSet("import", flag.ExitOnError)
 	filePath := fs.String("file", "", "Chemin vers le fichier CSV ou JSON")
@@ -250,6 +1178,7 @@ func cmdFiles(db *sql.DB, args []string) error {
 			   (SELECT COUNT(*) FROM attachments WHERE part_id = p.id) as attach_count
 		FROM parts p
 		INNER JOIN attachments a ON a.part_id = p.id
+		WHERE p.deleted_at IS NULL
 		ORDER BY p.id
 	`)
 	if err != nil {
@@ -590,6 +1519,7 @@ func cmdLocSet(db *sql.DB, args []string) error {
 	partID := fs.Int("part", 0, "ID de la pièce")
 	locName := fs.String("loc", "", "Nom ou ID de la localisation")
 	clear := fs.Bool("clear", false, "Supprimer la localisation de la pièce")
+	withChildren := fs.Bool("with-children", false, "Déplacer aussi les sous-composants (voir 'recycle bom')")
 
 	if err := fs.Parse(args); err != nil {
 		return err
@@ -625,18 +1555,24 @@ func cmdLocSet(db *sql.DB, args []string) error {
 		}
 	}
 
-	if err := SetPartLocation(db, *partID, loc.ID); err != nil {
+	if err := SetPartLocation(db, *partID, loc.ID, *withChildren); err != nil {
 		return err
 	}
 
 	path, _ := GetFullPath(db, loc.ID)
 	fmt.Printf("✓ Pièce ID %d localisée dans: %s\n", *partID, path)
+	if *withChildren {
+		children, _ := ListDescendantIDs(db, *partID)
+		if len(children) > 0 {
+			fmt.Printf("  + %d sous-composant(s) déplacé(s)\n", len(children))
+		}
+	}
 	return nil
 }
 
 // --- Helpers d'affichage ---
 
-func printPartsTableWithAttachments(db *sql.DB, parts []PartRecord, countLabel string) error {
+func printPartsTableWithAttachments(db *sql.DB, parts []PartRecord, countLabel string, display DisplayUnits) error {
 	var partIDs []int
 	var locationIDs []int
 	for _, p := range parts {
@@ -646,23 +1582,20 @@ func printPartsTableWithAttachments(db *sql.DB, parts []PartRecord, countLabel s
 		}
 	}
 
-	// Récupérer les attachments et localisations
+	// Récupérer les attachments, localisations et tags
 	attachmentsMap, _ := GetAttachmentsForParts(db, partIDs)
 	locationsMap, _ := GetLocationsMap(db, locationIDs)
+	tagsMap, _ := GetTagsForParts(db, partIDs)
 
 	// Afficher le tableau
-	fmt.Println("┌─────┬──────────────┬────────────────────────────┬────────────────────────────────────────┬───────┐")
-	fmt.Println("│ ID  │ Type         │ Nom                        │ Propriétés                             │ Docs  │")
-	fmt.Println("├─────┼──────────────┼────────────────────────────┼────────────────────────────────────────┼───────┤")
+	fmt.Println("┌─────┬──────────────┬────────────────────────────┬───────┬─────────────┬────────────────────────────────┬───────┐")
+	fmt.Println("│ ID  │ Type         │ Nom                        │ Qté   │ État        │ Propriétés                     │ Docs  │")
+	fmt.Println("├─────┼──────────────┼────────────────────────────┼───────┼─────────────┼────────────────────────────────┼───────┤")
 
 	for _, p := range parts {
 		displayType := truncate(p.Type, 12)
 		displayName := truncate(p.Name, 26)
-		propsStr := "{}"
-		if p.Props.Valid {
-			propsStr = p.Props.String
-		}
-		displayProps := truncate(propsStr, 38)
+		displayProps := truncate(FormatPropsJSON(p.Type, p.Props, p.PropUnits, display), 30)
 
 		// Indicateur de fichiers attachés
 		docsIndicator := ""
@@ -671,11 +1604,11 @@ func printPartsTableWithAttachments(db *sql.DB, parts []PartRecord, countLabel s
 		}
 		docsDisplay := truncate(docsIndicator, 5)
 
-		fmt.Printf("│ %-3d │ %-12s │ %-26s │ %-38s │ %-5s │\n",
-			p.ID, displayType, displayName, displayProps, docsDisplay)
+		fmt.Printf("│ %-3d │ %-12s │ %-26s │ %5d │ %-11s │ %-30s │ %-5s │\n",
+			p.ID, displayType, displayName, p.Quantity, StateLabel(p.State), displayProps, docsDisplay)
 	}
 
-	fmt.Println("└─────┴──────────────┴────────────────────────────┴────────────────────────────────────────┴───────┘")
+	fmt.Println("└─────┴──────────────┴────────────────────────────┴───────┴─────────────┴────────────────────────────────┴───────┘")
 	fmt.Printf("\n%s: %d pièce(s)\n", countLabel, len(parts))
 
 	// Collecter pièces avec docs et pièces avec localisation
@@ -700,6 +1633,16 @@ func printPartsTableWithAttachments(db *sql.DB, parts []PartRecord, countLabel s
 		}
 	}
 
+	// Afficher les tags
+	if len(tagsMap) > 0 {
+		fmt.Println("\n🏷️  Tags:")
+		for _, p := range parts {
+			if tags, ok := tagsMap[p.ID]; ok {
+				fmt.Printf("  [%d] %s: %s\n", p.ID, p.Name, strings.Join(tags, ", "))
+			}
+		}
+	}
+
 	// Afficher les pièces avec documentation
 	if len(partsWithDocs) > 0 {
 		fmt.Println("\n📎 Documentation disponible:")
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// computedField est un champ calculé compilé (clé "compute" d'un champ de template)
+type computedField struct {
+	name     string
+	expr     *Expr
+	override bool
+}
+
+// compileComputedFields compile les expressions des champs calculés d'un template résolu et
+// les range dans l'ordre d'évaluation (un champ calculé peut dépendre d'un autre).
+// Les champs inconnus et les dépendances circulaires sont refusés.
+func compileComputedFields(t *Template) error {
+	exprs := make(map[string]*Expr)
+	for name, def := range t.Fields {
+		if def.Compute == "" {
+			continue
+		}
+		expr, err := ParseExpr(def.Compute)
+		if err != nil {
+			return fmt.Errorf("champ '%s': expression invalide: %v", name, err)
+		}
+		for _, dep := range expr.Fields() {
+			if _, ok := t.Fields[dep]; !ok {
+				return fmt.Errorf("champ '%s': l'expression utilise un champ inconnu '%s'", name, dep)
+			}
+		}
+		exprs[name] = expr
+	}
+
+	names := make([]string, 0, len(exprs))
+	for name := range exprs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	t.computed = nil
+	done := make(map[string]bool)
+	var visit func(name string, chain []string) error
+	visit = func(name string, chain []string) error {
+		if done[name] {
+			return nil
+		}
+		for _, seen := range chain {
+			if seen == name {
+				return fmt.Errorf("dépendance circulaire entre champs calculés: %s", strings.Join(append(chain, name), " → "))
+			}
+		}
+		for _, dep := range exprs[name].Fields() {
+			if _, ok := exprs[dep]; ok {
+				if err := visit(dep, append(chain, name)); err != nil {
+					return err
+				}
+			}
+		}
+		done[name] = true
+		t.computed = append(t.computed, computedField{name: name, expr: exprs[name], override: t.Fields[name].Override})
+		return nil
+	}
+	for _, name := range names {
+		if err := visit(name, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ComputeProps renseigne les champs calculés d'une pièce à partir de ses props normalisées.
+// Un champ "override" garde la valeur saisie; les autres sont toujours recalculés, et retirés
+// si le calcul est impossible (champ source absent, valeur hors table, etc.).
+func ComputeProps(typeName string, props map[string]interface{}) {
+	tmpl, ok := Templates.Get(typeName)
+	if !ok {
+		return
+	}
+
+	for _, field := range tmpl.computed {
+		if field.override {
+			if v, ok := props[field.name]; ok && v != nil && v != "" {
+				continue
+			}
+		}
+
+		value, err := field.expr.Eval(props)
+		if err != nil {
+			delete(props, field.name)
+			continue
+		}
+		if f, ok := value.(float64); ok {
+			if math.IsNaN(f) || math.IsInf(f, 0) {
+				delete(props, field.name)
+				continue
+			}
+			// Arrondi pour éviter les artefacts flottants (1.1 / 2 = 0.5500000000000001)
+			value = math.Round(f*1e9) / 1e9
+		}
+		props[field.name] = value
+	}
+}
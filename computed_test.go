@@ -0,0 +1,97 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// seedComputedTemplates ajoute des templates à champs calculés
+func seedComputedTemplates(t *testing.T) {
+	t.Helper()
+	raw := map[string]*Template{
+		"moteur": {Name: "moteur", Fields: map[string]FieldDef{
+			"volts":     {Required: true, Domain: "tension", DefaultUnit: "V"},
+			"watts":     {Required: true, Domain: "puissance", DefaultUnit: "W"},
+			"courant":   {Required: true, Domain: "courant", DefaultUnit: "A", Compute: "round(watts / volts, 2)"},
+			"categorie": {Compute: "if(courant > 2, 'puissant', 'petit')"},
+		}},
+		"vis": {Name: "vis", Fields: map[string]FieldDef{
+			"diametre": {Required: true, Domain: "dimension", DefaultUnit: "mm"},
+			"pas":      {Domain: "dimension", DefaultUnit: "mm", Compute: "iso_pitch(diametre)", Override: true},
+		}},
+	}
+	setTestTemplates(t, raw)
+}
+
+func TestComputePropsChained(t *testing.T) {
+	seedComputedTemplates(t)
+
+	props, err := NormalizePartProps("moteur", map[string]interface{}{"volts": "12V", "watts": "30W"})
+	if err != nil {
+		t.Fatalf("normalize: %v", err)
+	}
+	if err := ValidateProps("moteur", props); err != nil {
+		t.Fatalf("computed required field should not be asked: %v", err)
+	}
+	ComputeProps("moteur", props)
+	if props["courant"] != 2.5 || props["categorie"] != "puissant" {
+		t.Fatalf("unexpected computed props: %v", props)
+	}
+
+	// Sans override, une valeur saisie est recalculée; un calcul impossible retire le champ
+	props = map[string]interface{}{"volts": float64(12), "courant": float64(99)}
+	ComputeProps("moteur", props)
+	if _, ok := props["courant"]; ok {
+		t.Fatalf("expected courant removed when watts is missing: %v", props)
+	}
+}
+
+func TestComputePropsOverride(t *testing.T) {
+	seedComputedTemplates(t)
+
+	props := map[string]interface{}{"diametre": "M6"}
+	ComputeProps("vis", props)
+	if props["pas"] != float64(1) {
+		t.Fatalf("expected ISO coarse pitch, got %v", props["pas"])
+	}
+
+	props = map[string]interface{}{"diametre": "M6", "pas": 0.75}
+	ComputeProps("vis", props)
+	if props["pas"] != 0.75 {
+		t.Fatalf("expected user pitch kept, got %v", props["pas"])
+	}
+}
+
+func TestCreatePartStoresComputedFields(t *testing.T) {
+	seedComputedTemplates(t)
+	db := newTestDB(t)
+	defer db.Close()
+
+	id, err := CreatePart(db, "vis", "Vis M8", `{"diametre":8}`, nil, 10)
+	if err != nil {
+		t.Fatalf("create part: %v", err)
+	}
+	meta, err := EditPart(db, int(id), PartUpdate{Props: map[string]interface{}{"diametre": "M10"}})
+	if err != nil {
+		t.Fatalf("edit part: %v", err)
+	}
+	if !strings.Contains(meta.PropsJSON, `"pas":1.5`) {
+		t.Fatalf("expected pitch computed on edit, got %s", meta.PropsJSON)
+	}
+}
+
+func TestResolveTemplatesRejectsInvalidComputedFields(t *testing.T) {
+	cases := map[string]map[string]FieldDef{
+		"syntaxe":        {"a": {Compute: "round("}},
+		"champ inconnu":  {"a": {Compute: "b * 2"}},
+		"fonction":       {"a": {Compute: "system('ls')"}},
+		"cycle":          {"a": {Compute: "b + 1"}, "b": {Compute: "a + 1"}},
+		"auto-référence": {"a": {Compute: "a + 1"}},
+	}
+	for label, fields := range cases {
+		raw := map[string]*Template{"t": {Name: "t", Fields: fields}}
+		if _, err := ResolveTemplates(raw); err == nil {
+			t.Fatalf("%s: expected error", label)
+		}
+	}
+}
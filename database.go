@@ -54,6 +54,81 @@ func runMigrations(db *sql.DB) error {
 		return err
 	}
 
+	// Migration v7: Ajout colonne quantity sur parts
+	if err := migrateV7(db); err != nil {
+		return err
+	}
+
+	// Migration v8: Registre des mouvements de stock
+	if err := migrateV8(db); err != nil {
+		return err
+	}
+
+	// Migration v9: Corbeille (suppression réversible des pièces)
+	if err := migrateV9(db); err != nil {
+		return err
+	}
+
+	// Migration v10: Historique des modifications (append-only)
+	if err := migrateV10(db); err != nil {
+		return err
+	}
+
+	// Migration v11: État du cycle de vie des pièces
+	if err := migrateV11(db); err != nil {
+		return err
+	}
+
+	// Migration v12: Appareils donneurs et origine des pièces
+	if err := migrateV12(db); err != nil {
+		return err
+	}
+
+	// Migration v13: Nomenclature (sous-ensembles parent/enfant)
+	if err := migrateV13(db); err != nil {
+		return err
+	}
+
+	// Migration v14: Tags libres sur les pièces
+	if err := migrateV14(db); err != nil {
+		return err
+	}
+
+	// Migration v15: Redirections des pièces fusionnées (doublons)
+	if err := migrateV15(db); err != nil {
+		return err
+	}
+
+	// Migration v16: Prêts de pièces et d'outils
+	if err := migrateV16(db); err != nil {
+		return err
+	}
+
+	// Migration v17: Seuil de stock minimum par pièce
+	if err := migrateV17(db); err != nil {
+		return err
+	}
+
+	// Migration v18: Valeur unitaire estimée et devise
+	if err := migrateV18(db); err != nil {
+		return err
+	}
+
+	// Migration v19: Version du template des propriétés de chaque pièce
+	if err := migrateV19(db); err != nil {
+		return err
+	}
+
+	// Migration v20: Templates créés ou modifiés depuis la CLI ou l'API
+	if err := migrateV20(db); err != nil {
+		return err
+	}
+
+	// Migration v21: Saisie d'origine et unité de base des props numériques
+	if err := migrateV21(db); err != nil {
+		return err
+	}
+
 	// Index
 	if err := createIndexes(db); err != nil {
 		return err
@@ -141,6 +216,222 @@ func migrateV6(db *sql.DB) error {
 	return err
 }
 
+// migrateV7 ajoute la colonne quantity sur parts (1 par défaut: une ligne = un objet)
+func migrateV7(db *sql.DB) error {
+	if hasColumn(db, "parts", "quantity") {
+		return nil
+	}
+
+	_, err := db.Exec("ALTER TABLE parts ADD COLUMN quantity INTEGER NOT NULL DEFAULT 1")
+	return err
+}
+
+// migrateV8 crée le registre des mouvements de stock (entrées, sorties, ajustements)
+func migrateV8(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS stock_movements (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			part_id INTEGER NOT NULL,
+			kind TEXT NOT NULL,
+			delta INTEGER NOT NULL,
+			balance INTEGER NOT NULL,
+			reason TEXT DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (part_id) REFERENCES parts(id) ON DELETE CASCADE
+		)
+	`)
+	return err
+}
+
+// migrateV9 ajoute la colonne deleted_at sur parts (NULL = pièce active)
+func migrateV9(db *sql.DB) error {
+	if hasColumn(db, "parts", "deleted_at") {
+		return nil
+	}
+
+	_, err := db.Exec("ALTER TABLE parts ADD COLUMN deleted_at DATETIME")
+	return err
+}
+
+// migrateV10 crée la table d'historique des modifications (pièces et localisations)
+func migrateV10(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS history (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			entity TEXT NOT NULL,
+			entity_id INTEGER NOT NULL,
+			action TEXT NOT NULL,
+			before JSON,
+			after JSON,
+			actor TEXT DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	return err
+}
+
+// migrateV11 ajoute la colonne state sur parts (les pièces existantes sont "untested")
+func migrateV11(db *sql.DB) error {
+	if hasColumn(db, "parts", "state") {
+		return nil
+	}
+
+	_, err := db.Exec("ALTER TABLE parts ADD COLUMN state TEXT NOT NULL DEFAULT 'untested'")
+	return err
+}
+
+// migrateV12 crée la table des appareils donneurs et ajoute donor_id sur parts
+func migrateV12(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS donors (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			category TEXT NOT NULL DEFAULT '',
+			brand TEXT NOT NULL DEFAULT '',
+			model TEXT NOT NULL DEFAULT '',
+			notes TEXT NOT NULL DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	if hasColumn(db, "parts", "donor_id") {
+		return nil
+	}
+
+	_, err = db.Exec("ALTER TABLE parts ADD COLUMN donor_id INTEGER REFERENCES donors(id) ON DELETE SET NULL")
+	return err
+}
+
+// migrateV13 crée la table de composition des pièces (parent contient quantity × enfant)
+func migrateV13(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS part_components (
+			parent_id INTEGER NOT NULL,
+			child_id INTEGER NOT NULL,
+			quantity INTEGER NOT NULL DEFAULT 1,
+			PRIMARY KEY (parent_id, child_id),
+			FOREIGN KEY (parent_id) REFERENCES parts(id) ON DELETE CASCADE,
+			FOREIGN KEY (child_id) REFERENCES parts(id) ON DELETE CASCADE
+		)
+	`)
+	return err
+}
+
+// migrateV14 crée les tables de tags (relation many-to-many avec parts)
+func migrateV14(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS tags (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL UNIQUE
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS part_tags (
+			part_id INTEGER NOT NULL,
+			tag_id INTEGER NOT NULL,
+			PRIMARY KEY (part_id, tag_id),
+			FOREIGN KEY (part_id) REFERENCES parts(id) ON DELETE CASCADE,
+			FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE
+		)
+	`)
+	return err
+}
+
+// migrateV15 crée la table de redirection des IDs fusionnés (anciennes étiquettes PRT-<id>)
+func migrateV15(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS part_redirects (
+			old_id INTEGER PRIMARY KEY,
+			new_id INTEGER NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	return err
+}
+
+// migrateV16 crée la table des prêts (sortie/retour d'une pièce par un membre)
+func migrateV16(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS loans (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			part_id INTEGER NOT NULL,
+			borrower TEXT NOT NULL,
+			quantity INTEGER NOT NULL DEFAULT 1,
+			due_date TEXT NOT NULL,
+			notes TEXT NOT NULL DEFAULT '',
+			out_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			returned_at DATETIME,
+			FOREIGN KEY (part_id) REFERENCES parts(id) ON DELETE CASCADE
+		)
+	`)
+	return err
+}
+
+// migrateV17 ajoute le seuil de stock minimum propre à une pièce
+// (NULL: seuil du template, 0: pas d'alerte)
+func migrateV17(db *sql.DB) error {
+	if hasColumn(db, "parts", "min_stock") {
+		return nil
+	}
+	_, err := db.Exec("ALTER TABLE parts ADD COLUMN min_stock INTEGER")
+	return err
+}
+
+// migrateV18 ajoute la valeur unitaire estimée d'une pièce et sa devise (NULL: non valorisée)
+func migrateV18(db *sql.DB) error {
+	if !hasColumn(db, "parts", "unit_value") {
+		if _, err := db.Exec("ALTER TABLE parts ADD COLUMN unit_value REAL"); err != nil {
+			return err
+		}
+	}
+	if !hasColumn(db, "parts", "currency") {
+		if _, err := db.Exec("ALTER TABLE parts ADD COLUMN currency TEXT"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateV19 ajoute la version du template selon laquelle les props d'une pièce sont écrites
+// (les pièces existantes sont en version 1, voir recycle templates migrate)
+func migrateV19(db *sql.DB) error {
+	if hasColumn(db, "parts", "template_version") {
+		return nil
+	}
+	_, err := db.Exec("ALTER TABLE parts ADD COLUMN template_version INTEGER NOT NULL DEFAULT 1")
+	return err
+}
+
+// migrateV20 crée la table des templates enregistrés en base (définition YAML),
+// prioritaires sur les fichiers du dossier templates et les templates embarqués
+func migrateV20(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS templates (
+			name TEXT PRIMARY KEY,
+			definition TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	return err
+}
+
+// migrateV21 ajoute, par pièce, la saisie d'origine et l'unité de base de chaque prop
+// normalisée ({"d_int": {"original": "1/4 inch", "unit": "mm"}}, voir units.go)
+func migrateV21(db *sql.DB) error {
+	if hasColumn(db, "parts", "props_units") {
+		return nil
+	}
+	_, err := db.Exec("ALTER TABLE parts ADD COLUMN props_units JSON")
+	return err
+}
+
 func createIndexes(db *sql.DB) error {
 	indexes := []string{
 		"CREATE INDEX IF NOT EXISTS idx_parts_name ON parts (name)",
@@ -149,6 +440,15 @@ func createIndexes(db *sql.DB) error {
 		"CREATE INDEX IF NOT EXISTS idx_attachments_part_id ON attachments (part_id)",
 		"CREATE INDEX IF NOT EXISTS idx_locations_parent ON locations (parent_id)",
 		"CREATE INDEX IF NOT EXISTS idx_peers_url ON peers (url)",
+		"CREATE INDEX IF NOT EXISTS idx_stock_movements_part ON stock_movements (part_id)",
+		"CREATE INDEX IF NOT EXISTS idx_history_entity ON history (entity, entity_id)",
+		"CREATE INDEX IF NOT EXISTS idx_parts_state ON parts (state)",
+		"CREATE INDEX IF NOT EXISTS idx_parts_donor ON parts (donor_id)",
+		"CREATE INDEX IF NOT EXISTS idx_donors_model ON donors (category, brand, model)",
+		"CREATE INDEX IF NOT EXISTS idx_part_components_child ON part_components (child_id)",
+		"CREATE INDEX IF NOT EXISTS idx_part_tags_tag ON part_tags (tag_id)",
+		"CREATE INDEX IF NOT EXISTS idx_part_redirects_new ON part_redirects (new_id)",
+		"CREATE INDEX IF NOT EXISTS idx_loans_part ON loans (part_id, returned_at)",
 	}
 
 	for _, idx := range indexes {
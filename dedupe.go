@@ -0,0 +1,366 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// DefaultDedupeThreshold est la similarité de nom minimale (0..1) pour proposer un doublon
+const DefaultDedupeThreshold = 0.6
+
+// DuplicateGroup regroupe des pièces candidates à la fusion (triées par ID)
+type DuplicateGroup struct {
+	Type  string
+	Parts []PartRecord
+	Score float64 // Similarité de nom minimale observée dans le groupe
+}
+
+// NameSimilarity retourne la similarité (0..1) entre deux noms, basée sur la distance
+// de Levenshtein après normalisation (casse, ponctuation, espaces)
+func NameSimilarity(a, b string) float64 {
+	ra := []rune(normalizeNameForDedupe(a))
+	rb := []rune(normalizeNameForDedupe(b))
+	if len(ra) == 0 && len(rb) == 0 {
+		return 1
+	}
+
+	maxLen := len(ra)
+	if len(rb) > maxLen {
+		maxLen = len(rb)
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(maxLen)
+}
+
+// normalizeNameForDedupe met un nom en minuscules et remplace la ponctuation par des espaces
+func normalizeNameForDedupe(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune(' ')
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(prev[j]+1, minInt(cur[j-1]+1, prev[j-1]+cost))
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// dedupeKeyFields retourne les champs comparés pour un type: les champs requis du template,
+// ou nil (toutes les propriétés) si le type n'a pas de template
+func dedupeKeyFields(typeName string) []string {
+	if tmpl, ok := Templates.Get(typeName); ok && len(tmpl.Required) > 0 {
+		return tmpl.Required
+	}
+	return nil
+}
+
+// propsEqual compare les propriétés normalisées de deux pièces sur les champs donnés
+// (toutes les propriétés si fields est nil)
+func propsEqual(a, b map[string]interface{}, fields []string) bool {
+	if fields == nil {
+		return reflect.DeepEqual(a, b)
+	}
+	for _, f := range fields {
+		if !reflect.DeepEqual(a[f], b[f]) {
+			return false
+		}
+	}
+	return true
+}
+
+func parsePartProps(p PartRecord) map[string]interface{} {
+	props := map[string]interface{}{}
+	if p.Props.Valid && p.Props.String != "" {
+		json.Unmarshal([]byte(p.Props.String), &props)
+	}
+	return props
+}
+
+// FindDuplicates cherche les doublons probables: même type, propriétés normalisées égales
+// (champs requis du template) et noms similaires au-delà du seuil.
+// typeName (optionnel) restreint la recherche à un type.
+func FindDuplicates(db *sql.DB, typeName string, threshold float64) ([]DuplicateGroup, error) {
+	parts, err := SearchPartsDB(db, SearchFilters{Type: typeName})
+	if err != nil {
+		return nil, err
+	}
+
+	byType := make(map[string][]PartRecord)
+	var types []string
+	for _, p := range parts {
+		if _, ok := byType[p.Type]; !ok {
+			types = append(types, p.Type)
+		}
+		byType[p.Type] = append(byType[p.Type], p)
+	}
+	sort.Strings(types)
+
+	var groups []DuplicateGroup
+	for _, t := range types {
+		candidates := byType[t]
+		fields := dedupeKeyFields(t)
+		props := make([]map[string]interface{}, len(candidates))
+		for i, p := range candidates {
+			props[i] = parsePartProps(p)
+		}
+
+		// Union-find: les paires similaires sont regroupées
+		parent := make([]int, len(candidates))
+		for i := range parent {
+			parent[i] = i
+		}
+		var find func(int) int
+		find = func(i int) int {
+			for parent[i] != i {
+				parent[i] = parent[parent[i]]
+				i = parent[i]
+			}
+			return i
+		}
+
+		type pair struct {
+			i   int
+			sim float64
+		}
+		var pairs []pair
+		for i := 0; i < len(candidates); i++ {
+			for j := i + 1; j < len(candidates); j++ {
+				if !propsEqual(props[i], props[j], fields) {
+					continue
+				}
+				sim := NameSimilarity(candidates[i].Name, candidates[j].Name)
+				if sim < threshold {
+					continue
+				}
+				if ri, rj := find(i), find(j); ri != rj {
+					parent[rj] = ri
+				}
+				pairs = append(pairs, pair{i: i, sim: sim})
+			}
+		}
+
+		members := make(map[int][]PartRecord)
+		var roots []int
+		for i, p := range candidates {
+			r := find(i)
+			if _, ok := members[r]; !ok {
+				roots = append(roots, r)
+			}
+			members[r] = append(members[r], p)
+		}
+		scores := make(map[int]float64)
+		for _, p := range pairs {
+			r := find(p.i)
+			if s, ok := scores[r]; !ok || p.sim < s {
+				scores[r] = p.sim
+			}
+		}
+		for _, r := range roots {
+			if len(members[r]) < 2 {
+				continue
+			}
+			groups = append(groups, DuplicateGroup{Type: t, Parts: members[r], Score: scores[r]})
+		}
+	}
+
+	return groups, nil
+}
+
+// MergeParts fusionne des doublons dans une pièce survivante:
+// les propriétés manquantes sont complétées, les quantités additionnées, les fichiers attachés,
+// tags, prêts et liens de nomenclature déplacés. Les IDs fusionnés redirigent vers le survivant.
+func MergeParts(db *sql.DB, survivorID int, duplicateIDs []int) error {
+	survivor, err := GetPartMeta(db, survivorID)
+	if err != nil {
+		return err
+	}
+	if !survivor.Found {
+		return fmt.Errorf("pièce ID %d introuvable", survivorID)
+	}
+
+	merged := map[string]interface{}{}
+	if survivor.PropsJSON != "" {
+		if err := json.Unmarshal([]byte(survivor.PropsJSON), &merged); err != nil {
+			return fmt.Errorf("props invalides (ID %d): %v", survivorID, err)
+		}
+	}
+	mergedUnits := map[string]PropUnit{}
+	for k, u := range survivor.PropUnits {
+		mergedUnits[k] = u
+	}
+
+	var duplicates []*PartMeta
+	for _, id := range duplicateIDs {
+		if id == survivorID {
+			return fmt.Errorf("la pièce ID %d ne peut pas être fusionnée avec elle-même", id)
+		}
+		dup, err := GetPartMeta(db, id)
+		if err != nil {
+			return err
+		}
+		if !dup.Found {
+			return fmt.Errorf("pièce ID %d introuvable", id)
+		}
+		if dup.Type != survivor.Type {
+			return fmt.Errorf("types différents: ID %d (%s) et ID %d (%s)", survivorID, survivor.Type, id, dup.Type)
+		}
+		duplicates = append(duplicates, dup)
+	}
+	if len(duplicates) == 0 {
+		return fmt.Errorf("aucun doublon à fusionner")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := snapshotPart(tx, survivorID)
+	if err != nil {
+		return err
+	}
+
+	quantity := survivor.Quantity
+	locationID := survivor.LocationID
+	donorID := survivor.DonorID
+
+	for _, dup := range duplicates {
+		// Compléter les propriétés absentes du survivant
+		dupProps := map[string]interface{}{}
+		if dup.PropsJSON != "" {
+			json.Unmarshal([]byte(dup.PropsJSON), &dupProps)
+		}
+		for k, v := range dupProps {
+			if _, ok := merged[k]; !ok {
+				merged[k] = v
+				// La prop reprise garde sa saisie d'origine et son unité
+				if u, ok := dup.PropUnits[k]; ok {
+					mergedUnits[k] = u
+				}
+			}
+		}
+		if !locationID.Valid {
+			locationID = dup.LocationID
+		}
+		if !donorID.Valid {
+			donorID = dup.DonorID
+		}
+
+		if dup.Quantity > 0 {
+			quantity += dup.Quantity
+			if err := insertStockMovement(tx, survivorID, MovementIn, dup.Quantity, quantity, fmt.Sprintf("fusion PRT-%d", dup.ID)); err != nil {
+				return err
+			}
+		}
+
+		dupBefore, err := snapshotPart(tx, dup.ID)
+		if err != nil {
+			return err
+		}
+
+		statements := []string{
+			"UPDATE attachments SET part_id = ?1 WHERE part_id = ?2",
+			"UPDATE loans SET part_id = ?1 WHERE part_id = ?2",
+			"INSERT OR IGNORE INTO part_tags (part_id, tag_id) SELECT ?1, tag_id FROM part_tags WHERE part_id = ?2",
+			"DELETE FROM part_tags WHERE part_id = ?2",
+			"INSERT OR IGNORE INTO part_components (parent_id, child_id, quantity) SELECT ?1, child_id, quantity FROM part_components WHERE parent_id = ?2 AND child_id != ?1",
+			"INSERT OR IGNORE INTO part_components (parent_id, child_id, quantity) SELECT parent_id, ?1, quantity FROM part_components WHERE child_id = ?2 AND parent_id != ?1",
+			"DELETE FROM part_components WHERE parent_id = ?2 OR child_id = ?2",
+			"DELETE FROM stock_movements WHERE part_id = ?2",
+			"UPDATE part_redirects SET new_id = ?1 WHERE new_id = ?2",
+			"INSERT OR REPLACE INTO part_redirects (old_id, new_id) VALUES (?2, ?1)",
+			"DELETE FROM parts WHERE id = ?2",
+		}
+		for _, stmt := range statements {
+			if _, err := tx.Exec(stmt, survivorID, dup.ID); err != nil {
+				return err
+			}
+		}
+
+		if err := recordHistory(tx, HistoryEntityPart, dup.ID, "merged", dupBefore, nil); err != nil {
+			return err
+		}
+	}
+
+	mergedJSON, err := json.Marshal(merged)
+	if err != nil {
+		return fmt.Errorf("erreur sérialisation: %v", err)
+	}
+	unitsJSON, err := encodePropUnits(mergedUnits)
+	if err != nil {
+		return fmt.Errorf("erreur sérialisation: %v", err)
+	}
+	_, err = tx.Exec("UPDATE parts SET props = ?, props_units = ?, quantity = ?, location_id = ?, donor_id = ? WHERE id = ?",
+		string(mergedJSON), unitsJSON, quantity, locationID, donorID, survivorID)
+	if err != nil {
+		return err
+	}
+
+	after, err := snapshotPart(tx, survivorID)
+	if err != nil {
+		return err
+	}
+	if err := recordHistory(tx, HistoryEntityPart, survivorID, "merge", before, after); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ResolvePartRedirect retourne l'ID de la pièce qui a absorbé partID lors d'une fusion
+// (ok = false si partID n'a pas été fusionnée)
+func ResolvePartRedirect(db *sql.DB, partID int) (int, bool, error) {
+	var newID int
+	err := db.QueryRow("SELECT new_id FROM part_redirects WHERE old_id = ?", partID).Scan(&newID)
+	if err == sql.ErrNoRows {
+		return partID, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return newID, true, nil
+}
+
+// PrintDuplicateGroup affiche un groupe de doublons
+func PrintDuplicateGroup(g DuplicateGroup, index int) {
+	fmt.Printf("\n▸ Groupe %d — %s (similarité des noms ≥ %.0f%%)\n", index, displayTypeName(g.Type), g.Score*100)
+	for _, p := range g.Parts {
+		props := "{}"
+		if p.Props.Valid {
+			props = p.Props.String
+		}
+		fmt.Printf("    [%d] %s ×%d %s\n", p.ID, p.Name, p.Quantity, truncate(props, 50))
+	}
+}
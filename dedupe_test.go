@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestNameSimilarity(t *testing.T) {
+	if s := NameSimilarity("SKF 6204-2Z", "skf 6204 2z"); s != 1 {
+		t.Fatalf("expected identical names after normalization, got %.2f", s)
+	}
+	if s := NameSimilarity("Roulement 6204", "Moteur essuie-glace"); s >= DefaultDedupeThreshold {
+		t.Fatalf("expected unrelated names below threshold, got %.2f", s)
+	}
+}
+
+func TestFindDuplicatesAndMerge(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+
+	seedTemplates()
+
+	a, _ := CreatePart(db, "bearing", "SKF 6204-2Z", `{"d_int":20,"d_ext":47,"width":14}`, nil, 2)
+	b, _ := CreatePart(db, "bearing", "SKF 6204 2Z", `{"d_int":20,"d_ext":47,"width":14,"brand":"SKF"}`, nil, 3)
+	CreatePart(db, "bearing", "SKF 6205-2Z", `{"d_int":25,"d_ext":52,"width":15}`, nil, 1)
+	CreatePart(db, "bearing", "Roulement récup DVD", `{"d_int":20,"d_ext":47,"width":14}`, nil, 1)
+
+	if err := AddPartTags(db, int(b), []string{"to-sort"}); err != nil {
+		t.Fatalf("add tag: %v", err)
+	}
+
+	groups, err := FindDuplicates(db, "", DefaultDedupeThreshold)
+	if err != nil {
+		t.Fatalf("find duplicates: %v", err)
+	}
+	if len(groups) != 1 || len(groups[0].Parts) != 2 {
+		t.Fatalf("expected one group of 2 parts, got %+v", groups)
+	}
+	if groups[0].Parts[0].ID != int(a) || groups[0].Parts[1].ID != int(b) {
+		t.Fatalf("unexpected group members: %+v", groups[0].Parts)
+	}
+
+	if err := MergeParts(db, int(a), []int{int(b)}); err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+
+	meta, _ := GetPartMeta(db, int(a))
+	if meta.Quantity != 5 {
+		t.Fatalf("expected merged quantity 5, got %d", meta.Quantity)
+	}
+	var props map[string]interface{}
+	json.Unmarshal([]byte(meta.PropsJSON), &props)
+	if props["brand"] != "SKF" {
+		t.Fatalf("expected missing props to be combined, got %v", props)
+	}
+	if len(meta.Tags) != 1 || meta.Tags[0] != "to-sort" {
+		t.Fatalf("expected tags moved to survivor, got %v", meta.Tags)
+	}
+
+	old, _ := GetPartMeta(db, int(b))
+	if old.Found {
+		t.Fatalf("merged-away part should no longer exist")
+	}
+	newID, ok, err := ResolvePartRedirect(db, int(b))
+	if err != nil || !ok || newID != int(a) {
+		t.Fatalf("expected redirect %d -> %d, got %d (ok=%v, err=%v)", b, a, newID, ok, err)
+	}
+
+	// Une seconde fusion fait suivre les redirections existantes
+	c, _ := CreatePart(db, "bearing", "SKF 6204-2Z", `{"d_int":20,"d_ext":47,"width":14}`, nil, 1)
+	if err := MergeParts(db, int(c), []int{int(a)}); err != nil {
+		t.Fatalf("second merge: %v", err)
+	}
+	if newID, _, _ := ResolvePartRedirect(db, int(b)); newID != int(c) {
+		t.Fatalf("expected chained redirect to %d, got %d", c, newID)
+	}
+}
+
+func TestMergePartsKeepsPropUnits(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+
+	seedTemplates()
+
+	a, _ := CreatePart(db, "bearing", "SKF 6204-2Z", `{"d_int":20,"d_ext":47}`, nil, 1)
+	b, _ := CreatePart(db, "bearing", "SKF 6204 2Z", `{"d_int":19.05,"d_ext":47,"width":14}`, nil, 1)
+	SetPropUnits(db, int(a), map[string]PropUnit{"d_int": {Original: "2cm", Unit: "mm"}})
+	SetPropUnits(db, int(b), map[string]PropUnit{"d_int": {Original: "3/4in", Unit: "mm"}, "width": {Original: "1.4cm", Unit: "mm"}})
+
+	if err := MergeParts(db, int(a), []int{int(b)}); err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+
+	meta, _ := GetPartMeta(db, int(a))
+	if meta.PropUnits["width"].Original != "1.4cm" {
+		t.Fatalf("expected width units taken from duplicate, got %+v", meta.PropUnits)
+	}
+	if meta.PropUnits["d_int"].Original != "2cm" {
+		t.Fatalf("survivor units must win, got %+v", meta.PropUnits)
+	}
+}
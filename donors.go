@@ -0,0 +1,311 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Donor représente un appareil donneur (imprimante, lecteur DVD, lave-linge...) démonté pour ses pièces
+type Donor struct {
+	ID        int
+	Category  string // Catégorie d'appareil (imprimante, lecteur DVD...)
+	Brand     string
+	Model     string
+	Notes     string
+	CreatedAt string
+	PartCount int // Nombre de pièces récupérées (hors corbeille)
+}
+
+// Label retourne une désignation lisible de l'appareil (catégorie marque modèle)
+func (d *Donor) Label() string {
+	var fields []string
+	for _, f := range []string{d.Category, d.Brand, d.Model} {
+		if f != "" {
+			fields = append(fields, f)
+		}
+	}
+	if len(fields) == 0 {
+		return fmt.Sprintf("Appareil #%d", d.ID)
+	}
+	return strings.Join(fields, " ")
+}
+
+// DonorTypeStats résume, pour un modèle d'appareil, ce qui est récupéré pour un type de pièce
+type DonorTypeStats struct {
+	Type     string
+	Donors   int // Nombre d'appareils ayant fourni ce type de pièce
+	Quantity int // Quantité totale récupérée
+	Working  int // Dont fonctionnelles
+	Broken   int // Dont en panne
+	Untested int // Dont non testées
+	Spare    int // Dont conservées pour pièces détachées
+}
+
+// DonorModelStats regroupe les statistiques de récupération d'un modèle d'appareil
+type DonorModelStats struct {
+	Category string
+	Brand    string
+	Model    string
+	Donors   int // Nombre d'appareils de ce modèle démontés
+	Types    []DonorTypeStats
+}
+
+// CreateDonor enregistre un nouvel appareil donneur
+func CreateDonor(db *sql.DB, category, brand, model, notes string) (*Donor, error) {
+	if category == "" && model == "" {
+		return nil, fmt.Errorf("la catégorie ou le modèle de l'appareil est requis")
+	}
+
+	res, err := db.Exec(`
+		INSERT INTO donors (category, brand, model, notes)
+		VALUES (?, ?, ?, ?)
+	`, category, brand, model, notes)
+	if err != nil {
+		return nil, err
+	}
+
+	id, _ := res.LastInsertId()
+	return GetDonor(db, int(id))
+}
+
+// GetDonor retourne un appareil donneur par son ID
+func GetDonor(db *sql.DB, id int) (*Donor, error) {
+	var d Donor
+	err := db.QueryRow(`
+		SELECT d.id, d.category, d.brand, d.model, d.notes, d.created_at,
+			(SELECT COUNT(*) FROM parts p WHERE p.donor_id = d.id AND p.deleted_at IS NULL)
+		FROM donors d
+		WHERE d.id = ?
+	`, id).Scan(&d.ID, &d.Category, &d.Brand, &d.Model, &d.Notes, &d.CreatedAt, &d.PartCount)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("appareil donneur ID %d introuvable", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// ListDonors retourne tous les appareils donneurs (plus récents en premier)
+func ListDonors(db *sql.DB) ([]Donor, error) {
+	rows, err := db.Query(`
+		SELECT d.id, d.category, d.brand, d.model, d.notes, d.created_at,
+			(SELECT COUNT(*) FROM parts p WHERE p.donor_id = d.id AND p.deleted_at IS NULL)
+		FROM donors d
+		ORDER BY d.id DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var donors []Donor
+	for rows.Next() {
+		var d Donor
+		if err := rows.Scan(&d.ID, &d.Category, &d.Brand, &d.Model, &d.Notes, &d.CreatedAt, &d.PartCount); err != nil {
+			return nil, err
+		}
+		donors = append(donors, d)
+	}
+
+	return donors, nil
+}
+
+// SetPartDonor rattache une pièce à l'appareil dont elle a été récupérée
+func SetPartDonor(db *sql.DB, partID, donorID int) error {
+	if _, err := GetDonor(db, donorID); err != nil {
+		return err
+	}
+	return setPartDonorValue(db, partID, donorID)
+}
+
+// ClearPartDonor retire le lien entre une pièce et son appareil d'origine
+func ClearPartDonor(db *sql.DB, partID int) error {
+	return setPartDonorValue(db, partID, nil)
+}
+
+func setPartDonorValue(db *sql.DB, partID int, donorID interface{}) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := snapshotPart(tx, partID)
+	if err != nil {
+		return err
+	}
+	if before == nil || before.DeletedAt != nil {
+		return fmt.Errorf("pièce ID %d introuvable", partID)
+	}
+
+	if _, err := tx.Exec("UPDATE parts SET donor_id = ? WHERE id = ?", donorID, partID); err != nil {
+		return err
+	}
+
+	after, err := snapshotPart(tx, partID)
+	if err != nil {
+		return err
+	}
+	if err := recordHistory(tx, HistoryEntityPart, partID, "harvest", before, after); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ListDonorParts retourne les pièces récupérées sur un appareil (hors corbeille)
+func ListDonorParts(db *sql.DB, donorID int) ([]PartRecord, error) {
+	rows, err := db.Query(`
+		SELECT id, type, name, props, props_units, location_id, quantity, state
+		FROM parts
+		WHERE donor_id = ? AND deleted_at IS NULL
+		ORDER BY id
+	`, donorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var parts []PartRecord
+	for rows.Next() {
+		var p PartRecord
+		if err := rows.Scan(&p.ID, &p.Type, &p.Name, &p.Props, &p.PropUnits, &p.LocationID, &p.Quantity, &p.State); err != nil {
+			return nil, err
+		}
+		parts = append(parts, p)
+	}
+
+	return parts, nil
+}
+
+// GetDonorModelStats calcule, par modèle d'appareil, les types de pièces récupérés
+// et leur état. modelFilter (optionnel) restreint aux modèles contenant ce texte.
+func GetDonorModelStats(db *sql.DB, modelFilter string) ([]DonorModelStats, error) {
+	rows, err := db.Query(`
+		SELECT category, brand, model, COUNT(*)
+		FROM donors
+		WHERE ? = '' OR model LIKE '%' || ? || '%'
+		GROUP BY category, brand, model
+		ORDER BY category, brand, model
+	`, modelFilter, modelFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	var stats []DonorModelStats
+	index := make(map[string]int)
+	for rows.Next() {
+		var s DonorModelStats
+		if err := rows.Scan(&s.Category, &s.Brand, &s.Model, &s.Donors); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		index[donorModelKey(s.Category, s.Brand, s.Model)] = len(stats)
+		stats = append(stats, s)
+	}
+	rows.Close()
+
+	rows, err = db.Query(`
+		SELECT d.category, d.brand, d.model, p.type,
+			COUNT(DISTINCT d.id),
+			SUM(p.quantity),
+			SUM(CASE WHEN p.state = 'working' THEN p.quantity ELSE 0 END),
+			SUM(CASE WHEN p.state = 'broken' THEN p.quantity ELSE 0 END),
+			SUM(CASE WHEN p.state = 'untested' THEN p.quantity ELSE 0 END),
+			SUM(CASE WHEN p.state = 'spare' THEN p.quantity ELSE 0 END)
+		FROM donors d
+		JOIN parts p ON p.donor_id = d.id AND p.deleted_at IS NULL
+		GROUP BY d.category, d.brand, d.model, p.type
+		ORDER BY COUNT(DISTINCT d.id) DESC, p.type
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var category, brand, model string
+		var t DonorTypeStats
+		if err := rows.Scan(&category, &brand, &model, &t.Type, &t.Donors, &t.Quantity, &t.Working, &t.Broken, &t.Untested, &t.Spare); err != nil {
+			return nil, err
+		}
+		i, ok := index[donorModelKey(category, brand, model)]
+		if !ok {
+			continue // Modèle exclu par le filtre
+		}
+		stats[i].Types = append(stats[i].Types, t)
+	}
+
+	return stats, nil
+}
+
+func donorModelKey(category, brand, model string) string {
+	return category + "\x00" + brand + "\x00" + model
+}
+
+// PrintDonors affiche la liste des appareils donneurs
+func PrintDonors(db *sql.DB) error {
+	donors, err := ListDonors(db)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("\n🔧 Appareils donneurs:")
+	fmt.Println(strings.Repeat("─", 60))
+
+	if len(donors) == 0 {
+		fmt.Println("  Aucun appareil enregistré")
+		fmt.Println("  Créez-en un avec: recycle harvest new --category=imprimante --model=\"DeskJet 2130\"")
+		fmt.Println()
+		return nil
+	}
+
+	for _, d := range donors {
+		fmt.Printf("  [%d] %s — %d pièce(s) récupérée(s)\n", d.ID, d.Label(), d.PartCount)
+		if d.Notes != "" {
+			fmt.Printf("       %s\n", d.Notes)
+		}
+	}
+
+	fmt.Printf("\n%d appareil(s)\n\n", len(donors))
+	return nil
+}
+
+// PrintDonorModelStats affiche les statistiques de récupération par modèle d'appareil
+func PrintDonorModelStats(db *sql.DB, modelFilter string) error {
+	stats, err := GetDonorModelStats(db, modelFilter)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("\n📊 Récupération par modèle d'appareil:")
+	fmt.Println(strings.Repeat("─", 60))
+
+	if len(stats) == 0 {
+		fmt.Println("  Aucun appareil enregistré")
+		fmt.Println()
+		return nil
+	}
+
+	for _, s := range stats {
+		d := Donor{Category: s.Category, Brand: s.Brand, Model: s.Model}
+		fmt.Printf("\n▸ %s (%d appareil(s))\n", d.Label(), s.Donors)
+		if len(s.Types) == 0 {
+			fmt.Println("    Aucune pièce récupérée")
+			continue
+		}
+		for _, t := range s.Types {
+			typeName := t.Type
+			if typeName == "" {
+				typeName = "(sans type)"
+			}
+			fmt.Printf("    %-14s %d/%d appareil(s) · %d pièce(s): %d fonctionnelle(s), %d en panne, %d non testée(s), %d pour pièces\n",
+				typeName, t.Donors, s.Donors, t.Quantity, t.Working, t.Broken, t.Untested, t.Spare)
+		}
+	}
+
+	fmt.Println()
+	return nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import "testing"
+
+func TestDonorPartsAndModelStats(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+
+	first, err := CreateDonor(db, "imprimante", "HP", "DeskJet 2130", "")
+	if err != nil {
+		t.Fatalf("create donor: %v", err)
+	}
+	second, err := CreateDonor(db, "imprimante", "HP", "DeskJet 2130", "carter cassé")
+	if err != nil {
+		t.Fatalf("create donor: %v", err)
+	}
+	dvd, err := CreateDonor(db, "lecteur DVD", "LG", "GH24", "")
+	if err != nil {
+		t.Fatalf("create donor: %v", err)
+	}
+
+	harvest := func(donor *Donor, typeName, name string, qty int, state string) int {
+		t.Helper()
+		id, err := CreatePart(db, typeName, name, "{}", nil, qty)
+		if err != nil {
+			t.Fatalf("create part: %v", err)
+		}
+		if err := SetPartDonor(db, int(id), donor.ID); err != nil {
+			t.Fatalf("link donor: %v", err)
+		}
+		if state != StateUntested {
+			if err := SetPartState(db, int(id), state); err != nil {
+				t.Fatalf("set state: %v", err)
+			}
+		}
+		return int(id)
+	}
+
+	harvest(first, "moteur", "Moteur chariot", 1, StateWorking)
+	harvest(first, "moteur", "Moteur entraînement papier", 1, StateBroken)
+	harvest(first, "roulement", "Roulement 608", 2, StateWorking)
+	harvest(second, "moteur", "Moteur chariot", 1, StateWorking)
+	harvest(second, "moteur", "Moteur scanner", 1, StateSpare)
+	harvest(dvd, "moteur", "Moteur tiroir", 1, StateUntested)
+
+	parts, err := ListDonorParts(db, first.ID)
+	if err != nil {
+		t.Fatalf("list donor parts: %v", err)
+	}
+	if len(parts) != 3 {
+		t.Fatalf("expected 3 parts harvested from first printer, got %d", len(parts))
+	}
+
+	stats, err := GetDonorModelStats(db, "DeskJet")
+	if err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	if len(stats) != 1 || stats[0].Donors != 2 {
+		t.Fatalf("expected one model with 2 donors, got %+v", stats)
+	}
+	byType := map[string]DonorTypeStats{}
+	for _, ts := range stats[0].Types {
+		byType[ts.Type] = ts
+	}
+	motors := byType["moteur"]
+	if motors.Donors != 2 || motors.Quantity != 4 || motors.Working != 2 || motors.Broken != 1 || motors.Spare != 1 {
+		t.Fatalf("unexpected motor stats: %+v", motors)
+	}
+	bearings := byType["roulement"]
+	if bearings.Donors != 1 || bearings.Quantity != 2 {
+		t.Fatalf("unexpected bearing stats: %+v", bearings)
+	}
+}
+
+func TestSetPartDonorUnknownDonor(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+
+	id, err := CreatePart(db, "", "Courroie", "{}", nil, 1)
+	if err != nil {
+		t.Fatalf("create part: %v", err)
+	}
+	if err := SetPartDonor(db, int(id), 99); err == nil {
+		t.Fatalf("expected error for unknown donor")
+	}
+
+	donor, _ := CreateDonor(db, "lave-linge", "", "", "")
+	if err := SetPartDonor(db, int(id), donor.ID); err != nil {
+		t.Fatalf("link donor: %v", err)
+	}
+	meta, _ := GetPartMeta(db, int(id))
+	if !meta.DonorID.Valid || int(meta.DonorID.Int64) != donor.ID {
+		t.Fatalf("expected part linked to donor %d, got %+v", donor.ID, meta.DonorID)
+	}
+	if err := ClearPartDonor(db, int(id)); err != nil {
+		t.Fatalf("unlink donor: %v", err)
+	}
+	meta, _ = GetPartMeta(db, int(id))
+	if meta.DonorID.Valid {
+		t.Fatalf("expected part unlinked")
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// PartUpdate décrit une modification de pièce (nil = champ inchangé)
+type PartUpdate struct {
+	Type         *string
+	Name         *string
+	Props        map[string]interface{} // Patch JSON (ou remplacement si ReplaceProps)
+	ReplaceProps bool
+}
+
+// MergeProps applique un patch JSON (RFC 7396) sur des propriétés existantes.
+// Une valeur null supprime la clé, un objet est fusionné récursivement.
+func MergeProps(current, patch map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(current))
+	for k, v := range current {
+		merged[k] = v
+	}
+
+	for k, v := range patch {
+		if v == nil {
+			delete(merged, k)
+			continue
+		}
+		if patchObj, ok := v.(map[string]interface{}); ok {
+			if curObj, ok := merged[k].(map[string]interface{}); ok {
+				merged[k] = MergeProps(curObj, patchObj)
+				continue
+			}
+			merged[k] = MergeProps(nil, patchObj)
+			continue
+		}
+		merged[k] = v
+	}
+
+	return merged
+}
+
+// EditPart modifie le nom, le type et/ou les propriétés d'une pièce.
+// Les propriétés fusionnées sont revalidées; seules les props du patch sont normalisées, les
+// autres sont déjà dans l'unité de base (les renormaliser appliquerait à nouveau default_unit).
+func EditPart(db *sql.DB, id int, upd PartUpdate) (*PartMeta, error) {
+	meta, err := GetPartMeta(db, id)
+	if err != nil {
+		return nil, err
+	}
+	if !meta.Found {
+		return nil, fmt.Errorf("pièce ID %d introuvable", id)
+	}
+
+	typeName := meta.Type
+	if upd.Type != nil {
+		typeName = *upd.Type
+	}
+	name := meta.Name
+	if upd.Name != nil {
+		name = *upd.Name
+	}
+	if name == "" {
+		return nil, fmt.Errorf("le nom ne peut pas être vide")
+	}
+	if typeName != "" && !TypeExists(typeName) {
+		return nil, fmt.Errorf("type '%s' inconnu. Utilisez un template existant (commande 'templates')", typeName)
+	}
+
+	current := map[string]interface{}{}
+	if meta.PropsJSON != "" {
+		if err := json.Unmarshal([]byte(meta.PropsJSON), &current); err != nil {
+			return nil, fmt.Errorf("props existantes invalides: %v", err)
+		}
+	}
+
+	var props map[string]interface{}
+	if upd.ReplaceProps {
+		props = MergeProps(nil, upd.Props)
+	} else {
+		props = MergeProps(current, upd.Props)
+	}
+
+	// Valider selon le template si un type est spécifié
+	if typeName != "" {
+		if err := ValidateProps(typeName, props); err != nil {
+			return nil, err
+		}
+	}
+
+	// Normaliser les unités: toutes les props en remplacement, celles du patch sinon
+	if upd.ReplaceProps {
+		current = map[string]interface{}{}
+	}
+	patch := make(map[string]interface{}, len(upd.Props))
+	for field, value := range upd.Props {
+		if value != nil {
+			patch[field] = value
+		}
+	}
+	normalizedPatch, patchUnits, err := NormalizePartPropsWithUnits(typeName, patch)
+	if err != nil {
+		return nil, fmt.Errorf("erreur de normalisation: %v", err)
+	}
+	normalizedProps := MergeProps(current, upd.Props)
+	for field, value := range normalizedPatch {
+		if _, nested := upd.Props[field].(map[string]interface{}); !nested {
+			normalizedProps[field] = value // Un objet du patch reste fusionné (RFC 7396)
+		}
+	}
+
+	// Les props non modifiées gardent leur saisie d'origine et leur unité
+	propUnits := make(map[string]PropUnit, len(meta.PropUnits)+len(patchUnits))
+	if !upd.ReplaceProps {
+		for field, unit := range meta.PropUnits {
+			if _, edited := normalizedPatch[field]; edited {
+				continue
+			}
+			if _, kept := normalizedProps[field]; kept {
+				propUnits[field] = unit
+			}
+		}
+	}
+	for field, unit := range patchUnits {
+		propUnits[field] = unit
+	}
+	ComputeProps(typeName, normalizedProps)
+
+	normalizedJSON, err := json.Marshal(normalizedProps)
+	if err != nil {
+		return nil, fmt.Errorf("erreur sérialisation: %v", err)
+	}
+
+	if err := UpdatePart(db, id, typeName, name, string(normalizedJSON), propUnits); err != nil {
+		return nil, err
+	}
+
+	return GetPartMeta(db, id)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestMergeProps(t *testing.T) {
+	current := map[string]interface{}{
+		"d_int":  10.0,
+		"d_ext":  32.0,
+		"marque": "SKF",
+		"extra":  map[string]interface{}{"a": 1.0, "b": 2.0},
+	}
+	patch := map[string]interface{}{
+		"d_int":  "12mm",
+		"marque": nil,
+		"extra":  map[string]interface{}{"b": nil, "c": 3.0},
+	}
+
+	merged := MergeProps(current, patch)
+
+	if merged["d_int"] != "12mm" {
+		t.Fatalf("expected d_int to be replaced, got %v", merged["d_int"])
+	}
+	if merged["d_ext"] != 32.0 {
+		t.Fatalf("expected d_ext untouched, got %v", merged["d_ext"])
+	}
+	if _, ok := merged["marque"]; ok {
+		t.Fatalf("expected marque to be removed by null")
+	}
+	extra := merged["extra"].(map[string]interface{})
+	if extra["a"] != 1.0 || extra["c"] != 3.0 {
+		t.Fatalf("unexpected nested merge: %+v", extra)
+	}
+	if _, ok := extra["b"]; ok {
+		t.Fatalf("expected nested b to be removed")
+	}
+	if current["d_int"] != 10.0 {
+		t.Fatalf("MergeProps must not modify its input")
+	}
+}
+
+func TestEditPartMergesAndNormalizes(t *testing.T) {
+	seedTemplates()
+	db := newTestDB(t)
+	defer db.Close()
+
+	id, err := CreatePart(db, "bearing", "Roulement 6001", `{"d_int":12,"d_ext":28,"width":8}`, nil, 1)
+	if err != nil {
+		t.Fatalf("create part: %v", err)
+	}
+
+	name := "Roulement 6201"
+	meta, err := EditPart(db, int(id), PartUpdate{
+		Name:  &name,
+		Props: map[string]interface{}{"d_ext": "3.2cm", "brand": "SKF"},
+	})
+	if err != nil {
+		t.Fatalf("edit part: %v", err)
+	}
+
+	if meta.Name != name {
+		t.Fatalf("expected name %q, got %q", name, meta.Name)
+	}
+	var props map[string]interface{}
+	if err := json.Unmarshal([]byte(meta.PropsJSON), &props); err != nil {
+		t.Fatalf("parse props: %v", err)
+	}
+	if props["d_ext"] != 32.0 {
+		t.Fatalf("expected d_ext normalized to 32, got %v", props["d_ext"])
+	}
+	if props["d_int"] != 12.0 || props["brand"] != "SKF" {
+		t.Fatalf("unexpected merged props: %+v", props)
+	}
+}
+
+func TestEditPartRevalidatesRequiredFields(t *testing.T) {
+	seedTemplates()
+	db := newTestDB(t)
+	defer db.Close()
+
+	id, err := CreatePart(db, "bearing", "Roulement 6001", `{"d_int":12,"d_ext":28,"width":8}`, nil, 1)
+	if err != nil {
+		t.Fatalf("create part: %v", err)
+	}
+
+	_, err = EditPart(db, int(id), PartUpdate{Props: map[string]interface{}{"width": nil}})
+	if err == nil {
+		t.Fatalf("expected error when removing a required prop")
+	}
+
+	meta, _ := GetPartMeta(db, int(id))
+	if meta.PropsJSON != `{"d_int":12,"d_ext":28,"width":8}` {
+		t.Fatalf("props should be unchanged after failed edit, got %s", meta.PropsJSON)
+	}
+}
+
+func TestEditPartDoesNotRenormalizeStoredProps(t *testing.T) {
+	raw := map[string]*Template{
+		"condo": {Name: "condo", Fields: map[string]FieldDef{
+			"capacite": {Domain: "capacite", DefaultUnit: "nF"},
+			"marque":   {},
+		}},
+	}
+	resolved, err := ResolveTemplates(raw)
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	Templates.Set(resolved)
+	db := newTestDB(t)
+	defer db.Close()
+
+	props, units, err := NormalizePartPropsWithUnits("condo", map[string]interface{}{"capacite": 100.0})
+	if err != nil {
+		t.Fatalf("normalize: %v", err)
+	}
+	propsJSON, _ := json.Marshal(props)
+	id, err := CreatePart(db, "condo", "Condo 100nF", string(propsJSON), nil, 1)
+	if err != nil {
+		t.Fatalf("create part: %v", err)
+	}
+	if err := SetPropUnits(db, int(id), units); err != nil {
+		t.Fatalf("set units: %v", err)
+	}
+
+	// Modifier un autre champ ne doit pas réappliquer default_unit (nF) à la valeur stockée en uF
+	for _, brand := range []string{"Wima", "Kemet"} {
+		meta, err := EditPart(db, int(id), PartUpdate{Props: map[string]interface{}{"marque": brand}})
+		if err != nil {
+			t.Fatalf("edit part: %v", err)
+		}
+		if !strings.Contains(meta.PropsJSON, `"capacite":0.1`) {
+			t.Fatalf("capacitance changed by edit: %s", meta.PropsJSON)
+		}
+		if meta.PropUnits["capacite"].Unit != "uF" {
+			t.Fatalf("unit lost on edit: %+v", meta.PropUnits)
+		}
+	}
+}
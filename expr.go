@@ -0,0 +1,670 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Langage d'expressions des champs calculés (clé "compute" d'un champ de template).
+// Volontairement minimal et sans effet de bord: nombres, chaînes, noms de champs,
+// + - * / %, comparaisons (== != < <= > >=), parenthèses et une liste fermée de fonctions.
+//
+//	courant: round(watts / volts, 2)
+//	pas:     iso_pitch(diametre)
+//	serie:   bearing_series(d_int, d_ext, largeur)
+
+// Expr est une expression compilée
+type Expr struct {
+	source string
+	root   exprNode
+}
+
+// exprNode est un nœud de l'arbre syntaxique
+type exprNode interface {
+	eval(env map[string]interface{}) (interface{}, error)
+}
+
+// errMissingField signale un champ absent des props: le champ calculé n'est alors pas renseigné
+type errMissingField struct{ name string }
+
+func (e errMissingField) Error() string { return fmt.Sprintf("champ '%s' absent", e.name) }
+
+// ParseExpr compile une expression
+func ParseExpr(source string) (*Expr, error) {
+	tokens, err := tokenizeExpr(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{tokens: tokens}
+	root, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, fmt.Errorf("élément inattendu '%s' (position %d)", tok.text, tok.pos+1)
+	}
+	return &Expr{source: source, root: root}, nil
+}
+
+// Eval évalue l'expression avec les props d'une pièce
+func (e *Expr) Eval(props map[string]interface{}) (interface{}, error) {
+	return e.root.eval(props)
+}
+
+// Fields retourne les noms de champs utilisés par l'expression, triés
+func (e *Expr) Fields() []string {
+	seen := make(map[string]bool)
+	collectExprFields(e.root, seen)
+	fields := make([]string, 0, len(seen))
+	for name := range seen {
+		fields = append(fields, name)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func (e *Expr) String() string { return e.source }
+
+func collectExprFields(n exprNode, seen map[string]bool) {
+	switch n := n.(type) {
+	case fieldNode:
+		seen[string(n)] = true
+	case unaryNode:
+		collectExprFields(n.x, seen)
+	case binaryNode:
+		collectExprFields(n.left, seen)
+		collectExprFields(n.right, seen)
+	case callNode:
+		for _, arg := range n.args {
+			collectExprFields(arg, seen)
+		}
+	}
+}
+
+// --- Analyse lexicale ---
+
+type exprTokenKind int
+
+const (
+	tokEOF exprTokenKind = iota
+	tokNumber
+	tokString
+	tokIdent
+	tokOp
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+type exprToken struct {
+	kind exprTokenKind
+	text string
+	pos  int
+}
+
+func tokenizeExpr(src string) ([]exprToken, error) {
+	var tokens []exprToken
+	runes := []rune(src)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r) || (r == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, exprToken{tokNumber, string(runes[start:i]), start})
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, exprToken{tokIdent, string(runes[start:i]), start})
+		case r == '"' || r == '\'':
+			start := i
+			i++
+			for i < len(runes) && runes[i] != r {
+				i++
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("chaîne non terminée (position %d)", start+1)
+			}
+			tokens = append(tokens, exprToken{tokString, string(runes[start+1 : i]), start})
+			i++
+		case r == '(':
+			tokens = append(tokens, exprToken{tokLParen, "(", i})
+			i++
+		case r == ')':
+			tokens = append(tokens, exprToken{tokRParen, ")", i})
+			i++
+		case r == ',':
+			tokens = append(tokens, exprToken{tokComma, ",", i})
+			i++
+		case strings.ContainsRune("+-*/%", r):
+			tokens = append(tokens, exprToken{tokOp, string(r), i})
+			i++
+		case strings.ContainsRune("=!<>", r):
+			op := string(r)
+			if i+1 < len(runes) && runes[i+1] == '=' {
+				op += "="
+			}
+			if op == "=" || op == "!" {
+				return nil, fmt.Errorf("opérateur invalide '%s' (position %d)", op, i+1)
+			}
+			tokens = append(tokens, exprToken{tokOp, op, i})
+			i += len(op)
+		default:
+			return nil, fmt.Errorf("caractère invalide '%c' (position %d)", r, i+1)
+		}
+	}
+	return append(tokens, exprToken{tokEOF, "fin", len(runes)}), nil
+}
+
+// --- Analyse syntaxique (descente récursive) ---
+
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+}
+
+func (p *exprParser) peek() exprToken { return p.tokens[p.pos] }
+
+func (p *exprParser) next() exprToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *exprParser) acceptOp(ops ...string) (string, bool) {
+	tok := p.peek()
+	if tok.kind != tokOp {
+		return "", false
+	}
+	for _, op := range ops {
+		if tok.text == op {
+			p.pos++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+// comparaison := somme [ (== != < <= > >=) somme ]
+func (p *exprParser) parseComparison() (exprNode, error) {
+	left, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	if op, ok := p.acceptOp("==", "!=", "<", "<=", ">", ">="); ok {
+		right, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		return binaryNode{op: op, left: left, right: right}, nil
+	}
+	return left, nil
+}
+
+// somme := produit { (+ -) produit }
+func (p *exprParser) parseSum() (exprNode, error) {
+	left, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.acceptOp("+", "-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: op, left: left, right: right}
+	}
+}
+
+// produit := unaire { (* / %) unaire }
+func (p *exprParser) parseProduct() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.acceptOp("*", "/", "%")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: op, left: left, right: right}
+	}
+}
+
+// unaire := [-] primaire
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if _, ok := p.acceptOp("-"); ok {
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return unaryNode{x: x}, nil
+	}
+	return p.parsePrimary()
+}
+
+// primaire := nombre | chaîne | champ | fonction(args) | ( comparaison )
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokNumber:
+		v, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("nombre invalide '%s'", tok.text)
+		}
+		return literalNode{v}, nil
+	case tokString:
+		return literalNode{tok.text}, nil
+	case tokLParen:
+		inner, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		if p.next().kind != tokRParen {
+			return nil, fmt.Errorf("parenthèse fermante attendue (position %d)", tok.pos+1)
+		}
+		return inner, nil
+	case tokIdent:
+		switch tok.text {
+		case "true":
+			return literalNode{true}, nil
+		case "false":
+			return literalNode{false}, nil
+		}
+		if p.peek().kind != tokLParen {
+			return fieldNode(tok.text), nil
+		}
+		p.next()
+		return p.parseCall(tok)
+	case tokEOF:
+		return nil, fmt.Errorf("expression incomplète")
+	}
+	return nil, fmt.Errorf("élément inattendu '%s' (position %d)", tok.text, tok.pos+1)
+}
+
+func (p *exprParser) parseCall(name exprToken) (exprNode, error) {
+	fn, ok := exprFuncs[name.text]
+	if !ok {
+		return nil, fmt.Errorf("fonction inconnue '%s'", name.text)
+	}
+
+	var args []exprNode
+	if p.peek().kind == tokRParen {
+		p.next()
+	} else {
+		for {
+			arg, err := p.parseComparison()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			tok := p.next()
+			if tok.kind == tokRParen {
+				break
+			}
+			if tok.kind != tokComma {
+				return nil, fmt.Errorf("',' ou ')' attendu après un argument de %s", name.text)
+			}
+		}
+	}
+
+	if len(args) < fn.minArgs || (fn.maxArgs >= 0 && len(args) > fn.maxArgs) {
+		return nil, fmt.Errorf("%s: nombre d'arguments invalide (%d)", name.text, len(args))
+	}
+	return callNode{name: name.text, fn: fn, args: args}, nil
+}
+
+// --- Évaluation ---
+
+type literalNode struct{ value interface{} }
+
+func (n literalNode) eval(map[string]interface{}) (interface{}, error) { return n.value, nil }
+
+type fieldNode string
+
+func (n fieldNode) eval(env map[string]interface{}) (interface{}, error) {
+	v, ok := env[string(n)]
+	if !ok || v == nil || v == "" {
+		return nil, errMissingField{string(n)}
+	}
+	return v, nil
+}
+
+type unaryNode struct{ x exprNode }
+
+func (n unaryNode) eval(env map[string]interface{}) (interface{}, error) {
+	v, err := n.x.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	f, err := exprNumber(v)
+	if err != nil {
+		return nil, err
+	}
+	return -f, nil
+}
+
+type binaryNode struct {
+	op          string
+	left, right exprNode
+}
+
+func (n binaryNode) eval(env map[string]interface{}) (interface{}, error) {
+	l, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	r, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	// + concatène dès qu'un des opérandes est une chaîne non numérique
+	if n.op == "+" {
+		_, lErr := exprNumber(l)
+		_, rErr := exprNumber(r)
+		if lErr != nil || rErr != nil {
+			return exprString(l) + exprString(r), nil
+		}
+	}
+	if n.op == "==" || n.op == "!=" {
+		equal := exprString(l) == exprString(r)
+		if lf, err := exprNumber(l); err == nil {
+			if rf, err := exprNumber(r); err == nil {
+				equal = lf == rf
+			}
+		}
+		return equal == (n.op == "=="), nil
+	}
+
+	lf, err := exprNumber(l)
+	if err != nil {
+		return nil, err
+	}
+	rf, err := exprNumber(r)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "+":
+		return lf + rf, nil
+	case "-":
+		return lf - rf, nil
+	case "*":
+		return lf * rf, nil
+	case "/":
+		if rf == 0 {
+			return nil, fmt.Errorf("division par zéro")
+		}
+		return lf / rf, nil
+	case "%":
+		if rf == 0 {
+			return nil, fmt.Errorf("division par zéro")
+		}
+		return math.Mod(lf, rf), nil
+	case "<":
+		return lf < rf, nil
+	case "<=":
+		return lf <= rf, nil
+	case ">":
+		return lf > rf, nil
+	case ">=":
+		return lf >= rf, nil
+	}
+	return nil, fmt.Errorf("opérateur inconnu '%s'", n.op)
+}
+
+type callNode struct {
+	name string
+	fn   exprFunc
+	args []exprNode
+}
+
+func (n callNode) eval(env map[string]interface{}) (interface{}, error) {
+	// if() n'évalue que la branche retenue
+	if n.name == "if" {
+		cond, err := n.args[0].eval(env)
+		if err != nil {
+			return nil, err
+		}
+		if exprTruthy(cond) {
+			return n.args[1].eval(env)
+		}
+		return n.args[2].eval(env)
+	}
+
+	args := make([]interface{}, len(n.args))
+	for i, arg := range n.args {
+		v, err := arg.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	return n.fn.call(args)
+}
+
+// exprNumber convertit une valeur en nombre (les chaînes numériques sont acceptées)
+func exprNumber(v interface{}) (float64, error) {
+	switch v := v.(type) {
+	case float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return 0, fmt.Errorf("nombre attendu, reçu '%s'", v)
+		}
+		return f, nil
+	}
+	// Valeur structurée (voir ranges.go): sa valeur nominale, pas une plage
+	if rv, ok := AsRangeValue(v); ok {
+		if n, ok := rv.Number(); ok {
+			return n, nil
+		}
+		return 0, fmt.Errorf("nombre attendu, reçu la plage %s", rv)
+	}
+	return 0, fmt.Errorf("nombre attendu, reçu %v", v)
+}
+
+func exprString(v interface{}) string {
+	if f, ok := v.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+func exprTruthy(v interface{}) bool {
+	switch v := v.(type) {
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	case string:
+		return v != ""
+	}
+	return v != nil
+}
+
+// --- Fonctions ---
+
+type exprFunc struct {
+	minArgs, maxArgs int // maxArgs -1: variadique
+	call             func(args []interface{}) (interface{}, error)
+}
+
+// exprFuncs liste les fonctions disponibles dans les expressions
+var exprFuncs map[string]exprFunc
+
+func init() {
+	exprFuncs = map[string]exprFunc{
+		"round": {1, 2, func(args []interface{}) (interface{}, error) {
+			nums, err := exprNumbers(args)
+			if err != nil {
+				return nil, err
+			}
+			scale := 1.0
+			if len(nums) == 2 {
+				scale = math.Pow(10, nums[1])
+			}
+			return math.Round(nums[0]*scale) / scale, nil
+		}},
+		"floor": mathFunc(math.Floor),
+		"ceil":  mathFunc(math.Ceil),
+		"abs":   mathFunc(math.Abs),
+		"sqrt": {1, 1, func(args []interface{}) (interface{}, error) {
+			x, err := exprNumber(args[0])
+			if err != nil {
+				return nil, err
+			}
+			if x < 0 {
+				return nil, fmt.Errorf("sqrt d'un nombre négatif")
+			}
+			return math.Sqrt(x), nil
+		}},
+		"pow": {2, 2, func(args []interface{}) (interface{}, error) {
+			nums, err := exprNumbers(args)
+			if err != nil {
+				return nil, err
+			}
+			return math.Pow(nums[0], nums[1]), nil
+		}},
+		"min": {1, -1, func(args []interface{}) (interface{}, error) {
+			nums, err := exprNumbers(args)
+			if err != nil {
+				return nil, err
+			}
+			sort.Float64s(nums)
+			return nums[0], nil
+		}},
+		"max": {1, -1, func(args []interface{}) (interface{}, error) {
+			nums, err := exprNumbers(args)
+			if err != nil {
+				return nil, err
+			}
+			sort.Float64s(nums)
+			return nums[len(nums)-1], nil
+		}},
+		"if": {3, 3, nil}, // Évaluation paresseuse dans callNode.eval
+		"str": {1, 1, func(args []interface{}) (interface{}, error) {
+			return exprString(args[0]), nil
+		}},
+		"iso_pitch": {1, 1, func(args []interface{}) (interface{}, error) {
+			return isoCoarsePitch(args[0])
+		}},
+		"bearing_ref": {3, 3, func(args []interface{}) (interface{}, error) {
+			return bearingDesignation(args)
+		}},
+		"bearing_series": {3, 3, func(args []interface{}) (interface{}, error) {
+			ref, err := bearingDesignation(args)
+			if err != nil {
+				return nil, err
+			}
+			return ref[:2], nil
+		}},
+	}
+}
+
+func mathFunc(f func(float64) float64) exprFunc {
+	return exprFunc{1, 1, func(args []interface{}) (interface{}, error) {
+		x, err := exprNumber(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return f(x), nil
+	}}
+}
+
+func exprNumbers(args []interface{}) ([]float64, error) {
+	nums := make([]float64, len(args))
+	for i, arg := range args {
+		n, err := exprNumber(arg)
+		if err != nil {
+			return nil, err
+		}
+		nums[i] = n
+	}
+	return nums, nil
+}
+
+// isoCoarsePitches donne le pas gros ISO (mm) des filetages métriques courants
+var isoCoarsePitches = map[float64]float64{
+	1: 0.25, 1.2: 0.25, 1.4: 0.3, 1.6: 0.35, 2: 0.4, 2.5: 0.45, 3: 0.5, 3.5: 0.6,
+	4: 0.7, 5: 0.8, 6: 1, 7: 1, 8: 1.25, 10: 1.5, 12: 1.75, 14: 2, 16: 2,
+	18: 2.5, 20: 2.5, 22: 2.5, 24: 3, 27: 3, 30: 3.5, 33: 3.5, 36: 4, 42: 4.5, 48: 5,
+}
+
+// isoCoarsePitch retourne le pas gros ISO d'un diamètre (4, "4" ou "M4")
+func isoCoarsePitch(v interface{}) (interface{}, error) {
+	if s, ok := v.(string); ok {
+		v = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(s)), "M")
+	}
+	d, err := exprNumber(v)
+	if err != nil {
+		return nil, err
+	}
+	pitch, ok := isoCoarsePitches[d]
+	if !ok {
+		return nil, fmt.Errorf("pas ISO inconnu pour M%g", d)
+	}
+	return pitch, nil
+}
+
+// bearingSizes associe les dimensions (d × D × B, mm) des roulements à billes courants à leur désignation
+var bearingSizes = []struct {
+	d, D, B float64
+	ref     string
+}{
+	{5, 16, 5, "625"}, {6, 19, 6, "626"}, {7, 19, 6, "607"}, {7, 22, 7, "627"},
+	{8, 22, 7, "608"}, {9, 24, 7, "609"}, {9, 26, 8, "629"},
+	{10, 26, 8, "6000"}, {12, 28, 8, "6001"}, {15, 32, 9, "6002"}, {17, 35, 10, "6003"},
+	{20, 42, 12, "6004"}, {25, 47, 12, "6005"}, {30, 55, 13, "6006"}, {35, 62, 14, "6007"},
+	{40, 68, 15, "6008"}, {45, 75, 16, "6009"}, {50, 80, 16, "6010"},
+	{10, 30, 9, "6200"}, {12, 32, 10, "6201"}, {15, 35, 11, "6202"}, {17, 40, 12, "6203"},
+	{20, 47, 14, "6204"}, {25, 52, 15, "6205"}, {30, 62, 16, "6206"}, {35, 72, 17, "6207"},
+	{40, 80, 18, "6208"}, {45, 85, 19, "6209"}, {50, 90, 20, "6210"},
+	{10, 35, 11, "6300"}, {12, 37, 12, "6301"}, {15, 42, 13, "6302"}, {17, 47, 14, "6303"},
+	{20, 52, 15, "6304"}, {25, 62, 17, "6305"}, {30, 72, 19, "6306"}, {35, 80, 21, "6307"},
+	{40, 90, 23, "6308"}, {45, 100, 25, "6309"}, {50, 110, 27, "6310"},
+}
+
+// bearingDesignation retrouve la désignation d'un roulement à partir de ses dimensions
+func bearingDesignation(args []interface{}) (string, error) {
+	dims, err := exprNumbers(args)
+	if err != nil {
+		return "", err
+	}
+	const tolerance = 0.05
+	for _, b := range bearingSizes {
+		if math.Abs(b.d-dims[0]) <= tolerance && math.Abs(b.D-dims[1]) <= tolerance && math.Abs(b.B-dims[2]) <= tolerance {
+			return b.ref, nil
+		}
+	}
+	return "", fmt.Errorf("roulement %g×%g×%g non répertorié", dims[0], dims[1], dims[2])
+}
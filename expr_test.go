@@ -0,0 +1,111 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExprEval(t *testing.T) {
+	props := map[string]interface{}{
+		"watts":    float64(60),
+		"volts":    float64(12),
+		"diametre": "M4",
+		"nom":      "moteur",
+		"d_int":    float64(8),
+		"d_ext":    float64(22),
+		"largeur":  "7",
+	}
+	cases := []struct {
+		source string
+		want   interface{}
+	}{
+		{"watts / volts", float64(5)},
+		{"round(10 / 3, 2)", 3.33},
+		{"-volts + 2 * 3", float64(-6)},
+		{"(1 + 2) * 3 % 4", float64(1)},
+		{"max(1, volts, 3)", float64(12)},
+		{"watts >= 60", true},
+		{"nom == 'moteur'", true},
+		{"if(volts > 24, 'HT', 'BT')", "BT"},
+		{"nom + '-' + volts", "moteur-12"},
+		{"iso_pitch(diametre)", 0.7},
+		{"iso_pitch(10)", 1.5},
+		{"bearing_ref(d_int, d_ext, largeur)", "608"},
+		{"bearing_series(20, 47, 14)", "62"},
+		{"sqrt(pow(3, 2) + 16)", float64(5)},
+	}
+	for _, c := range cases {
+		expr, err := ParseExpr(c.source)
+		if err != nil {
+			t.Fatalf("%s: parse: %v", c.source, err)
+		}
+		got, err := expr.Eval(props)
+		if err != nil {
+			t.Fatalf("%s: eval: %v", c.source, err)
+		}
+		if got != c.want {
+			t.Fatalf("%s: expected %#v, got %#v", c.source, c.want, got)
+		}
+	}
+}
+
+func TestParseExprErrors(t *testing.T) {
+	cases := map[string]string{
+		"watts /":         "incomplète",
+		"round(1":         "attendu",
+		"(1 + 2":          "parenthèse",
+		"exec('rm')":      "fonction inconnue",
+		"round(1, 2, 3)":  "nombre d'arguments",
+		"a = 1":           "opérateur invalide",
+		"'sans fin":       "non terminée",
+		"watts $ 2":       "caractère invalide",
+		"watts volts":     "inattendu",
+		"if(true, 1)":     "nombre d'arguments",
+		"bearing_ref(1,)": "inattendu",
+	}
+	for source, want := range cases {
+		_, err := ParseExpr(source)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("%s: expected error containing %q, got %v", source, want, err)
+		}
+	}
+}
+
+func TestExprEvalErrors(t *testing.T) {
+	cases := map[string]string{
+		"watts / volts":         "absent",
+		"1 / 0":                 "division par zéro",
+		"'abc' * 2":             "nombre attendu",
+		"iso_pitch('M5.5')":     "pas ISO inconnu",
+		"bearing_ref(1, 2, 3)":  "non répertorié",
+		"sqrt(-1)":              "négatif",
+		"if(false, 1, missing)": "absent",
+	}
+	for source, want := range cases {
+		expr, err := ParseExpr(source)
+		if err != nil {
+			t.Fatalf("%s: parse: %v", source, err)
+		}
+		_, err = expr.Eval(map[string]interface{}{"watts": float64(10)})
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("%s: expected error containing %q, got %v", source, want, err)
+		}
+	}
+
+	// Seule la branche retenue est évaluée
+	expr, _ := ParseExpr("if(true, 1, missing)")
+	if v, err := expr.Eval(nil); err != nil || v != float64(1) {
+		t.Fatalf("expected lazy if, got %v (%v)", v, err)
+	}
+}
+
+func TestExprFields(t *testing.T) {
+	expr, err := ParseExpr("round(watts / volts, 2) + if(reducteur, 1, 0) + watts")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if got := expr.Fields(); !reflect.DeepEqual(got, []string{"reducteur", "volts", "watts"}) {
+		t.Fatalf("unexpected fields: %v", got)
+	}
+}
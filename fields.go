@@ -0,0 +1,372 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Types de champs d'un template (clé "type" dans le YAML)
+const (
+	FieldText    = "text"    // Texte libre (pattern optionnel)
+	FieldNumber  = "number"  // Nombre, avec unité si le champ a un domaine (min/max optionnels)
+	FieldInteger = "integer" // Nombre entier (min/max optionnels)
+	FieldBoolean = "boolean" // Oui / non
+	FieldEnum    = "enum"    // Une valeur parmi values
+	FieldList    = "list"    // Liste de valeurs (restreintes à values et/ou pattern si définis)
+)
+
+// numericDomains sont les domaines saisis comme des nombres quand le champ n'a pas de type explicite
+// (les résistances et capacités gardent une saisie texte: "4.7k", "100nF")
+var numericDomains = map[string]bool{
+	"dimension":   true,
+	"tension":     true,
+	"puissance":   true,
+	"vitesse_rot": true,
+	"frequence":   true,
+}
+
+// Kind retourne le type effectif du champ (type explicite, sinon déduit du domaine)
+func (f FieldDef) Kind() string {
+	if f.Type != "" {
+		return f.Type
+	}
+	if numericDomains[f.Domain] {
+		return FieldNumber
+	}
+	return FieldText
+}
+
+// InputType retourne le type de contrôle de formulaire à utiliser pour le champ
+func (f FieldDef) InputType() string {
+	switch f.Kind() {
+	case FieldNumber, FieldInteger:
+		return "number"
+	case FieldBoolean:
+		return "checkbox"
+	case FieldEnum:
+		return "select"
+	case FieldList:
+		return "list"
+	default:
+		return "text"
+	}
+}
+
+// checkFieldDef vérifie la cohérence de la définition d'un champ (au chargement des templates)
+func checkFieldDef(name string, f FieldDef) error {
+	switch f.Kind() {
+	case FieldText, FieldNumber, FieldInteger, FieldBoolean, FieldList:
+	case FieldEnum:
+		if len(f.Values) == 0 {
+			return fmt.Errorf("champ '%s': un enum doit lister ses valeurs (values)", name)
+		}
+	default:
+		return fmt.Errorf("champ '%s': type inconnu '%s' (text, number, integer, boolean, enum, list)", name, f.Type)
+	}
+	if f.Min != nil && f.Max != nil && *f.Min > *f.Max {
+		return fmt.Errorf("champ '%s': min (%g) supérieur à max (%g)", name, *f.Min, *f.Max)
+	}
+	if f.Pattern != "" {
+		if _, err := regexp.Compile(f.Pattern); err != nil {
+			return fmt.Errorf("champ '%s': pattern invalide: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// validateField vérifie une valeur selon la définition de son champ.
+// Seuls les types explicites sont contraignants: un champ sans type (ex: diamètre "M4" ou "4mm")
+// reste libre, sauf s'il déclare des bornes min/max.
+func validateField(name string, f FieldDef, value interface{}) error {
+	kind := f.Type
+	if kind == "" {
+		kind = FieldText
+		if f.Min != nil || f.Max != nil {
+			kind = FieldNumber
+		}
+	}
+
+	switch kind {
+	case FieldNumber, FieldInteger:
+		low, high, err := fieldBounds(f, value)
+		if err != nil {
+			return fmt.Errorf("champ '%s': %v", name, err)
+		}
+		if kind == FieldInteger && (low != math.Trunc(low) || high != math.Trunc(high)) {
+			return fmt.Errorf("champ '%s': entier attendu, reçu %v", name, value)
+		}
+		if f.Min != nil && low < *f.Min {
+			return fmt.Errorf("champ '%s': %g inférieur au minimum %g", name, low, *f.Min)
+		}
+		if f.Max != nil && high > *f.Max {
+			return fmt.Errorf("champ '%s': %g supérieur au maximum %g", name, high, *f.Max)
+		}
+	case FieldBoolean:
+		if _, ok := parseBool(value); !ok {
+			return fmt.Errorf("champ '%s': booléen attendu (true/false), reçu %v", name, value)
+		}
+	case FieldEnum:
+		if _, ok := matchEnum(f.Values, value); !ok {
+			return fmt.Errorf("champ '%s': valeur '%v' non autorisée (%s)", name, value, strings.Join(f.Values, ", "))
+		}
+	case FieldList:
+		for _, item := range listItems(value) {
+			if len(f.Values) > 0 {
+				if _, ok := matchEnum(f.Values, item); !ok {
+					return fmt.Errorf("champ '%s': élément '%s' non autorisé (%s)", name, item, strings.Join(f.Values, ", "))
+				}
+			}
+			if err := matchPattern(name, f.Pattern, item); err != nil {
+				return err
+			}
+		}
+	default:
+		if err := matchPattern(name, f.Pattern, fmt.Sprint(value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fieldBounds retourne les bornes de la valeur numérique d'un champ, converties dans l'unité
+// de base de son domaine (les bornes min/max s'expriment dans cette unité). Une valeur simple
+// a deux bornes égales; une plage ou une tolérance ("12-24V", "5V ±5%") ses extrêmes.
+func fieldBounds(f FieldDef, value interface{}) (float64, float64, error) {
+	if rv, ok := AsRangeValue(value); ok {
+		return rv.Min, rv.Max, nil
+	}
+
+	var input string
+	switch v := value.(type) {
+	case float64:
+		if f.DefaultUnit == "" {
+			return v, v, nil
+		}
+		input = fmt.Sprintf("%g%s", v, f.DefaultUnit)
+	case int:
+		return fieldBounds(f, float64(v))
+	case string:
+		input = strings.TrimSpace(v)
+		if rv, err := parseRangeValue(input, f.DefaultUnit, UnitDomain(f.Domain)); err == nil && rv != nil {
+			return rv.Min, rv.Max, nil
+		}
+	default:
+		return 0, 0, fmt.Errorf("nombre attendu, reçu %v", value)
+	}
+
+	result, err := NormalizeValue(input, f.DefaultUnit)
+	if err != nil {
+		return 0, 0, fmt.Errorf("nombre attendu, reçu '%s'", input)
+	}
+	return result.Value, result.Value, nil
+}
+
+func matchPattern(name, pattern, value string) error {
+	if pattern == "" {
+		return nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("champ '%s': pattern invalide: %v", name, err)
+	}
+	if !re.MatchString(value) {
+		return fmt.Errorf("champ '%s': '%s' ne respecte pas le format %s", name, value, pattern)
+	}
+	return nil
+}
+
+// matchEnum retourne la valeur autorisée correspondante (comparaison insensible à la casse)
+func matchEnum(values []string, value interface{}) (string, bool) {
+	s := strings.TrimSpace(fmt.Sprint(value))
+	for _, allowed := range values {
+		if strings.EqualFold(allowed, s) {
+			return allowed, true
+		}
+	}
+	return "", false
+}
+
+func parseBool(value interface{}) (bool, bool) {
+	switch v := value.(type) {
+	case bool:
+		return v, true
+	case string:
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "true", "oui", "yes", "1", "on":
+			return true, true
+		case "false", "non", "no", "0", "off", "":
+			return false, true
+		}
+	case float64:
+		if v == 0 || v == 1 {
+			return v == 1, true
+		}
+	}
+	return false, false
+}
+
+// listItems découpe une valeur de liste: tableau JSON ou chaîne séparée par des virgules
+func listItems(value interface{}) []string {
+	var items []string
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			items = append(items, strings.TrimSpace(fmt.Sprint(item)))
+		}
+	case []string:
+		for _, item := range v {
+			items = append(items, strings.TrimSpace(item))
+		}
+	case string:
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	default:
+		items = append(items, fmt.Sprint(v))
+	}
+	return items
+}
+
+// NormalizePartProps normalise les propriétés d'une pièce selon son template:
+// les champs typés non numériques sont convertis (booléen, valeur d'enum canonique, liste,
+// entier), les autres passent par NormalizeProps (conversion d'unités).
+func NormalizePartProps(typeName string, props map[string]interface{}) (map[string]interface{}, error) {
+	normalized, _, err := NormalizePartPropsWithUnits(typeName, props)
+	return normalized, err
+}
+
+// NormalizePartPropsWithUnits normalise comme NormalizePartProps et retourne aussi l'unité de base
+// et la saisie d'origine des props numériques (à enregistrer avec SetPropUnits)
+func NormalizePartPropsWithUnits(typeName string, props map[string]interface{}) (map[string]interface{}, map[string]PropUnit, error) {
+	tmpl, _ := Templates.Get(typeName)
+
+	numeric := make(map[string]interface{}, len(props))
+	typed := make(map[string]interface{})
+	for key, value := range props {
+		var def FieldDef
+		var ok bool
+		if tmpl != nil {
+			def, ok = tmpl.Fields[key]
+		}
+		if !ok {
+			numeric[key] = value
+			continue
+		}
+
+		switch def.Type {
+		case FieldText:
+			typed[key] = value
+		case FieldBoolean:
+			if b, ok := parseBool(value); ok {
+				typed[key] = b
+			} else {
+				typed[key] = value
+			}
+		case FieldEnum:
+			if canonical, ok := matchEnum(def.Values, value); ok {
+				typed[key] = canonical
+			} else {
+				typed[key] = value
+			}
+		case FieldList:
+			items := []interface{}{}
+			for _, item := range listItems(value) {
+				if canonical, ok := matchEnum(def.Values, item); ok {
+					item = canonical
+				}
+				items = append(items, item)
+			}
+			typed[key] = items
+		case FieldInteger:
+			if s, ok := value.(string); ok {
+				if n, err := strconv.Atoi(strings.TrimSpace(s)); err == nil {
+					value = float64(n)
+				}
+			}
+			numeric[key] = value
+		default:
+			numeric[key] = value
+		}
+	}
+
+	normalized, units, err := NormalizePropsWithUnits(numeric, GetFieldUnits(typeName), GetFieldDomains(typeName))
+	if err != nil {
+		return nil, nil, err
+	}
+	for key, value := range typed {
+		normalized[key] = value
+	}
+	return normalized, units, nil
+}
+
+// TemplateFieldSchema décrit un champ de template pour les formulaires (API et partial HTML)
+type TemplateFieldSchema struct {
+	Name          string   `json:"name"`
+	Label         string   `json:"label"` // Libellé dans la langue demandée (défaut: nom)
+	Description   string   `json:"description"`
+	Required      bool     `json:"required"`
+	Type          string   `json:"type"`       // Contrôle de formulaire: text, number, checkbox, select, list
+	FieldType     string   `json:"field_type"` // Type du champ: text, number, integer, boolean, enum, list
+	Options       []string `json:"options,omitempty"`
+	Min           *float64 `json:"min,omitempty"`
+	Max           *float64 `json:"max,omitempty"`
+	Step          string   `json:"step,omitempty"`
+	Pattern       string   `json:"pattern,omitempty"`
+	Domain        string   `json:"domain,omitempty"`
+	Unit          string   `json:"unit,omitempty"`
+	InheritedFrom string   `json:"inherited_from,omitempty"`
+	Compute       string   `json:"compute,omitempty"`  // Expression d'un champ calculé
+	Override      bool     `json:"override,omitempty"` // Champ calculé modifiable (calculé si vide)
+	Aliases       []string `json:"aliases,omitempty"`
+}
+
+// TemplateFieldSchemas retourne le schéma aplati d'un template (champs hérités inclus, triés par nom),
+// libellés et descriptions dans la langue demandée
+func TemplateFieldSchemas(tmpl *Template, lang string) []TemplateFieldSchema {
+	names := make([]string, 0, len(tmpl.Fields))
+	for name := range tmpl.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fields := []TemplateFieldSchema{}
+	for _, name := range names {
+		def := tmpl.Fields[name]
+		field := TemplateFieldSchema{
+			Name:        name,
+			Label:       def.Label(name, lang),
+			Description: def.LocalizedDescription(lang),
+			Required:    def.Required && def.Compute == "", // Un champ calculé n'est pas à saisir
+			Type:        def.InputType(),
+			FieldType:   def.Kind(),
+			Min:         def.Min,
+			Max:         def.Max,
+			Pattern:     def.Pattern,
+			Domain:      def.Domain,
+			Unit:        def.DefaultUnit,
+			Compute:     def.Compute,
+			Override:    def.Override,
+			Aliases:     def.Aliases,
+		}
+		if field.FieldType == FieldEnum || field.FieldType == FieldList {
+			field.Options = def.Values
+		}
+		switch field.FieldType {
+		case FieldInteger:
+			field.Step = "1"
+		case FieldNumber:
+			field.Step = "any"
+		}
+		if from := tmpl.FieldFrom[name]; from != "" && from != tmpl.Name {
+			field.InheritedFrom = from
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
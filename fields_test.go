@@ -0,0 +1,165 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func floatPtr(v float64) *float64 { return &v }
+
+// seedTypedTemplate ajoute un template aux champs typés
+func seedTypedTemplate(t *testing.T) {
+	t.Helper()
+	setTestTemplates(t, map[string]*Template{
+		"capteur": {Name: "capteur", Fields: map[string]FieldDef{
+			"sortie":   {Required: true, Type: FieldEnum, Values: []string{"analogique", "I2C", "SPI"}},
+			"broches":  {Type: FieldInteger, Min: floatPtr(2), Max: floatPtr(40)},
+			"etanche":  {Type: FieldBoolean},
+			"ref":      {Type: FieldText, Pattern: `^[A-Z]{2}\d+$`},
+			"bus":      {Type: FieldList, Values: []string{"I2C", "SPI", "UART"}},
+			"tension":  {Type: FieldNumber, Domain: "tension", DefaultUnit: "V", Max: floatPtr(24)},
+			"remarque": {},
+		}},
+	})
+}
+
+func TestValidatePropsTypedOK(t *testing.T) {
+	seedTypedTemplate(t)
+	props := map[string]interface{}{
+		"sortie":   "i2c",
+		"broches":  float64(4),
+		"etanche":  true,
+		"ref":      "BM280",
+		"bus":      []interface{}{"I2C", "spi"},
+		"tension":  float64(5),
+		"remarque": "libre",
+	}
+	if err := ValidateProps("capteur", props); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestValidatePropsTypedErrors(t *testing.T) {
+	seedTypedTemplate(t)
+	cases := []struct {
+		field string
+		value interface{}
+		want  string
+	}{
+		{"sortie", "USB", "non autorisée"},
+		{"broches", float64(2.5), "entier attendu"},
+		{"broches", float64(64), "supérieur au maximum"},
+		{"broches", "beaucoup", "nombre attendu"},
+		{"etanche", "peut-être", "booléen attendu"},
+		{"ref", "bm280", "ne respecte pas le format"},
+		{"bus", "I2C, CAN", "'CAN' non autorisé"},
+		{"tension", "48V", "supérieur au maximum"},
+	}
+	for _, c := range cases {
+		props := map[string]interface{}{"sortie": "SPI", c.field: c.value}
+		err := ValidateProps("capteur", props)
+		if err == nil {
+			t.Fatalf("%s=%v: expected error", c.field, c.value)
+		}
+		if !strings.Contains(err.Error(), "champ '"+c.field+"'") || !strings.Contains(err.Error(), c.want) {
+			t.Fatalf("%s=%v: unexpected error: %v", c.field, c.value, err)
+		}
+	}
+}
+
+func TestValidatePropsReportsEveryField(t *testing.T) {
+	seedTypedTemplate(t)
+	err := ValidateProps("capteur", map[string]interface{}{"sortie": "USB", "broches": float64(1)})
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	if !strings.Contains(err.Error(), "champ 'broches'") || !strings.Contains(err.Error(), "champ 'sortie'") {
+		t.Fatalf("expected one error per field, got: %v", err)
+	}
+}
+
+func TestNormalizePartPropsTyped(t *testing.T) {
+	seedTypedTemplate(t)
+	props, err := NormalizePartProps("capteur", map[string]interface{}{
+		"sortie":  "spi",
+		"broches": "8",
+		"etanche": "oui",
+		"bus":     "i2c, uart",
+		"tension": "3.3V",
+		"ref":     "BM280",
+	})
+	if err != nil {
+		t.Fatalf("normalize: %v", err)
+	}
+	if props["sortie"] != "SPI" {
+		t.Fatalf("expected canonical enum value, got %v", props["sortie"])
+	}
+	if props["broches"] != float64(8) {
+		t.Fatalf("expected integer 8, got %#v", props["broches"])
+	}
+	if props["etanche"] != true {
+		t.Fatalf("expected boolean true, got %#v", props["etanche"])
+	}
+	if !reflect.DeepEqual(props["bus"], []interface{}{"I2C", "UART"}) {
+		t.Fatalf("unexpected list: %#v", props["bus"])
+	}
+	if props["tension"] != 3.3 {
+		t.Fatalf("expected 3.3, got %#v", props["tension"])
+	}
+	if props["ref"] != "BM280" {
+		t.Fatalf("expected text kept as is, got %#v", props["ref"])
+	}
+	if err := ValidateProps("capteur", props); err != nil {
+		t.Fatalf("normalized props should validate: %v", err)
+	}
+}
+
+func TestResolveTemplatesRejectsInvalidFields(t *testing.T) {
+	cases := map[string]FieldDef{
+		"type inconnu":      {Type: "date"},
+		"enum sans valeurs": {Type: FieldEnum},
+		"min supérieur":     {Type: FieldInteger, Min: floatPtr(10), Max: floatPtr(1)},
+		"pattern invalide":  {Type: FieldText, Pattern: "("},
+	}
+	for label, def := range cases {
+		raw := map[string]*Template{
+			"t": {Name: "t", Fields: map[string]FieldDef{"f": def}},
+		}
+		if _, err := ResolveTemplates(raw); err == nil {
+			t.Fatalf("%s: expected error", label)
+		}
+	}
+}
+
+func TestTemplateFieldSchemas(t *testing.T) {
+	seedTypedTemplate(t)
+	byName := map[string]TemplateFieldSchema{}
+	for _, f := range TemplateFieldSchemas(mustTemplate(t, "capteur"), "") {
+		byName[f.Name] = f
+	}
+
+	if f := byName["sortie"]; f.Type != "select" || f.FieldType != FieldEnum || len(f.Options) != 3 || !f.Required {
+		t.Fatalf("unexpected enum schema: %+v", f)
+	}
+	if f := byName["broches"]; f.Type != "number" || f.Step != "1" || *f.Min != 2 || *f.Max != 40 {
+		t.Fatalf("unexpected integer schema: %+v", f)
+	}
+	if f := byName["etanche"]; f.Type != "checkbox" {
+		t.Fatalf("unexpected boolean schema: %+v", f)
+	}
+	if f := byName["bus"]; f.Type != "list" || len(f.Options) != 3 {
+		t.Fatalf("unexpected list schema: %+v", f)
+	}
+	if f := byName["remarque"]; f.Type != "text" || f.FieldType != FieldText {
+		t.Fatalf("unexpected text schema: %+v", f)
+	}
+
+	// Sans type explicite, les domaines numériques restent saisis comme des nombres
+	seedTemplates()
+	for _, f := range TemplateFieldSchemas(mustTemplate(t, "bearing"), "") {
+		if f.Name == "d_int" && (f.Type != "number" || f.FieldType != FieldNumber) {
+			t.Fatalf("expected numeric input for dimension domain: %+v", f)
+		}
+	}
+}
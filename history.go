@@ -0,0 +1,235 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Entités suivies par l'historique
+const (
+	HistoryEntityPart     = "part"
+	HistoryEntityLocation = "location"
+)
+
+// HistoryEntry représente une ligne de l'historique (append-only)
+type HistoryEntry struct {
+	ID        int
+	Entity    string
+	EntityID  int
+	Action    string
+	Before    sql.NullString // JSON de l'état avant la modification
+	After     sql.NullString // JSON de l'état après la modification
+	Actor     string
+	CreatedAt string
+}
+
+// historyActor identifie l'auteur des modifications (RECYCLE_USER, sinon USER)
+var historyActor = defaultHistoryActor()
+
+func defaultHistoryActor() string {
+	for _, env := range []string{"RECYCLE_USER", "USER", "USERNAME"} {
+		if v := strings.TrimSpace(os.Getenv(env)); v != "" {
+			return v
+		}
+	}
+	return "inconnu"
+}
+
+// execer est satisfait par *sql.DB et *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// queryRower est satisfait par *sql.DB et *sql.Tx
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// queryer est satisfait par *sql.DB et *sql.Tx
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// partSnapshot est l'état d'une pièce enregistré dans l'historique
+type partSnapshot struct {
+	Type       string          `json:"type"`
+	Name       string          `json:"name"`
+	Props      json.RawMessage `json:"props"`
+	LocationID *int64          `json:"location_id"`
+	Quantity   int             `json:"quantity"`
+	State      string          `json:"state"`
+	DonorID    *int64          `json:"donor_id,omitempty"`
+	MinStock   *int64          `json:"min_stock,omitempty"`
+	UnitValue  *float64        `json:"unit_value,omitempty"`
+	Currency   *string         `json:"currency,omitempty"`
+	DeletedAt  *string         `json:"deleted_at,omitempty"`
+
+	TemplateVersion int `json:"template_version,omitempty"`
+}
+
+// locationSnapshot est l'état d'une localisation enregistré dans l'historique
+type locationSnapshot struct {
+	Name        string `json:"name"`
+	ParentID    *int64 `json:"parent_id"`
+	LocType     string `json:"loc_type"`
+	Description string `json:"description"`
+}
+
+// snapshotPart lit l'état courant d'une pièce (nil si elle n'existe pas)
+func snapshotPart(q queryRower, partID int) (*partSnapshot, error) {
+	var s partSnapshot
+	var props, currency, deletedAt sql.NullString
+	var locationID, donorID, minStock sql.NullInt64
+	var unitValue sql.NullFloat64
+	err := q.QueryRow(`SELECT type, name, props, location_id, quantity, state, donor_id, min_stock, unit_value, currency, deleted_at, template_version FROM parts WHERE id = ?`, partID).
+		Scan(&s.Type, &s.Name, &props, &locationID, &s.Quantity, &s.State, &donorID, &minStock, &unitValue, &currency, &deletedAt, &s.TemplateVersion)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.Props = json.RawMessage("{}")
+	if props.Valid && props.String != "" {
+		s.Props = json.RawMessage(props.String)
+	}
+	if locationID.Valid {
+		s.LocationID = &locationID.Int64
+	}
+	if donorID.Valid {
+		s.DonorID = &donorID.Int64
+	}
+	if minStock.Valid {
+		s.MinStock = &minStock.Int64
+	}
+	if unitValue.Valid {
+		s.UnitValue = &unitValue.Float64
+	}
+	if currency.Valid {
+		s.Currency = &currency.String
+	}
+	if deletedAt.Valid {
+		s.DeletedAt = &deletedAt.String
+	}
+	return &s, nil
+}
+
+// snapshotLocation lit l'état courant d'une localisation (nil si elle n'existe pas)
+func snapshotLocation(q queryRower, locationID int) (*locationSnapshot, error) {
+	var s locationSnapshot
+	var parentID sql.NullInt64
+	err := q.QueryRow(`SELECT name, parent_id, loc_type, description FROM locations WHERE id = ?`, locationID).
+		Scan(&s.Name, &parentID, &s.LocType, &s.Description)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if parentID.Valid {
+		s.ParentID = &parentID.Int64
+	}
+	return &s, nil
+}
+
+// recordHistory ajoute une ligne à l'historique. before/after sont sérialisés en JSON (nil = NULL).
+func recordHistory(ex execer, entity string, entityID int, action string, before, after interface{}) error {
+	beforeJSON, err := historyJSON(before)
+	if err != nil {
+		return err
+	}
+	afterJSON, err := historyJSON(after)
+	if err != nil {
+		return err
+	}
+
+	_, err = ex.Exec(`
+		INSERT INTO history (entity, entity_id, action, before, after, actor)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, entity, entityID, action, beforeJSON, afterJSON, historyActor)
+	return err
+}
+
+// historyJSON sérialise un état (les pointeurs nil deviennent NULL)
+func historyJSON(v interface{}) (sql.NullString, error) {
+	switch s := v.(type) {
+	case nil:
+		return sql.NullString{}, nil
+	case *partSnapshot:
+		if s == nil {
+			return sql.NullString{}, nil
+		}
+	case *locationSnapshot:
+		if s == nil {
+			return sql.NullString{}, nil
+		}
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("erreur sérialisation historique: %v", err)
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
+
+// ListHistory retourne l'historique d'une entité (plus récent en premier)
+func ListHistory(db *sql.DB, entity string, entityID int) ([]HistoryEntry, error) {
+	rows, err := db.Query(`
+		SELECT id, entity, entity_id, action, before, after, actor, created_at
+		FROM history
+		WHERE entity = ? AND entity_id = ?
+		ORDER BY id DESC
+	`, entity, entityID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []HistoryEntry
+	for rows.Next() {
+		var h HistoryEntry
+		if err := rows.Scan(&h.ID, &h.Entity, &h.EntityID, &h.Action, &h.Before, &h.After, &h.Actor, &h.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, h)
+	}
+
+	return entries, nil
+}
+
+// PrintHistory affiche l'historique d'une pièce ou d'une localisation
+func PrintHistory(db *sql.DB, entity string, entityID int) error {
+	entries, err := ListHistory(db, entity, entityID)
+	if err != nil {
+		return err
+	}
+
+	label := "pièce"
+	if entity == HistoryEntityLocation {
+		label = "localisation"
+	}
+
+	fmt.Printf("\n🕘 Historique de la %s ID %d:\n", label, entityID)
+	fmt.Println(strings.Repeat("─", 60))
+
+	if len(entries) == 0 {
+		fmt.Println("  Aucune modification enregistrée")
+		fmt.Println()
+		return nil
+	}
+
+	for _, h := range entries {
+		fmt.Printf("  %s  %-8s par %s\n", h.CreatedAt, h.Action, h.Actor)
+		if h.Before.Valid {
+			fmt.Printf("     avant: %s\n", h.Before.String)
+		}
+		if h.After.Valid {
+			fmt.Printf("     après: %s\n", h.After.String)
+		}
+	}
+
+	fmt.Println()
+	return nil
+}
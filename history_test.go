@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestHistoryRecordsPartChanges(t *testing.T) {
+	seedTemplates()
+	db := newTestDB(t)
+	defer db.Close()
+
+	historyActor = "alice"
+
+	loc, err := CreateLocation(db, "Boite Roulements", nil, "BOX", "")
+	if err != nil {
+		t.Fatalf("create location: %v", err)
+	}
+	id, err := CreatePart(db, "bearing", "Roulement 6204", `{"d_int":20,"d_ext":47,"width":14}`, nil, 1)
+	if err != nil {
+		t.Fatalf("create part: %v", err)
+	}
+	if _, err := EditPart(db, int(id), PartUpdate{Props: map[string]interface{}{"d_ext": 52}}); err != nil {
+		t.Fatalf("edit part: %v", err)
+	}
+	if err := SetPartLocation(db, int(id), loc.ID, false); err != nil {
+		t.Fatalf("set location: %v", err)
+	}
+
+	entries, err := ListHistory(db, HistoryEntityPart, int(id))
+	if err != nil {
+		t.Fatalf("list history: %v", err)
+	}
+	var actions []string
+	for _, h := range entries {
+		actions = append(actions, h.Action)
+		if h.Actor != "alice" {
+			t.Fatalf("expected actor alice, got %q", h.Actor)
+		}
+	}
+	if len(actions) != 3 || actions[0] != "move" || actions[1] != "update" || actions[2] != "create" {
+		t.Fatalf("unexpected history actions: %v", actions)
+	}
+
+	var before, after partSnapshot
+	if err := json.Unmarshal([]byte(entries[1].Before.String), &before); err != nil {
+		t.Fatalf("parse before: %v", err)
+	}
+	if err := json.Unmarshal([]byte(entries[1].After.String), &after); err != nil {
+		t.Fatalf("parse after: %v", err)
+	}
+	if string(before.Props) == string(after.Props) {
+		t.Fatalf("expected props to differ between before and after, got %s", before.Props)
+	}
+	if entries[2].Before.Valid {
+		t.Fatalf("create entry should have no 'before' state")
+	}
+}
+
+func TestHistoryRecordsLocationMoves(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+
+	a, _ := CreateLocation(db, "Armoire A", nil, "FURNITURE", "")
+	b, _ := CreateLocation(db, "Armoire B", nil, "FURNITURE", "")
+	box, _ := CreateLocation(db, "Boite", &a.ID, "BOX", "")
+
+	if err := MoveLocation(db, box.ID, &b.ID); err != nil {
+		t.Fatalf("move location: %v", err)
+	}
+
+	entries, err := ListHistory(db, HistoryEntityLocation, box.ID)
+	if err != nil {
+		t.Fatalf("list history: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Action != "move" || entries[1].Action != "create" {
+		t.Fatalf("unexpected location history: %+v", entries)
+	}
+
+	var after locationSnapshot
+	json.Unmarshal([]byte(entries[0].After.String), &after)
+	if after.ParentID == nil || int(*after.ParentID) != b.ID {
+		t.Fatalf("expected new parent %d in history, got %+v", b.ID, after.ParentID)
+	}
+}
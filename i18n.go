@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Templates multilingues: libellés par langue (clé "labels" / "descriptions" d'un template
+// ou d'un champ) et alias entre familles de types et de champs, ex:
+//
+//	name: roulement
+//	aliases: [bearing]          # type=bearing trouve aussi les roulements (et inversement)
+//	labels: {en: Bearing}
+//	fields:
+//	  largeur:
+//	    aliases: [width]        # prop=width:10..20 filtre aussi largeur
+//	    labels: {en: Width}
+
+// DefaultLang est la langue des noms et descriptions saisis dans les templates
+const DefaultLang = "fr"
+
+// normalizeLang réduit une langue à son code principal ("en-US" → "en"), DefaultLang si vide
+func normalizeLang(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if i := strings.IndexAny(lang, "-_,;"); i >= 0 {
+		lang = lang[:i]
+	}
+	if lang == "" {
+		return DefaultLang
+	}
+	return lang
+}
+
+// Label retourne le libellé du type dans une langue (défaut: nom du type capitalisé)
+func (t *Template) Label(lang string) string {
+	if label := t.Labels[normalizeLang(lang)]; label != "" {
+		return label
+	}
+	return strings.Title(t.Name)
+}
+
+// LocalizedDescription retourne la description du type dans une langue (défaut: description)
+func (t *Template) LocalizedDescription(lang string) string {
+	if desc := t.Descriptions[normalizeLang(lang)]; desc != "" {
+		return desc
+	}
+	return t.Description
+}
+
+// Label retourne le libellé d'un champ dans une langue (défaut: nom du champ)
+func (f FieldDef) Label(name, lang string) string {
+	if label := f.Labels[normalizeLang(lang)]; label != "" {
+		return label
+	}
+	return name
+}
+
+// LocalizedDescription retourne la description d'un champ dans une langue (défaut: description)
+func (f FieldDef) LocalizedDescription(lang string) string {
+	if desc := f.Descriptions[normalizeLang(lang)]; desc != "" {
+		return desc
+	}
+	return f.Description
+}
+
+// checkAliases vérifie les alias d'un template résolu (format des noms, collisions entre champs)
+func checkAliases(t *Template) error {
+	for _, alias := range t.Aliases {
+		if !templateNameRegex.MatchString(alias) || alias == t.Name {
+			return fmt.Errorf("alias invalide '%s'", alias)
+		}
+	}
+	for name, def := range t.Fields {
+		for _, alias := range def.Aliases {
+			if alias == "" || alias == name {
+				return fmt.Errorf("champ '%s': alias invalide '%s'", name, alias)
+			}
+			if _, ok := t.Fields[alias]; ok {
+				return fmt.Errorf("champ '%s': l'alias '%s' est déjà un champ du template", name, alias)
+			}
+		}
+	}
+	return nil
+}
+
+// aliasGroup retourne la composante de start dans un graphe d'alias non orienté
+func aliasGroup(edges map[string][]string, start string) map[string]bool {
+	group := map[string]bool{start: true}
+	queue := []string{start}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for _, next := range edges[node] {
+			if !group[next] {
+				group[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+	return group
+}
+
+// TypeFamily retourne un type et les types qui lui sont équivalents par alias (déclarés d'un côté
+// ou de l'autre, transitivement), triés. Un alias sans template propre résout vers son template.
+func TypeFamily(typeName string) []string {
+	edges := make(map[string][]string)
+	for _, name := range Templates.Names() {
+		tmpl, _ := Templates.Get(name)
+		for _, alias := range tmpl.Aliases {
+			edges[name] = append(edges[name], alias)
+			edges[alias] = append(edges[alias], name)
+		}
+	}
+
+	var family []string
+	for name := range aliasGroup(edges, typeName) {
+		family = append(family, name)
+	}
+	sort.Strings(family)
+	return family
+}
+
+// EquivalentFields retourne un champ et ses équivalents par alias dans les templates des types
+// donnés (tous les templates si types est vide), triés: "width" → [largeur, width]
+func EquivalentFields(types []string, field string) []string {
+	names := types
+	if len(names) == 0 {
+		names = Templates.Names()
+	}
+
+	edges := make(map[string][]string)
+	for _, typeName := range names {
+		tmpl, ok := Templates.Get(typeName)
+		if !ok {
+			continue
+		}
+		for name, def := range tmpl.Fields {
+			for _, alias := range def.Aliases {
+				edges[name] = append(edges[name], alias)
+				edges[alias] = append(edges[alias], name)
+			}
+		}
+	}
+
+	var fields []string
+	for name := range aliasGroup(edges, field) {
+		fields = append(fields, name)
+	}
+	sort.Strings(fields)
+	return fields
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// seedBilingualTemplates ajoute les familles roulement (fr) et bearing (en), reliées par alias
+func seedBilingualTemplates(t *testing.T) {
+	t.Helper()
+	raw := map[string]*Template{
+		"roulement": {
+			Name:        "roulement",
+			Description: "Roulement",
+			Aliases:     []string{"bearing", "ball_bearing"},
+			Labels:      map[string]string{"en": "Bearing"},
+			Fields: map[string]FieldDef{
+				"d_int":   {Required: true, Domain: "dimension", DefaultUnit: "mm", Labels: map[string]string{"en": "Inner diameter"}},
+				"largeur": {Domain: "dimension", DefaultUnit: "mm", Aliases: []string{"width"}, Labels: map[string]string{"en": "Width"}},
+			},
+		},
+		"roulement_etanche": {Name: "roulement_etanche", Extends: "roulement"},
+		"bearing": {
+			Name:         "bearing",
+			Description:  "Bearing",
+			Descriptions: map[string]string{"fr": "Roulement ISO"},
+			Fields: map[string]FieldDef{
+				"d_int": {Required: true, Domain: "dimension", DefaultUnit: "mm"},
+				"width": {Domain: "dimension", DefaultUnit: "mm"},
+			},
+		},
+		"vis": {Name: "vis", Fields: map[string]FieldDef{"largeur": {}}},
+	}
+	setTestTemplates(t, raw)
+}
+
+func TestTypeFamilyAndEquivalentFields(t *testing.T) {
+	seedBilingualTemplates(t)
+
+	want := []string{"ball_bearing", "bearing", "roulement"}
+	for _, typeName := range want {
+		if got := TypeFamily(typeName); !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s: expected family %v, got %v", typeName, want, got)
+		}
+	}
+	if got := TypeFamily("vis"); !reflect.DeepEqual(got, []string{"vis"}) {
+		t.Fatalf("expected vis alone, got %v", got)
+	}
+
+	if got := EquivalentFields([]string{"bearing"}, "width"); !reflect.DeepEqual(got, []string{"width"}) {
+		t.Fatalf("aliases of other templates should not apply, got %v", got)
+	}
+	if got := EquivalentFields(nil, "width"); !reflect.DeepEqual(got, []string{"largeur", "width"}) {
+		t.Fatalf("expected largeur and width, got %v", got)
+	}
+}
+
+func TestSearchResolvesAliases(t *testing.T) {
+	seedBilingualTemplates(t)
+	db := newTestDB(t)
+	defer db.Close()
+
+	if _, err := CreatePart(db, "roulement", "Roulement 6204", `{"d_int":20,"largeur":14}`, nil, 1); err != nil {
+		t.Fatalf("create part: %v", err)
+	}
+	if _, err := CreatePart(db, "bearing", "Bearing 6001", `{"d_int":12,"width":8}`, nil, 1); err != nil {
+		t.Fatalf("create part: %v", err)
+	}
+	if _, err := CreatePart(db, "roulement_etanche", "Roulement 6204-2RS", `{"d_int":20,"largeur":14}`, nil, 1); err != nil {
+		t.Fatalf("create part: %v", err)
+	}
+	if _, err := CreatePart(db, "vis", "Vis", `{"largeur":14}`, nil, 1); err != nil {
+		t.Fatalf("create part: %v", err)
+	}
+
+	parts, err := SearchPartsDB(db, SearchFilters{Type: "bearing"})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(parts) != 2 {
+		t.Fatalf("expected bearing and roulement parts, got %+v", parts)
+	}
+
+	parts, err = SearchPartsDB(db, SearchFilters{Type: "ball_bearing", IncludeSubtypes: true})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(parts) != 3 {
+		t.Fatalf("expected alias with subtypes to find 3 parts, got %+v", parts)
+	}
+
+	criteria, _ := ParseSearchProp("width:10..20")
+	parts, err = SearchPartsDB(db, SearchFilters{Type: "bearing", Criteria: criteria})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(parts) != 1 || parts[0].Name != "Roulement 6204" {
+		t.Fatalf("expected width filter to match largeur, got %+v", parts)
+	}
+}
+
+func TestLocalizedLabels(t *testing.T) {
+	seedBilingualTemplates(t)
+	roulement := mustTemplate(t, "roulement")
+
+	if roulement.Label("en-US") != "Bearing" || roulement.Label("") != "Roulement" {
+		t.Fatalf("unexpected template labels: %q / %q", roulement.Label("en-US"), roulement.Label(""))
+	}
+	if desc := mustTemplate(t, "bearing").LocalizedDescription("fr"); desc != "Roulement ISO" {
+		t.Fatalf("unexpected localized description: %q", desc)
+	}
+
+	labels := map[string]string{}
+	for _, f := range TemplateFieldSchemas(roulement, "en") {
+		labels[f.Name] = f.Label
+	}
+	if labels["largeur"] != "Width" || labels["d_int"] != "Inner diameter" {
+		t.Fatalf("unexpected field labels: %v", labels)
+	}
+	for _, f := range TemplateFieldSchemas(roulement, "de") {
+		if f.Label != f.Name {
+			t.Fatalf("expected name as fallback label, got %q", f.Label)
+		}
+	}
+}
+
+func TestResolveTemplatesRejectsInvalidAliases(t *testing.T) {
+	cases := map[string]*Template{
+		"alias de type invalide": {Aliases: []string{"Bad Name"}},
+		"alias = champ":          {Fields: map[string]FieldDef{"largeur": {Aliases: []string{"width"}}, "width": {}}},
+	}
+	for label, tmpl := range cases {
+		tmpl.Name = "t"
+		if _, err := ResolveTemplates(map[string]*Template{"t": tmpl}); err == nil {
+			t.Fatalf("%s: expected error", label)
+		}
+	}
+}
@@ -74,10 +74,7 @@ func importCSV(db *sql.DB, opts ImportOptions) (*ImportStats, error) {
 	// Trouver les indices des colonnes spéciales
 	typeIdx := findIndex(headers, "type")
 	nameIdx := findIndex(headers, "name", "nom")
-
-	if nameIdx == -1 {
-		return nil, fmt.Errorf("colonne 'name' ou 'nom' requise dans l'en-tête CSV")
-	}
+	quantityIdx := findIndex(headers, "quantity", "quantite", "quantité", "qty")
 
 	stats := &ImportStats{}
 	start := time.Now()
@@ -89,7 +86,7 @@ func importCSV(db *sql.DB, opts ImportOptions) (*ImportStats, error) {
 	}
 	defer tx.Rollback()
 
-	stmt, err := tx.Prepare("INSERT INTO parts (type, name, props) VALUES (?, ?, ?)")
+	stmt, err := tx.Prepare("INSERT INTO parts (type, name, props, props_units, quantity, template_version) VALUES (?, ?, ?, ?, ?, ?)")
 	if err != nil {
 		return nil, fmt.Errorf("erreur préparation: %v", err)
 	}
@@ -132,24 +129,32 @@ func importCSV(db *sql.DB, opts ImportOptions) (*ImportStats, error) {
 			continue
 		}
 
-		// Extraire le nom
-		if nameIdx >= len(record) {
-			stats.Errors++
-			stats.ErrorMsgs = append(stats.ErrorMsgs, fmt.Sprintf("ligne %d: colonne 'name' manquante", lineNum))
-			continue
+		// Extraire le nom (colonne facultative si le template a un name_pattern)
+		name := ""
+		if nameIdx != -1 && nameIdx < len(record) {
+			name = strings.TrimSpace(record[nameIdx])
 		}
-		name := strings.TrimSpace(record[nameIdx])
-		if name == "" {
+
+		// Quantité en stock (colonne facultative: une ligne = un objet)
+		var quantityValue interface{}
+		if quantityIdx != -1 && quantityIdx < len(record) {
+			quantityValue = strings.TrimSpace(record[quantityIdx])
+		}
+		quantity, err := parseImportQuantity(quantityValue)
+		if err != nil {
 			stats.Errors++
-			stats.ErrorMsgs = append(stats.ErrorMsgs, fmt.Sprintf("ligne %d: nom vide", lineNum))
+			stats.ErrorMsgs = append(stats.ErrorMsgs, fmt.Sprintf("ligne %d: %v", lineNum, err))
+			if opts.StopOnErr {
+				return stats, fmt.Errorf("ligne %d: %v", lineNum, err)
+			}
 			continue
 		}
 
 		// Construire les props à partir des autres colonnes
 		props := make(map[string]interface{})
 		for i, header := range headers {
-			if i == typeIdx || i == nameIdx {
-				continue // Ignorer type et name
+			if i == typeIdx || i == nameIdx || i == quantityIdx {
+				continue // Ignorer type, name et quantity
 			}
 			if i >= len(record) {
 				continue
@@ -168,8 +173,7 @@ func importCSV(db *sql.DB, opts ImportOptions) (*ImportStats, error) {
 		}
 
 		// Normaliser les unités
-		fieldUnits := GetFieldUnits(typeName)
-		normalizedProps, err := NormalizeProps(props, fieldUnits)
+		normalizedProps, units, err := NormalizePartPropsWithUnits(typeName, props)
 		if err != nil {
 			stats.Errors++
 			stats.ErrorMsgs = append(stats.ErrorMsgs, fmt.Sprintf("ligne %d: %v", lineNum, err))
@@ -178,6 +182,16 @@ func importCSV(db *sql.DB, opts ImportOptions) (*ImportStats, error) {
 			}
 			continue
 		}
+		ComputeProps(typeName, normalizedProps)
+
+		if name == "" {
+			name = GeneratePartName(typeName, normalizedProps)
+		}
+		if name == "" {
+			stats.Errors++
+			stats.ErrorMsgs = append(stats.ErrorMsgs, fmt.Sprintf("ligne %d: nom vide (colonne 'name' ou 'nom', ou name_pattern du template)", lineNum))
+			continue
+		}
 
 		// Valider selon le template
 		if typeName != "" {
@@ -201,7 +215,7 @@ func importCSV(db *sql.DB, opts ImportOptions) (*ImportStats, error) {
 
 		// Insérer en DB (sauf si dry-run)
 		if !opts.DryRun {
-			_, err = stmt.Exec(typeName, name, string(propsJSON))
+			err = insertImportedPart(tx, stmt, typeName, name, string(propsJSON), units, quantity)
 			if err != nil {
 				stats.Errors++
 				stats.ErrorMsgs = append(stats.ErrorMsgs, fmt.Sprintf("ligne %d: erreur DB: %v", lineNum, err))
@@ -254,7 +268,7 @@ func importJSON(db *sql.DB, opts ImportOptions) (*ImportStats, error) {
 	}
 	defer tx.Rollback()
 
-	stmt, err := tx.Prepare("INSERT INTO parts (type, name, props) VALUES (?, ?, ?)")
+	stmt, err := tx.Prepare("INSERT INTO parts (type, name, props, props_units, quantity, template_version) VALUES (?, ?, ?, ?, ?, ?)")
 	if err != nil {
 		return nil, fmt.Errorf("erreur préparation: %v", err)
 	}
@@ -291,11 +305,20 @@ func importJSON(db *sql.DB, opts ImportOptions) (*ImportStats, error) {
 		delete(record, "name")
 		delete(record, "nom")
 
-		if name == "" {
+		// Extraire la quantité en stock
+		var quantityValue interface{}
+		for _, key := range []string{"quantity", "quantite", "quantité", "qty"} {
+			if v, ok := record[key]; ok {
+				quantityValue = v
+				delete(record, key)
+			}
+		}
+		quantity, err := parseImportQuantity(quantityValue)
+		if err != nil {
 			stats.Errors++
-			stats.ErrorMsgs = append(stats.ErrorMsgs, fmt.Sprintf("enregistrement %d: nom manquant", lineNum))
+			stats.ErrorMsgs = append(stats.ErrorMsgs, fmt.Sprintf("enregistrement %d: %v", lineNum, err))
 			if opts.StopOnErr {
-				return stats, fmt.Errorf("enregistrement %d: nom manquant", lineNum)
+				return stats, fmt.Errorf("enregistrement %d: %v", lineNum, err)
 			}
 			continue
 		}
@@ -304,8 +327,7 @@ func importJSON(db *sql.DB, opts ImportOptions) (*ImportStats, error) {
 		props := record
 
 		// Normaliser les unités
-		fieldUnits := GetFieldUnits(typeName)
-		normalizedProps, err := NormalizeProps(props, fieldUnits)
+		normalizedProps, units, err := NormalizePartPropsWithUnits(typeName, props)
 		if err != nil {
 			stats.Errors++
 			stats.ErrorMsgs = append(stats.ErrorMsgs, fmt.Sprintf("enregistrement %d: %v", lineNum, err))
@@ -314,6 +336,20 @@ func importJSON(db *sql.DB, opts ImportOptions) (*ImportStats, error) {
 			}
 			continue
 		}
+		ComputeProps(typeName, normalizedProps)
+
+		// Nom automatique selon le template s'il n'est pas fourni
+		if name == "" {
+			name = GeneratePartName(typeName, normalizedProps)
+		}
+		if name == "" {
+			stats.Errors++
+			stats.ErrorMsgs = append(stats.ErrorMsgs, fmt.Sprintf("enregistrement %d: nom manquant", lineNum))
+			if opts.StopOnErr {
+				return stats, fmt.Errorf("enregistrement %d: nom manquant", lineNum)
+			}
+			continue
+		}
 
 		// Valider selon le template
 		if typeName != "" {
@@ -337,7 +373,7 @@ func importJSON(db *sql.DB, opts ImportOptions) (*ImportStats, error) {
 
 		// Insérer en DB
 		if !opts.DryRun {
-			_, err = stmt.Exec(typeName, name, string(propsJSON))
+			err = insertImportedPart(tx, stmt, typeName, name, string(propsJSON), units, quantity)
 			if err != nil {
 				stats.Errors++
 				stats.ErrorMsgs = append(stats.ErrorMsgs, fmt.Sprintf("enregistrement %d: erreur DB: %v", lineNum, err))
@@ -365,6 +401,58 @@ func importJSON(db *sql.DB, opts ImportOptions) (*ImportStats, error) {
 	return stats, nil
 }
 
+// insertImportedPart insère une pièce importée (avec les unités de ses props) et l'enregistre
+// dans l'historique; la quantité importée est une entrée de stock, comme à la création
+func insertImportedPart(tx *sql.Tx, stmt *sql.Stmt, typeName, name, propsJSON string, units map[string]PropUnit, quantity int) error {
+	unitsJSON, err := encodePropUnits(units)
+	if err != nil {
+		return err
+	}
+	res, err := stmt.Exec(typeName, name, propsJSON, unitsJSON, quantity, TemplateVersion(typeName))
+	if err != nil {
+		return err
+	}
+	id, _ := res.LastInsertId()
+
+	after, err := snapshotPart(tx, int(id))
+	if err != nil {
+		return err
+	}
+	if err := recordHistory(tx, HistoryEntityPart, int(id), "import", nil, after); err != nil {
+		return err
+	}
+	if quantity > 0 {
+		return insertStockMovement(tx, int(id), MovementIn, quantity, quantity, "import")
+	}
+	return nil
+}
+
+// parseImportQuantity lit la quantité d'une ligne importée (1 si absente)
+func parseImportQuantity(value interface{}) (int, error) {
+	var quantity float64
+	switch v := value.(type) {
+	case nil:
+		return 1, nil
+	case float64:
+		quantity = v
+	case string:
+		if v == "" {
+			return 1, nil
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, fmt.Errorf("quantité invalide: '%s'", v)
+		}
+		quantity = f
+	default:
+		return 0, fmt.Errorf("quantité invalide: %v", value)
+	}
+	if quantity < 0 || quantity != float64(int(quantity)) {
+		return 0, fmt.Errorf("quantité invalide: %v (entier positif attendu)", value)
+	}
+	return int(quantity), nil
+}
+
 // findIndex trouve l'index d'une colonne par ses noms possibles
 func findIndex(headers []string, names ...string) int {
 	for i, h := range headers {
@@ -15,6 +15,9 @@ import (
 	"golang.org/x/image/math/fixed"
 )
 
+// maxLabelProps limite le nombre de props imprimées sur une étiquette
+const maxLabelProps = 4
+
 // GenerateLabelPNG génère une étiquette PNG avec QR et texte et écrit sur writer
 func GenerateLabelPNG(dbPath *PartMeta, qrContent string, w io.Writer) error {
 	if dbPath == nil || !dbPath.Found {
@@ -34,9 +37,21 @@ func GenerateLabelPNG(dbPath *PartMeta, qrContent string, w io.Writer) error {
 		dbPath.Name,
 		dbPath.Type,
 	}
+	if dbPath.State != "" {
+		textLines = append(textLines, "Etat: "+StateLabel(dbPath.State))
+	}
 	if dbPath.LocationPath != "" {
 		textLines = append(textLines, dbPath.LocationPath)
 	}
+	// Cotes principales, avec leur unité (les props sans unité restent sur la fiche)
+	shown := 0
+	for _, f := range dbPath.DisplayFields(DisplayUnits{}) {
+		if f.Unit == "" || shown == maxLabelProps {
+			continue
+		}
+		textLines = append(textLines, f.Field+": "+f.Text)
+		shown++
+	}
 
 	// Dimensions
 	qrSize := qrImg.Bounds().Dx()
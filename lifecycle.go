@@ -0,0 +1,116 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// États du cycle de vie d'une pièce (récupérée sur du matériel en fin de vie)
+const (
+	StateUntested = "untested" // Récupérée, pas encore testée
+	StateWorking  = "working"  // Testée et fonctionnelle
+	StateBroken   = "broken"   // Testée et en panne
+	StateSpare    = "spare"    // Conservée pour pièces détachées
+)
+
+// StateLabels associe chaque état à son libellé d'affichage
+var StateLabels = map[string]string{
+	StateUntested: "non testé",
+	StateWorking:  "fonctionnel",
+	StateBroken:   "en panne",
+	StateSpare:    "pour pièces",
+}
+
+// StateTransitions définit les transitions autorisées depuis chaque état
+var StateTransitions = map[string][]string{
+	StateUntested: {StateWorking, StateBroken, StateSpare},
+	StateWorking:  {StateBroken, StateSpare},
+	StateBroken:   {StateWorking, StateSpare}, // Réparée, ou démontée
+	StateSpare:    {},
+}
+
+// IsValidState indique si un état est connu
+func IsValidState(state string) bool {
+	_, ok := StateLabels[state]
+	return ok
+}
+
+// StateLabel retourne le libellé d'un état (l'état brut s'il est inconnu)
+func StateLabel(state string) string {
+	if label, ok := StateLabels[state]; ok {
+		return label
+	}
+	return state
+}
+
+// StateLabel retourne le libellé de l'état de la pièce (utilisé par les templates web)
+func (p *PartMeta) StateLabel() string {
+	return StateLabel(p.State)
+}
+
+// ValidStates retourne la liste triée des états connus
+func ValidStates() []string {
+	states := make([]string, 0, len(StateLabels))
+	for s := range StateLabels {
+		states = append(states, s)
+	}
+	sort.Strings(states)
+	return states
+}
+
+// CanTransition vérifie qu'une pièce peut passer de l'état from à l'état to
+func CanTransition(from, to string) error {
+	if !IsValidState(to) {
+		return fmt.Errorf("état inconnu: %s (%s)", to, strings.Join(ValidStates(), ", "))
+	}
+	if from == to {
+		return fmt.Errorf("la pièce est déjà dans l'état '%s'", StateLabel(to))
+	}
+	for _, allowed := range StateTransitions[from] {
+		if allowed == to {
+			return nil
+		}
+	}
+	targets := StateTransitions[from]
+	if len(targets) == 0 {
+		return fmt.Errorf("transition interdite: '%s' est un état final", StateLabel(from))
+	}
+	return fmt.Errorf("transition interdite: %s → %s (possibles: %s)", from, to, strings.Join(targets, ", "))
+}
+
+// SetPartState fait passer une pièce dans un nouvel état en respectant les transitions
+func SetPartState(db *sql.DB, partID int, state string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := snapshotPart(tx, partID)
+	if err != nil {
+		return err
+	}
+	if before == nil || before.DeletedAt != nil {
+		return fmt.Errorf("pièce ID %d introuvable", partID)
+	}
+
+	if err := CanTransition(before.State, state); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("UPDATE parts SET state = ? WHERE id = ?", state, partID); err != nil {
+		return err
+	}
+
+	after, err := snapshotPart(tx, partID)
+	if err != nil {
+		return err
+	}
+	if err := recordHistory(tx, HistoryEntityPart, partID, "state", before, after); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
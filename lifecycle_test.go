@@ -0,0 +1,104 @@
+package main
+
+import "testing"
+
+func TestNewPartIsUntested(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+
+	id, err := CreatePart(db, "", "Alimentation ATX", "{}", nil, 1)
+	if err != nil {
+		t.Fatalf("create part: %v", err)
+	}
+
+	meta, err := GetPartMeta(db, int(id))
+	if err != nil {
+		t.Fatalf("get part: %v", err)
+	}
+	if meta.State != StateUntested {
+		t.Fatalf("expected state %q, got %q", StateUntested, meta.State)
+	}
+}
+
+func TestSetPartStateTransitions(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+
+	id, err := CreatePart(db, "", "Moteur pas à pas", "{}", nil, 1)
+	if err != nil {
+		t.Fatalf("create part: %v", err)
+	}
+	partID := int(id)
+
+	if err := SetPartState(db, partID, StateBroken); err != nil {
+		t.Fatalf("untested -> broken: %v", err)
+	}
+	if err := SetPartState(db, partID, StateBroken); err == nil {
+		t.Fatalf("expected error when state is unchanged")
+	}
+	if err := SetPartState(db, partID, StateUntested); err == nil {
+		t.Fatalf("expected error for broken -> untested")
+	}
+	if err := SetPartState(db, partID, "cassé"); err == nil {
+		t.Fatalf("expected error for unknown state")
+	}
+	if err := SetPartState(db, partID, StateWorking); err != nil {
+		t.Fatalf("broken -> working (repaired): %v", err)
+	}
+	if err := SetPartState(db, partID, StateSpare); err != nil {
+		t.Fatalf("working -> spare: %v", err)
+	}
+	if err := SetPartState(db, partID, StateWorking); err == nil {
+		t.Fatalf("expected error: spare is a final state")
+	}
+
+	entries, err := ListHistory(db, HistoryEntityPart, partID)
+	if err != nil {
+		t.Fatalf("list history: %v", err)
+	}
+	states := 0
+	for _, h := range entries {
+		if h.Action == "state" {
+			states++
+		}
+	}
+	if states != 3 {
+		t.Fatalf("expected 3 state changes in history, got %d", states)
+	}
+}
+
+func TestSearchPartsByState(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+
+	working, _ := CreatePart(db, "", "Ventilateur 80mm", "{}", nil, 1)
+	broken, _ := CreatePart(db, "", "Ventilateur 120mm", "{}", nil, 1)
+	CreatePart(db, "", "Ventilateur 92mm", "{}", nil, 1)
+
+	if err := SetPartState(db, int(working), StateWorking); err != nil {
+		t.Fatalf("set working: %v", err)
+	}
+	if err := SetPartState(db, int(broken), StateBroken); err != nil {
+		t.Fatalf("set broken: %v", err)
+	}
+
+	parts, err := SearchPartsDB(db, SearchFilters{Name: "Ventilateur", State: StateWorking})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(parts) != 1 || parts[0].ID != int(working) {
+		t.Fatalf("expected only the working part, got %+v", parts)
+	}
+
+	all, err := SearchPartsDB(db, SearchFilters{Name: "Ventilateur"})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(all) != 3 {
+		t.Fatalf("expected 3 parts without state filter, got %d", len(all))
+	}
+
+	if _, err := SearchPartsDB(db, SearchFilters{State: "neuf"}); err == nil {
+		t.Fatalf("expected error for unknown state filter")
+	}
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// loanDateLayout est le format des dates d'échéance (AAAA-MM-JJ)
+const loanDateLayout = "2006-01-02"
+
+// Loan représente le prêt d'une pièce (ou d'un outil) à un membre
+type Loan struct {
+	ID         int     `json:"id"`
+	PartID     int     `json:"part_id"`
+	PartName   string  `json:"part_name,omitempty"`
+	Borrower   string  `json:"borrower"`
+	Quantity   int     `json:"quantity"`
+	DueDate    string  `json:"due_date"`
+	Notes      string  `json:"notes,omitempty"`
+	OutAt      string  `json:"out_at"`
+	ReturnedAt *string `json:"returned_at,omitempty"`
+	Overdue    bool    `json:"overdue"`
+}
+
+// ParseDueDate interprète une échéance: date AAAA-MM-JJ, ou durée en jours ("14d", "14")
+func ParseDueDate(s string, now time.Time) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", fmt.Errorf("échéance vide")
+	}
+	if t, err := time.Parse(loanDateLayout, s); err == nil {
+		return t.Format(loanDateLayout), nil
+	}
+	var days int
+	if _, err := fmt.Sscanf(strings.TrimSuffix(s, "d"), "%d", &days); err == nil && days > 0 {
+		return now.AddDate(0, 0, days).Format(loanDateLayout), nil
+	}
+	return "", fmt.Errorf("échéance invalide: %s (AAAA-MM-JJ ou nombre de jours, ex: 14d)", s)
+}
+
+// CheckoutPart prête quantity unités d'une pièce à un emprunteur jusqu'à dueDate (AAAA-MM-JJ)
+func CheckoutPart(db *sql.DB, partID int, borrower string, quantity int, dueDate, notes string) (*Loan, error) {
+	borrower = strings.TrimSpace(borrower)
+	if borrower == "" {
+		return nil, fmt.Errorf("le nom de l'emprunteur est requis")
+	}
+	if quantity <= 0 {
+		return nil, fmt.Errorf("quantité invalide: %d", quantity)
+	}
+	if _, err := time.Parse(loanDateLayout, dueDate); err != nil {
+		return nil, fmt.Errorf("échéance invalide: %s (AAAA-MM-JJ)", dueDate)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var stock, loaned int
+	err = tx.QueryRow(`
+		SELECT p.quantity,
+			(SELECT COALESCE(SUM(l.quantity), 0) FROM loans l WHERE l.part_id = p.id AND l.returned_at IS NULL)
+		FROM parts p
+		WHERE p.id = ? AND p.deleted_at IS NULL
+	`, partID).Scan(&stock, &loaned)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("pièce ID %d introuvable", partID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if available := stock - loaned; quantity > available {
+		return nil, fmt.Errorf("disponible insuffisant: %d en stock, %d déjà prêté(s), %d demandé(s)", stock, loaned, quantity)
+	}
+
+	res, err := tx.Exec(`
+		INSERT INTO loans (part_id, borrower, quantity, due_date, notes)
+		VALUES (?, ?, ?, ?, ?)
+	`, partID, borrower, quantity, dueDate, notes)
+	if err != nil {
+		return nil, err
+	}
+	id, _ := res.LastInsertId()
+
+	loan, err := getLoan(tx, int(id))
+	if err != nil {
+		return nil, err
+	}
+	if err := recordHistory(tx, HistoryEntityPart, partID, "checkout", nil, loan); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return loan, nil
+}
+
+// ReturnLoan marque un prêt comme rendu
+func ReturnLoan(db *sql.DB, loanID int) (*Loan, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	before, err := getLoan(tx, loanID)
+	if err != nil {
+		return nil, err
+	}
+	if before.ReturnedAt != nil {
+		return nil, fmt.Errorf("prêt ID %d déjà rendu le %s", loanID, *before.ReturnedAt)
+	}
+
+	if _, err := tx.Exec("UPDATE loans SET returned_at = CURRENT_TIMESTAMP WHERE id = ?", loanID); err != nil {
+		return nil, err
+	}
+
+	after, err := getLoan(tx, loanID)
+	if err != nil {
+		return nil, err
+	}
+	if err := recordHistory(tx, HistoryEntityPart, after.PartID, "return", before, after); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return after, nil
+}
+
+// loanColumns est la liste des colonnes lues par scanLoan
+const loanColumns = `l.id, l.part_id, p.name, l.borrower, l.quantity, l.due_date, l.notes, l.out_at, l.returned_at,
+	(l.returned_at IS NULL AND l.due_date < date('now', 'localtime'))`
+
+func scanLoan(scan func(dest ...interface{}) error) (*Loan, error) {
+	var l Loan
+	var returnedAt sql.NullString
+	if err := scan(&l.ID, &l.PartID, &l.PartName, &l.Borrower, &l.Quantity, &l.DueDate, &l.Notes, &l.OutAt, &returnedAt, &l.Overdue); err != nil {
+		return nil, err
+	}
+	if returnedAt.Valid {
+		l.ReturnedAt = &returnedAt.String
+	}
+	return &l, nil
+}
+
+func getLoan(q queryRower, loanID int) (*Loan, error) {
+	row := q.QueryRow(`SELECT `+loanColumns+`
+		FROM loans l
+		JOIN parts p ON p.id = l.part_id
+		WHERE l.id = ?`, loanID)
+	loan, err := scanLoan(row.Scan)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("prêt ID %d introuvable", loanID)
+	}
+	return loan, err
+}
+
+// ListLoans retourne les prêts en cours (tous si includeReturned), éventuellement limités
+// aux prêts en retard ou à une pièce (partID > 0). Les pièces à la corbeille sont exclues.
+func ListLoans(db *sql.DB, partID int, overdueOnly, includeReturned bool) ([]Loan, error) {
+	query := `SELECT ` + loanColumns + `
+		FROM loans l
+		JOIN parts p ON p.id = l.part_id
+		WHERE (? = 0 OR l.part_id = ?) AND p.deleted_at IS NULL`
+	if !includeReturned {
+		query += ` AND l.returned_at IS NULL`
+	}
+	if overdueOnly {
+		query += ` AND l.returned_at IS NULL AND l.due_date < date('now', 'localtime')`
+	}
+	query += ` ORDER BY l.due_date, l.id`
+
+	rows, err := db.Query(query, partID, partID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var loans []Loan
+	for rows.Next() {
+		l, err := scanLoan(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		loans = append(loans, *l)
+	}
+
+	return loans, nil
+}
+
+// GetLoanedQuantities retourne la quantité actuellement prêtée de chaque pièce
+func GetLoanedQuantities(db *sql.DB, partIDs []int) (map[int]int, error) {
+	result := make(map[int]int)
+	if len(partIDs) == 0 {
+		return result, nil
+	}
+
+	rows, err := db.Query(`
+		SELECT part_id, SUM(quantity)
+		FROM loans
+		WHERE returned_at IS NULL
+		GROUP BY part_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	wanted := make(map[int]bool, len(partIDs))
+	for _, id := range partIDs {
+		wanted[id] = true
+	}
+	for rows.Next() {
+		var partID, qty int
+		if err := rows.Scan(&partID, &qty); err != nil {
+			return nil, err
+		}
+		if wanted[partID] {
+			result[partID] = qty
+		}
+	}
+
+	return result, nil
+}
+
+// PrintLoans affiche la liste des prêts
+func PrintLoans(db *sql.DB, overdueOnly, includeReturned bool) error {
+	loans, err := ListLoans(db, 0, overdueOnly, includeReturned)
+	if err != nil {
+		return err
+	}
+
+	title := "Prêts en cours"
+	if overdueOnly {
+		title = "Prêts en retard"
+	} else if includeReturned {
+		title = "Tous les prêts"
+	}
+	fmt.Printf("\n🤝 %s:\n", title)
+	fmt.Println(strings.Repeat("─", 60))
+
+	if len(loans) == 0 {
+		fmt.Println("  Aucun prêt")
+		fmt.Println()
+		return nil
+	}
+
+	for _, l := range loans {
+		marker := "  "
+		if l.Overdue {
+			marker = "⚠️ "
+		}
+		fmt.Printf("%s[prêt %d] %s (pièce ID %d) ×%d → %s, échéance %s", marker, l.ID, l.PartName, l.PartID, l.Quantity, l.Borrower, l.DueDate)
+		if l.ReturnedAt != nil {
+			fmt.Printf(", rendu le %s", *l.ReturnedAt)
+		}
+		fmt.Println()
+		if l.Notes != "" {
+			fmt.Printf("     %s\n", l.Notes)
+		}
+	}
+
+	fmt.Printf("\n%d prêt(s)\n\n", len(loans))
+	return nil
+}
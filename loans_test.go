@@ -0,0 +1,132 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseDueDate(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	cases := map[string]string{
+		"2026-04-01": "2026-04-01",
+		"14d":        "2026-03-24",
+		"7":          "2026-03-17",
+	}
+	for in, want := range cases {
+		got, err := ParseDueDate(in, now)
+		if err != nil || got != want {
+			t.Fatalf("ParseDueDate(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseDueDate("demain", now); err == nil {
+		t.Fatalf("expected error for invalid due date")
+	}
+}
+
+func TestCheckoutAndReturn(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+
+	id, _ := CreatePart(db, "", "Perceuse Bosch", "{}", nil, 2)
+
+	loan, err := CheckoutPart(db, int(id), "Alice", 1, "2000-01-01", "Projet robot")
+	if err != nil {
+		t.Fatalf("checkout: %v", err)
+	}
+	if !loan.Overdue {
+		t.Fatalf("expected loan due in the past to be overdue")
+	}
+	if _, err := CheckoutPart(db, int(id), "Bob", 1, "2999-01-01", ""); err != nil {
+		t.Fatalf("second checkout: %v", err)
+	}
+	if _, err := CheckoutPart(db, int(id), "Carol", 1, "2999-01-01", ""); err == nil {
+		t.Fatalf("expected checkout beyond stock to fail")
+	}
+
+	meta, _ := GetPartMeta(db, int(id))
+	if len(meta.Loans) != 2 || meta.Available() != 0 {
+		t.Fatalf("expected 2 active loans and nothing available, got %d loans, %d available", len(meta.Loans), meta.Available())
+	}
+
+	overdue, err := ListLoans(db, 0, true, false)
+	if err != nil {
+		t.Fatalf("list overdue: %v", err)
+	}
+	if len(overdue) != 1 || overdue[0].Borrower != "Alice" {
+		t.Fatalf("expected Alice's loan overdue, got %+v", overdue)
+	}
+
+	if _, err := ReturnLoan(db, loan.ID); err != nil {
+		t.Fatalf("return: %v", err)
+	}
+	if _, err := ReturnLoan(db, loan.ID); err == nil {
+		t.Fatalf("expected second return to fail")
+	}
+
+	loaned, _ := GetLoanedQuantities(db, []int{int(id)})
+	if loaned[int(id)] != 1 {
+		t.Fatalf("expected 1 unit still loaned, got %d", loaned[int(id)])
+	}
+	history, _ := ListHistory(db, HistoryEntityPart, int(id))
+	found := false
+	for _, h := range history {
+		if h.Action == "return" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected return to be recorded in history")
+	}
+}
+
+func TestListLoansSkipsTrashedParts(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+
+	id, _ := CreatePart(db, "", "Multimètre Fluke", "{}", nil, 1)
+	loan, err := CheckoutPart(db, int(id), "Alice", 1, "2999-01-01", "")
+	if err != nil {
+		t.Fatalf("checkout: %v", err)
+	}
+	if _, err := ReturnLoan(db, loan.ID); err != nil {
+		t.Fatalf("return: %v", err)
+	}
+	if err := TrashPart(db, int(id)); err != nil {
+		t.Fatalf("trash: %v", err)
+	}
+
+	// L'historique des prêts d'une pièce à la corbeille n'est plus listé
+	loans, err := ListLoans(db, 0, false, true)
+	if err != nil {
+		t.Fatalf("list loans: %v", err)
+	}
+	if len(loans) != 0 {
+		t.Fatalf("expected loans of trashed part to be hidden, got %+v", loans)
+	}
+}
+
+func TestTrashRefusedWhileLoaned(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+
+	id, _ := CreatePart(db, "", "Perceuse Makita", "{}", nil, 1)
+	loan, err := CheckoutPart(db, int(id), "Alice", 1, "2999-01-01", "")
+	if err != nil {
+		t.Fatalf("checkout: %v", err)
+	}
+
+	if err := TrashPart(db, int(id)); !errors.Is(err, ErrPartLoaned) {
+		t.Fatalf("expected trash of a loaned part to be refused, got %v", err)
+	}
+	if err := TrashPart(db, 999); err == nil || errors.Is(err, ErrPartLoaned) {
+		t.Fatalf("expected not found for an unknown part, got %v", err)
+	}
+
+	if _, err := ReturnLoan(db, loan.ID); err != nil {
+		t.Fatalf("return: %v", err)
+	}
+	if err := TrashPart(db, int(id)); err != nil {
+		t.Fatalf("trash after return: %v", err)
+	}
+}
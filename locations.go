@@ -64,8 +64,14 @@ func CreateLocation(db *sql.DB, name string, parentID *int, locType string, desc
 		parentIDValue = sql.NullInt64{Int64: int64(*parentID), Valid: true}
 	}
 
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
 	// Insérer la localisation
-	result, err := db.Exec(`
+	result, err := tx.Exec(`
 		INSERT INTO locations (name, parent_id, loc_type, description)
 		VALUES (?, ?, ?, ?)
 	`, name, parentIDValue, locType, description)
@@ -75,6 +81,17 @@ func CreateLocation(db *sql.DB, name string, parentID *int, locType string, desc
 
 	id, _ := result.LastInsertId()
 
+	after, err := snapshotLocation(tx, int(id))
+	if err != nil {
+		return nil, err
+	}
+	if err := recordHistory(tx, HistoryEntityLocation, int(id), "create", nil, after); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
 	return &Location{
 		ID:          int(id),
 		Name:        name,
@@ -154,7 +171,7 @@ func GetFullPath(db *sql.DB, locationID int) (string, error) {
 			JOIN 
 				location_tree lt 
 			ON 
-				l.parent_id = lt.id 
+				l.id = lt.parent_id 
 				AND 
 				l.id != lt.id 
 				AND 
@@ -165,7 +182,7 @@ func GetFullPath(db *sql.DB, locationID int) (string, error) {
 			level 
 		FROM 
 			location_tree 
-		ORDER BY level DESC
+		ORDER BY level
 	`
 	rows, err := db.Query(query, locationID)
 	if err != nil {
@@ -314,8 +331,28 @@ func MoveLocation(db *sql.DB, locationID int, newParentID *int) error {
 		parentIDValue = nil
 	}
 
-	_, err = db.Exec("UPDATE locations SET parent_id = ? WHERE id = ?", parentIDValue, locationID)
-	return err
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := snapshotLocation(tx, locationID)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE locations SET parent_id = ? WHERE id = ?", parentIDValue, locationID); err != nil {
+		return err
+	}
+	after, err := snapshotLocation(tx, locationID)
+	if err != nil {
+		return err
+	}
+	if err := recordHistory(tx, HistoryEntityLocation, locationID, "move", before, after); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 // isDescendant vérifie si potentialDescendant est un descendant de ancestorID
@@ -357,16 +394,32 @@ func DeleteLocation(db *sql.DB, locationID int) error {
 		return fmt.Errorf("impossible de supprimer: %d sous-localisation(s) existent", childCount)
 	}
 
-	_, err := db.Exec("DELETE FROM locations WHERE id = ?", locationID)
-	return err
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := snapshotLocation(tx, locationID)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM locations WHERE id = ?", locationID); err != nil {
+		return err
+	}
+	if err := recordHistory(tx, HistoryEntityLocation, locationID, "delete", before, nil); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 // GetPartsCount retourne le nombre de pièces dans une localisation (incluant les sous-localisations)
 func GetPartsCount(db *sql.DB, locationID int) int {
 	var count int
 
-	// Compter les pièces directement dans cette localisation
-	db.QueryRow("SELECT COUNT(*) FROM parts WHERE location_id = ?", locationID).Scan(&count)
+	// Compter les pièces directement dans cette localisation (hors corbeille)
+	db.QueryRow("SELECT COUNT(*) FROM parts WHERE location_id = ? AND deleted_at IS NULL", locationID).Scan(&count)
 
 	// Ajouter les pièces des sous-localisations
 	children, _ := ListChildLocations(db, locationID)
@@ -427,11 +480,12 @@ func printLocationNode(db *sql.DB, loc Location, depth int) {
 	}
 }
 
-// SetPartLocation définit la localisation d'une pièce
-func SetPartLocation(db *sql.DB, partID int, locationID int) error {
+// SetPartLocation définit la localisation d'une pièce.
+// Avec withChildren, ses sous-composants (nomenclature) sont déplacés au même endroit.
+func SetPartLocation(db *sql.DB, partID int, locationID int, withChildren bool) error {
 	// Vérifier que la pièce existe
 	var count int
-	err := db.QueryRow("SELECT COUNT(*) FROM parts WHERE id = ?", partID).Scan(&count)
+	err := db.QueryRow("SELECT COUNT(*) FROM parts WHERE id = ? AND deleted_at IS NULL", partID).Scan(&count)
 	if err != nil {
 		return err
 	}
@@ -445,14 +499,49 @@ func SetPartLocation(db *sql.DB, partID int, locationID int) error {
 		return err
 	}
 
-	_, err = db.Exec("UPDATE parts SET location_id = ? WHERE id = ?", locationID, partID)
-	return err
+	partIDs := []int{partID}
+	if withChildren {
+		children, err := ListDescendantIDs(db, partID)
+		if err != nil {
+			return err
+		}
+		partIDs = append(partIDs, children...)
+	}
+
+	return setPartLocationValue(db, partIDs, locationID)
 }
 
 // ClearPartLocation supprime la localisation d'une pièce
 func ClearPartLocation(db *sql.DB, partID int) error {
-	_, err := db.Exec("UPDATE parts SET location_id = NULL WHERE id = ?", partID)
-	return err
+	return setPartLocationValue(db, []int{partID}, nil)
+}
+
+// setPartLocationValue met à jour location_id (nil = aucune) des pièces et l'enregistre dans l'historique
+func setPartLocationValue(db *sql.DB, partIDs []int, locationID interface{}) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, partID := range partIDs {
+		before, err := snapshotPart(tx, partID)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec("UPDATE parts SET location_id = ? WHERE id = ?", locationID, partID); err != nil {
+			return err
+		}
+		after, err := snapshotPart(tx, partID)
+		if err != nil {
+			return err
+		}
+		if err := recordHistory(tx, HistoryEntityPart, partID, "move", before, after); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
 }
 
 // GetLocationsMap retourne un map des localisations par ID pour affichage batch
@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func TestGetFullPathWalksAncestors(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+
+	atelier, err := CreateLocation(db, "Atelier", nil, "ZONE", "")
+	if err != nil {
+		t.Fatalf("create location: %v", err)
+	}
+	meuble, _ := CreateLocation(db, "Meuble", &atelier.ID, "FURNITURE", "")
+	boite, _ := CreateLocation(db, "Boite", &meuble.ID, "BOX", "")
+	CreateLocation(db, "Sous-boite", &boite.ID, "BOX", "")
+
+	path, err := GetFullPath(db, boite.ID)
+	if err != nil {
+		t.Fatalf("GetFullPath: %v", err)
+	}
+	if path != "Atelier > Meuble > Boite" {
+		t.Fatalf("expected path from the root down, got %q", path)
+	}
+}
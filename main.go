@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -14,24 +15,126 @@ Usage:
 
 Commandes:
   add        Ajouter une pièce au stock
+  alerts     Lister les pièces sous leur stock minimum (par localisation)
   attach     Attacher un fichier (PDF, photo) à une pièce
   label      Générer une étiquette PNG (QR code) pour une pièce
   serve      Lancer l'API HTTP (mode serveur)
   network    Gérer les pairs fédérés (peers)
+  edit       Modifier une pièce (nom, type, propriétés)
+  bom        Gérer les sous-ensembles (nomenclature parent/enfant)
+  dedupe     Détecter et fusionner les pièces en double
   dump       Créer une sauvegarde complète (JSON)
   files      Lister les fichiers attachés
+  harvest    Gérer les appareils donneurs et les pièces récupérées
+  history    Afficher l'historique des modifications (pièce ou localisation)
   import     Importer des pièces depuis un fichier CSV ou JSON
+  lint       Vérifier les pièces existantes contre leur template (champs inconnus, valeurs)
   list       Lister toutes les pièces
+  loan       Prêter une pièce ou un outil à un membre (out, return)
+  loans      Lister les prêts en cours (--overdue: en retard)
   loc        Gérer les localisations (arborescence atelier)
+  rename     Renommer les pièces selon le name_pattern de leur template
+  report     Rapports (value: valeur estimée du stock par type, localisation ou donneur)
   restore    Restaurer depuis une sauvegarde JSON
+  rm         Mettre une pièce à la corbeille
   search     Rechercher des pièces
-  templates  Afficher les types de pièces disponibles
+  state      Afficher ou changer l'état d'une pièce (non testée, fonctionnelle...)
+  stock      Gérer les quantités en stock (entrées, sorties, inventaire)
+  tag        Gérer les tags libres des pièces (add, rm, list)
+  templates  Gérer les types de pièces (list, show, add, edit, rm, migrate)
+  trash      Gérer la corbeille (list, restore, purge)
 
 Exemples:
   # Gestion des pièces
   recycle add --type=moteur --name="Moteur 12V" --props='{"volts":12, "watts":50}' --loc="Boite Moteurs"
   recycle search --type=roulement --prop="d_int:10..25"
+  recycle search --type=roulement --subtypes            # Inclut roulement_etanche (extends: roulement)
+  recycle search --type=bearing --prop="width:10..20"   # Alias: trouve aussi les roulements (largeur)
+  recycle add --type=moteur --name="Moteur essuie-glace" --props='{"volts":"12-24V","watts":50}'
+  recycle search --type=moteur --prop="volts:12"        # Trouve les plages 12-24V, 5V ±5%, 3.3/5V...
+  recycle search --type=roulement --units=imperial      # Affiche les cotes en pouces (API: ?units=imperial)
+  recycle search --type=roulement --unit=d_int:cm       # Unité par champ (API: ?unit=d_int:cm)
   recycle import --file=stock.csv --type=roulement
+  recycle edit --id=42 --props='{"d_int":"12mm"}'     # Fusionne avec les props existantes
+  recycle edit --id=42 --name="Roulement 6204-2Z"
+
+  # Noms automatiques (name_pattern du template, ex: "{marque} {reference} ({d_int}x{d_ext}x{largeur})")
+  recycle add --type=roulement --props='{"d_int":20,"d_ext":47,"largeur":14,"marque":"SKF"}'
+  recycle rename --type=roulement                       # Aperçu des nouveaux noms
+  recycle rename --type=roulement --apply               # Renommer les pièces existantes
+
+  # Templates (embarqués < dossier templates/ < base)
+  recycle templates add --file=capteur.yaml             # Nouveau type, enregistré en base
+  recycle templates show --name=moteur > moteur.yaml
+  recycle templates edit --file=moteur.yaml             # Surcharge en base du template fichier
+  recycle templates rm --name=moteur                    # Rétablit la version fichier/embarquée
+
+  # Contrôle des données (strict: true dans le template refuse les champs inconnus)
+  recycle lint                                          # Violations des pièces existantes
+  recycle lint --type=moteur --strict                   # Comme si le template était strict
+
+  # Versions des templates (version + migrations dans le YAML)
+  recycle templates migrate --dry-run                   # Pièces à migrer et modifications prévues
+  recycle templates migrate --type=moteur               # Appliquer (transaction unique)
+
+  # Cycle de vie (untested → working | broken | spare)
+  recycle state --id=42 --set=working                   # Pièce testée, fonctionnelle
+  recycle search --state=working --type=moteur
+
+  # Appareils donneurs (démontage)
+  recycle harvest new --category=imprimante --brand=HP --model="DeskJet 2130"
+  recycle add --donor=3 --type=moteur --name="Moteur 550 récup imprimante" --props='{"volts":12,"watts":25}'
+  recycle harvest link --part=42 --donor=3              # Rattacher une pièce existante
+  recycle harvest show --donor=3                        # Pièces récupérées sur l'appareil
+  recycle harvest stats                                 # Pièces récupérables par modèle
+
+  # Sous-ensembles (nomenclature)
+  recycle bom add --parent=42 --child=43                # Le motoréducteur 42 contient le moteur 43
+  recycle bom add --parent=50 --child=51 --qty=2        # Le module ventilateur contient 2 roulements
+  recycle bom --id=42                                   # Afficher l'arbre
+  recycle loc set --part=42 --loc="Boite Moteurs" --with-children
+
+  # Tags libres
+  recycle tag add --id=42 to-sort high-value
+  recycle tag rm --id=42 to-sort
+  recycle tag                                           # Lister les tags
+  recycle search --tag=donated-by-fablab --type=moteur  # Combinable avec les autres filtres
+
+  # Doublons (même type, mêmes props normalisées, noms proches)
+  recycle dedupe --dry-run                              # Lister les doublons probables
+  recycle dedupe --type=roulement                       # Fusion interactive
+  recycle dedupe --auto --threshold=0.8                 # Fusion automatique dans l'ID le plus ancien
+
+  # Valorisation du stock (dossiers de subvention)
+  recycle add --type=moteur --name="Moteur 550" --props='{"volts":12,"watts":25}' --value=8.50
+  recycle edit --id=42 --value=15 --currency=CHF
+  recycle report value --by=location                    # Totaux par sous-arbre de localisation
+  recycle report value --by=donor --format=csv > valeur.csv
+  recycle report value --by=type --format=json
+
+  # Prêts (sortie/retour)
+  recycle loan out --id=42 --to="Alice" --due=2026-11-30 --notes="Projet robot"
+  recycle loan out --id=43 --to="Bob" --due=7d --qty=2  # Échéance dans 7 jours
+  recycle loan return --id=42                           # Ou --loan=<ID du prêt>
+  recycle loans --overdue                               # Prêts en retard
+
+  # Corbeille
+  recycle rm --id=42                                    # Mettre à la corbeille
+  recycle trash                                         # Lister la corbeille
+  recycle trash restore --id=42                         # Restaurer
+  recycle trash purge --id=42                           # Supprimer définitivement (+ fichiers)
+  recycle trash purge --all --force                     # Vider la corbeille
+
+  # Quantités en stock
+  recycle add --type=vis --name="Vis M4x12" --props='{"diametre":"4","longueur":12}' --qty=40
+  recycle stock --id=42 --out=2 --reason="Projet vélo"  # Sortie de stock
+  recycle stock --id=42 --in=10                         # Entrée en stock
+  recycle stock --id=42 --set=36 --reason="Inventaire"  # Ajustement
+  recycle stock --id=42                                 # Registre des mouvements
+  recycle stock --id=42 --min=10                        # Seuil d'alerte (sinon min_stock du template)
+  recycle alerts                                        # Pièces à réapprovisionner
+  recycle serve --alerts-every=1h --notify=webhook --notify-url=http://localhost:9000/hook
+  recycle serve --alerts-every=24h --notify=smtp --smtp-from=stock@fablab.local --smtp-to=bureau@fablab.local
 
   # Gestion des localisations
   recycle loc                                           # Afficher l'arborescence
@@ -41,6 +144,10 @@ Exemples:
   recycle loc move "Boite Roulements" --to="Armoire A"  # Déplacer
   recycle loc set --part=42 --loc="Boite Roulements"    # Localiser une pièce
 
+  # Historique (auteur: variable RECYCLE_USER, sinon USER)
+  recycle history --part=42
+  recycle history --loc=7
+
   # Étiquettes & QR
   recycle label --id=42 --format=png > stick.png
 
@@ -56,11 +163,6 @@ Exemples:
 }
 
 func main() {
-	// Charger les templates
-	if err := LoadTemplates(); err != nil {
-		log.Printf("Warning: impossible de charger les templates: %v", err)
-	}
-
 	if len(os.Args) < 2 {
 		printUsage()
 		os.Exit(1)
@@ -72,6 +174,15 @@ func main() {
 	}
 	defer db.Close()
 
+	// Charger les templates (embarqués, dossier templates, puis base)
+	// Un template en base invalide est écarté; toute autre erreur laisserait le registre vide
+	if err := LoadTemplates(db); err != nil {
+		if !errors.Is(err, ErrTemplatesSkipped) {
+			log.Fatalf("Erreur templates: %v", err)
+		}
+		log.Printf("Warning: %v", err)
+	}
+
 	cmd := os.Args[1]
 
 	switch cmd {
@@ -79,6 +190,14 @@ func main() {
 		if err := cmdAdd(db, os.Args[2:]); err != nil {
 			log.Fatalf("Erreur add: %v", err)
 		}
+	case "edit":
+		if err := cmdEdit(db, os.Args[2:]); err != nil {
+			log.Fatalf("Erreur edit: %v", err)
+		}
+	case "alerts":
+		if err := cmdAlerts(db, os.Args[2:]); err != nil {
+			log.Fatalf("Erreur alerts: %v", err)
+		}
 	case "attach":
 		if err := cmdAttach(db, os.Args[2:]); err != nil {
 			log.Fatalf("Erreur attach: %v", err)
@@ -103,22 +222,58 @@ func main() {
 		if err := cmdNetwork(db, os.Args[2:]); err != nil {
 			log.Fatalf("Erreur network: %v", err)
 		}
+	case "bom":
+		if err := cmdBom(db, os.Args[2:]); err != nil {
+			log.Fatalf("Erreur bom: %v", err)
+		}
+	case "dedupe":
+		if err := cmdDedupe(db, os.Args[2:]); err != nil {
+			log.Fatalf("Erreur dedupe: %v", err)
+		}
 	case "dump":
 		if err := cmdDump(db, os.Args[2:]); err != nil {
 			log.Fatalf("Erreur dump: %v", err)
 		}
+	case "rename":
+		if err := cmdRename(db, os.Args[2:]); err != nil {
+			log.Fatalf("Erreur rename: %v", err)
+		}
+	case "report":
+		if err := cmdReport(db, os.Args[2:]); err != nil {
+			log.Fatalf("Erreur report: %v", err)
+		}
 	case "restore":
 		if err := cmdRestore(db, os.Args[2:]); err != nil {
 			log.Fatalf("Erreur restore: %v", err)
 		}
+	case "harvest":
+		if err := cmdHarvest(db, os.Args[2:]); err != nil {
+			log.Fatalf("Erreur harvest: %v", err)
+		}
+	case "history":
+		if err := cmdHistory(db, os.Args[2:]); err != nil {
+			log.Fatalf("Erreur history: %v", err)
+		}
 	case "import":
 		if err := cmdImport(db, os.Args[2:]); err != nil {
 			log.Fatalf("Erreur import: %v", err)
 		}
+	case "lint":
+		if err := cmdLint(db, os.Args[2:]); err != nil {
+			log.Fatalf("Erreur lint: %v", err)
+		}
 	case "list":
 		if err := cmdList(db); err != nil {
 			log.Fatalf("Erreur list: %v", err)
 		}
+	case "loan":
+		if err := cmdLoan(db, os.Args[2:]); err != nil {
+			log.Fatalf("Erreur loan: %v", err)
+		}
+	case "loans":
+		if err := cmdLoans(db, os.Args[2:]); err != nil {
+			log.Fatalf("Erreur loans: %v", err)
+		}
 	case "loc", "location", "locations":
 		if err := cmdLoc(db, os.Args[2:]); err != nil {
 			log.Fatalf("Erreur loc: %v", err)
@@ -127,8 +282,28 @@ func main() {
 		if err := cmdSearch(db, os.Args[2:]); err != nil {
 			log.Fatalf("Erreur search: %v", err)
 		}
+	case "rm":
+		if err := cmdRm(db, os.Args[2:]); err != nil {
+			log.Fatalf("Erreur rm: %v", err)
+		}
+	case "state":
+		if err := cmdState(db, os.Args[2:]); err != nil {
+			log.Fatalf("Erreur state: %v", err)
+		}
+	case "trash":
+		if err := cmdTrash(db, os.Args[2:]); err != nil {
+			log.Fatalf("Erreur trash: %v", err)
+		}
+	case "stock":
+		if err := cmdStock(db, os.Args[2:]); err != nil {
+			log.Fatalf("Erreur stock: %v", err)
+		}
+	case "tag", "tags":
+		if err := cmdTag(db, os.Args[2:]); err != nil {
+			log.Fatalf("Erreur tag: %v", err)
+		}
 	case "templates":
-		if err := cmdTemplates(); err != nil {
+		if err := cmdTemplates(db, os.Args[2:]); err != nil {
 			log.Fatalf("Erreur templates: %v", err)
 		}
 	case "help", "-h", "--help":
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Nommage automatique des pièces (clé "name_pattern" d'un template), ex:
+//
+//	name_pattern: "{marque} {reference} ({d_int}x{d_ext}x{largeur})"
+//
+// Un champ absent laisse un vide; un groupe entre parenthèses ou crochets disparaît
+// entièrement si l'un de ses champs est absent ("Roulement 6204" plutôt que "Roulement 6204 (20x47x)").
+
+var (
+	namePlaceholderRegex = regexp.MustCompile(`\{([^{}]*)\}`)
+	nameGroupRegex       = regexp.MustCompile(`\([^()]*\)|\[[^\[\]]*\]`)
+)
+
+// RenamedPart décrit une pièce renommée (ou à renommer en simulation)
+type RenamedPart struct {
+	ID      int
+	Type    string
+	OldName string
+	NewName string
+}
+
+// checkNamePattern vérifie que le name_pattern d'un template n'utilise que des champs connus
+func checkNamePattern(t *Template) error {
+	if t.NamePattern == "" {
+		return nil
+	}
+	if strings.Count(t.NamePattern, "{") != strings.Count(t.NamePattern, "}") {
+		return fmt.Errorf("name_pattern: accolades non appariées")
+	}
+	matches := namePlaceholderRegex.FindAllStringSubmatch(t.NamePattern, -1)
+	if len(matches) == 0 {
+		return fmt.Errorf("name_pattern: aucun champ ({champ}) utilisé")
+	}
+	for _, m := range matches {
+		field := strings.TrimSpace(m[1])
+		if _, ok := t.Fields[field]; !ok {
+			return fmt.Errorf("name_pattern: champ inconnu '%s'", field)
+		}
+	}
+	return nil
+}
+
+// GeneratePartName construit le nom d'une pièce à partir du name_pattern de son template
+// et de ses props (normalisées). Retourne "" si le type n'a pas de pattern ou si aucun champ
+// du pattern n'est renseigné.
+func GeneratePartName(typeName string, props map[string]interface{}) string {
+	tmpl, ok := Templates.Get(typeName)
+	if !ok || tmpl.NamePattern == "" {
+		return ""
+	}
+
+	filled := 0
+	substitute := func(text string) (string, bool) {
+		complete := true
+		out := namePlaceholderRegex.ReplaceAllStringFunc(text, func(placeholder string) string {
+			value := formatNameValue(props[strings.TrimSpace(placeholder[1:len(placeholder)-1])])
+			if value == "" {
+				complete = false
+			} else {
+				filled++
+			}
+			return value
+		})
+		return out, complete
+	}
+
+	name := nameGroupRegex.ReplaceAllStringFunc(tmpl.NamePattern, func(group string) string {
+		if !namePlaceholderRegex.MatchString(group) {
+			return group
+		}
+		out, complete := substitute(group)
+		if !complete {
+			return ""
+		}
+		return out
+	})
+	name, _ = substitute(name)
+
+	if filled == 0 {
+		return ""
+	}
+	return strings.Trim(strings.Join(strings.Fields(name), " "), " -,/")
+}
+
+// formatNameValue formate une valeur de prop pour un nom ("" si absente)
+func formatNameValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		if v {
+			return "oui"
+		}
+		return "non"
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			if s := formatNameValue(item); s != "" {
+				items = append(items, s)
+			}
+		}
+		return strings.Join(items, "/")
+	}
+	if rv, ok := AsRangeValue(value); ok {
+		return rv.String()
+	}
+	return strings.TrimSpace(fmt.Sprint(value))
+}
+
+// RenameParts régénère le nom des pièces (hors corbeille) d'un type et de ses sous-types
+// selon leur name_pattern (typeName vide: tous les types). Seules les pièces dont le nom change
+// sont retournées; en simulation (apply false), rien n'est écrit.
+func RenameParts(db *sql.DB, typeName string, apply bool) ([]RenamedPart, error) {
+	var types []string
+	if typeName != "" {
+		tmpl, ok := Templates.Get(typeName)
+		if !ok {
+			return nil, fmt.Errorf("type inconnu: %s", typeName)
+		}
+		if tmpl.NamePattern == "" {
+			return nil, fmt.Errorf("le template %s n'a pas de name_pattern", typeName)
+		}
+		types = Subtypes(typeName)
+	} else {
+		types = Templates.Names()
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var renamed []RenamedPart
+	for _, name := range types {
+		rows, err := tx.Query(`
+			SELECT id, name, props
+			FROM parts
+			WHERE type = ? AND deleted_at IS NULL
+			ORDER BY id
+		`, name)
+		if err != nil {
+			return nil, err
+		}
+
+		type pending struct {
+			part  RenamedPart
+			props string
+		}
+		var parts []pending
+		for rows.Next() {
+			var p pending
+			var props sql.NullString
+			if err := rows.Scan(&p.part.ID, &p.part.OldName, &props); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			p.part.Type = name
+			p.props = props.String
+			parts = append(parts, p)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+
+		for _, p := range parts {
+			props := make(map[string]interface{})
+			if p.props != "" {
+				if err := json.Unmarshal([]byte(p.props), &props); err != nil {
+					return nil, fmt.Errorf("pièce %d: props invalides: %v", p.part.ID, err)
+				}
+			}
+			p.part.NewName = GeneratePartName(name, props)
+			if p.part.NewName == "" || p.part.NewName == p.part.OldName {
+				continue
+			}
+			renamed = append(renamed, p.part)
+
+			if !apply {
+				continue
+			}
+			if err := renamePart(tx, p.part.ID, p.part.NewName); err != nil {
+				return nil, fmt.Errorf("pièce %d: %v", p.part.ID, err)
+			}
+		}
+	}
+
+	if !apply {
+		return renamed, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return renamed, nil
+}
+
+// renamePart change le nom d'une pièce, avec historique
+func renamePart(tx *sql.Tx, partID int, name string) error {
+	before, err := snapshotPart(tx, partID)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE parts SET name = ? WHERE id = ?", name, partID); err != nil {
+		return err
+	}
+	after, err := snapshotPart(tx, partID)
+	if err != nil {
+		return err
+	}
+	return recordHistory(tx, HistoryEntityPart, partID, "rename", before, after)
+}
+
+// PrintRenameReport affiche les pièces renommées (ou à renommer en simulation)
+func PrintRenameReport(parts []RenamedPart, apply bool) {
+	title := "🏷  Renommage des pièces"
+	if !apply {
+		title += " (simulation, relancer avec --apply pour écrire)"
+	}
+	fmt.Println("\n" + title + ":")
+	fmt.Println(strings.Repeat("─", 60))
+
+	if len(parts) == 0 {
+		fmt.Println("  Tous les noms sont à jour")
+		fmt.Println()
+		return
+	}
+
+	for _, p := range parts {
+		fmt.Printf("  [%d] %s: %s → %s\n", p.ID, p.Type, p.OldName, p.NewName)
+	}
+
+	verb := "renommée(s)"
+	if !apply {
+		verb = "à renommer"
+	}
+	fmt.Printf("\n%d pièce(s) %s\n\n", len(parts), verb)
+}
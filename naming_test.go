@@ -0,0 +1,153 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// seedNamedTemplates ajoute un template roulement avec name_pattern (et un sous-type qui en hérite)
+func seedNamedTemplates(t *testing.T) {
+	t.Helper()
+	raw := map[string]*Template{
+		"roulement": {Name: "roulement", NamePattern: "{marque} {reference} ({d_int}x{d_ext}x{largeur})", Fields: map[string]FieldDef{
+			"d_int":     {Required: true, Domain: "dimension", DefaultUnit: "mm"},
+			"d_ext":     {Required: true, Domain: "dimension", DefaultUnit: "mm"},
+			"largeur":   {Domain: "dimension", DefaultUnit: "mm"},
+			"marque":    {},
+			"reference": {Compute: "bearing_ref(d_int, d_ext, largeur)", Override: true},
+		}},
+		"roulement_etanche": {Name: "roulement_etanche", Extends: "roulement"},
+	}
+	setTestTemplates(t, raw)
+}
+
+func TestGeneratePartName(t *testing.T) {
+	seedNamedTemplates(t)
+	cases := []struct {
+		props map[string]interface{}
+		want  string
+	}{
+		{map[string]interface{}{"marque": "SKF", "reference": "6204", "d_int": float64(20), "d_ext": float64(47), "largeur": float64(14)}, "SKF 6204 (20x47x14)"},
+		{map[string]interface{}{"reference": "6204", "d_int": float64(20), "d_ext": float64(47), "largeur": float64(14)}, "6204 (20x47x14)"},
+		{map[string]interface{}{"marque": "FAG", "d_int": 8.5, "d_ext": float64(22)}, "FAG"},
+		{map[string]interface{}{}, ""},
+	}
+	for _, c := range cases {
+		if got := GeneratePartName("roulement", c.props); got != c.want {
+			t.Fatalf("%v: expected %q, got %q", c.props, c.want, got)
+		}
+	}
+
+	if got := GeneratePartName("roulement_etanche", map[string]interface{}{"marque": "NSK"}); got != "NSK" {
+		t.Fatalf("expected name_pattern inherited, got %q", got)
+	}
+}
+
+func TestResolveTemplatesRejectsInvalidNamePattern(t *testing.T) {
+	for _, pattern := range []string{"{inconnu}", "{d_int", "Roulement"} {
+		raw := map[string]*Template{"t": {Name: "t", NamePattern: pattern, Fields: map[string]FieldDef{"d_int": {}}}}
+		if _, err := ResolveTemplates(raw); err == nil {
+			t.Fatalf("%q: expected error", pattern)
+		}
+	}
+}
+
+func TestCmdAddGeneratesName(t *testing.T) {
+	seedNamedTemplates(t)
+	db := newTestDB(t)
+	defer db.Close()
+
+	if err := cmdAdd(db, []string{"--type=roulement", `--props={"d_int":20,"d_ext":47,"largeur":14,"marque":"SKF"}`}); err != nil {
+		t.Fatalf("cmdAdd: %v", err)
+	}
+	var name string
+	if err := db.QueryRow("SELECT name FROM parts LIMIT 1").Scan(&name); err != nil {
+		t.Fatalf("fetch part: %v", err)
+	}
+	if name != "SKF 6204 (20x47x14)" {
+		t.Fatalf("unexpected generated name: %q", name)
+	}
+
+	seedTemplates()
+	if err := cmdAdd(db, []string{"--type=bearing", `--props={"d_int":20,"d_ext":47,"width":14}`}); err == nil {
+		t.Fatalf("expected error without name nor name_pattern")
+	}
+}
+
+func TestImportCSVWithoutNameColumn(t *testing.T) {
+	seedNamedTemplates(t)
+	db := newTestDB(t)
+	defer db.Close()
+
+	path := filepath.Join(t.TempDir(), "roulements.csv")
+	if err := os.WriteFile(path, []byte("d_int,d_ext,largeur,marque\n8,22,7,SKF\n10,26,8,\n"), 0644); err != nil {
+		t.Fatalf("write csv: %v", err)
+	}
+	stats, err := ImportFromFile(db, ImportOptions{FilePath: path, TypeName: "roulement"})
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if stats.Imported != 2 {
+		t.Fatalf("expected 2 imported parts, got %+v", stats)
+	}
+
+	var names []string
+	rows, err := db.Query("SELECT name FROM parts ORDER BY id")
+	if err != nil {
+		t.Fatalf("list parts: %v", err)
+	}
+	for rows.Next() {
+		var name string
+		rows.Scan(&name)
+		names = append(names, name)
+	}
+	rows.Close()
+	if strings.Join(names, "|") != "SKF 608 (8x22x7)|6000 (10x26x8)" {
+		t.Fatalf("unexpected names: %v", names)
+	}
+}
+
+func TestRenamePartsPreviewThenApply(t *testing.T) {
+	seedNamedTemplates(t)
+	db := newTestDB(t)
+	defer db.Close()
+
+	id, err := CreatePart(db, "roulement_etanche", "6204 skf", `{"d_int":20,"d_ext":47,"largeur":14,"marque":"SKF","reference":"6204-2RS"}`, nil, 1)
+	if err != nil {
+		t.Fatalf("create part: %v", err)
+	}
+
+	parts, err := RenameParts(db, "roulement", false)
+	if err != nil {
+		t.Fatalf("preview: %v", err)
+	}
+	if len(parts) != 1 || parts[0].NewName != "SKF 6204-2RS (20x47x14)" {
+		t.Fatalf("unexpected preview: %+v", parts)
+	}
+	if meta, _ := GetPartMeta(db, int(id)); meta.Name != "6204 skf" {
+		t.Fatalf("preview should not write, got %q", meta.Name)
+	}
+
+	if _, err := RenameParts(db, "roulement", true); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if meta, _ := GetPartMeta(db, int(id)); meta.Name != "SKF 6204-2RS (20x47x14)" {
+		t.Fatalf("expected part renamed, got %q", meta.Name)
+	}
+	entries, err := ListHistory(db, HistoryEntityPart, int(id))
+	if err != nil {
+		t.Fatalf("list history: %v", err)
+	}
+	if entries[0].Action != "rename" {
+		t.Fatalf("expected rename history entry, got %s", entries[0].Action)
+	}
+
+	if parts, _ := RenameParts(db, "", false); len(parts) != 0 {
+		t.Fatalf("expected names up to date, got %+v", parts)
+	}
+	if _, err := RenameParts(db, "inconnu", false); err == nil {
+		t.Fatalf("expected unknown type error")
+	}
+}
@@ -2,7 +2,9 @@ package main
 
 import (
 	"fmt"
+	"math"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -19,6 +21,7 @@ const (
 	DomainPression    UnitDomain = "pression"    // Pression -> bar
 	DomainVitesseRot  UnitDomain = "vitesse_rot" // Vitesse de rotation -> rpm
 	DomainPuissance   UnitDomain = "puissance"   // Puissance -> W
+	DomainFrequence   UnitDomain = "frequence"   // Fréquence -> Hz
 	DomainNone        UnitDomain = ""            // Pas de domaine (texte libre)
 )
 
@@ -38,6 +41,7 @@ var BaseUnits = map[UnitDomain]string{
 	DomainPression:    "bar",
 	DomainVitesseRot:  "rpm",
 	DomainPuissance:   "W",
+	DomainFrequence:   "Hz",
 }
 
 // UnitConversions mappe les alias d'unités vers leurs informations de conversion
@@ -104,6 +108,64 @@ var UnitConversions = map[string]UnitInfo{
 	"watt": {DomainPuissance, 1},
 	"mW":   {DomainPuissance, 0.001},
 	"kW":   {DomainPuissance, 1000},
+
+	// Fréquence (base: Hz)
+	"Hz": {DomainFrequence, 1},
+	"hz": {DomainFrequence, 1},
+}
+
+// prefixableUnits sont les symboles SI qui acceptent un préfixe (MΩ, µA, GHz, nm...)
+// en plus des alias de UnitConversions
+var prefixableUnits = []string{"m", "V", "A", "Ohm", "ohm", "Ω", "F", "Pa", "W", "Hz"}
+
+// barePrefixUnits donne le sens d'un préfixe employé seul sans domaine attendu, selon l'usage
+// en électronique: "4k7", "1M" sont des résistances, "100n", "4u7" des capacités
+var barePrefixUnits = map[string]string{
+	"p": "pF",
+	"n": "nF",
+	"u": "uF",
+	"µ": "µF",
+	"K": "kOhm",
+	"M": "MOhm",
+	"G": "GOhm",
+}
+
+// LookupUnit retrouve une unité: alias de UnitConversions, sinon symbole SI préfixé
+// ("MΩ", "µA", "GHz", "nm"), sinon préfixe seul (voir barePrefixUnits)
+func LookupUnit(unit string) (UnitInfo, bool) {
+	if info, ok := UnitConversions[unit]; ok {
+		return info, true
+	}
+	if full, ok := barePrefixUnits[unit]; ok {
+		return LookupUnit(full)
+	}
+	for prefix, factor := range siPrefixes {
+		if !strings.HasPrefix(unit, prefix) {
+			continue
+		}
+		symbol := strings.TrimPrefix(unit, prefix)
+		for _, candidate := range prefixableUnits {
+			if symbol == candidate {
+				info := UnitConversions[symbol]
+				return UnitInfo{Domain: info.Domain, ToBaseFactor: factor * info.ToBaseFactor}, true
+			}
+		}
+	}
+	return UnitInfo{}, false
+}
+
+// roundSignificant supprime les artefacts flottants des conversions (4.7 * 1000 = 4700.000000000001)
+func roundSignificant(v float64) float64 {
+	return roundDigits(v, 12)
+}
+
+// roundDigits arrondit v à digits chiffres significatifs
+func roundDigits(v float64, digits int) float64 {
+	rounded, err := strconv.ParseFloat(strconv.FormatFloat(v, 'g', digits, 64), 64)
+	if err != nil {
+		return v
+	}
+	return rounded
 }
 
 // ParsedValue représente une valeur parsée avec son unité
@@ -116,7 +178,70 @@ type ParsedValue struct {
 // parseValueRegex extrait un nombre et son unité optionnelle
 var parseValueRegex = regexp.MustCompile(`^([-+]?\d*\.?\d+)\s*([a-zA-ZΩµ"/]+)?$`)
 
-// ParseValueWithUnit parse une chaîne comme "10mm" ou "12.5 cm" ou "10"
+// engineeringRegex reconnaît la notation des composants où le préfixe remplace la virgule:
+// "4k7" (4.7k), "2R2" (2.2 Ohm), "4u7F" (4.7uF), et "R47" (0.47 Ohm) sans partie entière
+// (réservé à R: "M3" reste une désignation de filetage)
+var engineeringRegex = regexp.MustCompile(`^(?:(\d+)([pnuµmkKMGR])|()(R))(\d+)([a-zA-ZΩ]*)$`)
+
+// capacitorCodeRegex reconnaît le code à 3 chiffres des condensateurs (2 chiffres significatifs,
+// puis le multiplicateur en pF), suivi éventuellement d'une lettre de tolérance: "104", "473J"
+var capacitorCodeRegex = regexp.MustCompile(`^(\d{2})([0-6])([JKMZ]?)$`)
+
+// parseCapacitorCode décode un code condensateur ("473J" = 47nF). Hors contexte (domaine capacité
+// non connu), seules les tolérances J et Z sont reconnues: "104" reste un nombre et "473K" 473kΩ.
+// Dans le domaine capacité, "104" et "473K" sont aussi des codes (sauf multiplicateur 0: "100" = 100uF).
+func parseCapacitorCode(input string, inDomain bool) (*ParsedValue, bool) {
+	matches := capacitorCodeRegex.FindStringSubmatch(input)
+	if matches == nil {
+		return nil, false
+	}
+	switch matches[3] {
+	case "J", "Z":
+	case "K", "M":
+		if !inDomain {
+			return nil, false
+		}
+	default:
+		if !inDomain || matches[2] == "0" {
+			return nil, false
+		}
+	}
+
+	significant, _ := strconv.Atoi(matches[1])
+	exponent, _ := strconv.Atoi(matches[2])
+	value := float64(significant)
+	for i := 0; i < exponent; i++ {
+		value *= 10
+	}
+	return &ParsedValue{Value: value, Unit: "pF", HasUnit: true}, true
+}
+
+// capacitorCodeNumber traite un nombre entier saisi pour une capacité comme sa saisie en texte:
+// 104 et "104" valent tous deux 100nF (un nombre qui n'est pas un code est retourné tel quel)
+func capacitorCodeNumber(value interface{}) interface{} {
+	var code string
+	switch v := value.(type) {
+	case float64:
+		if v != math.Trunc(v) {
+			return value
+		}
+		code = strconv.FormatFloat(v, 'f', 0, 64)
+	case int:
+		code = strconv.Itoa(v)
+	default:
+		return value
+	}
+	if _, ok := parseCapacitorCode(code, true); !ok {
+		return value
+	}
+	return code
+}
+
+// inchFractionRegex reconnaît les fractions de pouce: "1/4 inch", "1 1/2in", `3/8"`
+var inchFractionRegex = regexp.MustCompile(`^(?:(\d+)\s+)?(\d+)/(\d+)\s*(in|inch|pouce|")$`)
+
+// ParseValueWithUnit parse une chaîne comme "10mm" ou "12.5 cm" ou "10", ainsi que les notations
+// de l'électronique ("4k7", "2R2", "100n", "473J") et les fractions de pouce ("1/4 inch")
 func ParseValueWithUnit(input string) (*ParsedValue, error) {
 	input = strings.TrimSpace(input)
 	
@@ -124,6 +249,34 @@ func ParseValueWithUnit(input string) (*ParsedValue, error) {
 		return nil, fmt.Errorf("valeur vide")
 	}
 
+	if matches := engineeringRegex.FindStringSubmatch(input); matches != nil {
+		whole, prefix := matches[1]+matches[3], matches[2]+matches[4]
+		value, err := strconv.ParseFloat("0"+whole+"."+matches[5], 64)
+		if err != nil {
+			return nil, fmt.Errorf("nombre invalide: '%s'", input)
+		}
+		unit := prefix + matches[6]
+		if prefix == "R" {
+			unit = "Ohm"
+			if matches[6] != "" {
+				unit = matches[6]
+			}
+		}
+		return &ParsedValue{Value: value, Unit: unit, HasUnit: true}, nil
+	}
+	if parsed, ok := parseCapacitorCode(input, false); ok {
+		return parsed, nil
+	}
+	if matches := inchFractionRegex.FindStringSubmatch(input); matches != nil {
+		whole, _ := strconv.ParseFloat("0"+matches[1], 64)
+		num, _ := strconv.ParseFloat(matches[2], 64)
+		den, _ := strconv.ParseFloat(matches[3], 64)
+		if den == 0 {
+			return nil, fmt.Errorf("fraction invalide: '%s'", input)
+		}
+		return &ParsedValue{Value: whole + num/den, Unit: matches[4], HasUnit: true}, nil
+	}
+
 	matches := parseValueRegex.FindStringSubmatch(input)
 	if matches == nil {
 		return nil, fmt.Errorf("format invalide: '%s' (attendu: nombre[unité])", input)
@@ -172,8 +325,8 @@ func NormalizeValue(input string, defaultUnit string) (*NormalizeResult, error)
 		unitToUse = defaultUnit
 	}
 
-	// Chercher l'unité dans les conversions
-	info, exists := UnitConversions[unitToUse]
+	// Chercher l'unité dans les conversions (alias, puis symbole SI préfixé)
+	info, exists := LookupUnit(unitToUse)
 	if !exists {
 		// Suggérer les unités valides pour ce type d'entrée
 		suggestions := getSuggestionsForUnit(unitToUse)
@@ -184,7 +337,7 @@ func NormalizeValue(input string, defaultUnit string) (*NormalizeResult, error)
 	}
 
 	// Convertir vers l'unité de base
-	normalizedValue := parsed.Value * info.ToBaseFactor
+	normalizedValue := roundSignificant(parsed.Value * info.ToBaseFactor)
 
 	return &NormalizeResult{
 		Value:    normalizedValue,
@@ -193,6 +346,93 @@ func NormalizeValue(input string, defaultUnit string) (*NormalizeResult, error)
 	}, nil
 }
 
+// siPrefixes sont les préfixes employés seuls comme unité ("4.7k", "500m", "100n"):
+// ils s'appliquent à l'unité SI du domaine attendu du champ
+var siPrefixes = map[string]float64{
+	"p": 1e-12,
+	"n": 1e-9,
+	"u": 1e-6,
+	"µ": 1e-6,
+	"m": 1e-3,
+	"k": 1e3,
+	"K": 1e3,
+	"M": 1e6,
+	"G": 1e9,
+}
+
+// domainSIUnits donne l'unité SI à laquelle s'appliquent les préfixes, par domaine
+var domainSIUnits = map[UnitDomain]string{
+	DomainDimension:  "m",
+	DomainTension:    "V",
+	DomainCourant:    "A",
+	DomainResistance: "Ohm",
+	DomainCapacite:   "F",
+	DomainPression:   "Pa",
+	DomainPuissance:  "W",
+	DomainFrequence:  "Hz",
+}
+
+// resolveUnitInDomain retrouve une unité dans le contexte d'un domaine: unité du domaine,
+// sinon préfixe seul appliqué à l'unité SI du domaine ("k" = kV pour une tension, kΩ pour
+// une résistance; "m" = mètre pour une dimension, mA pour un courant).
+// Une unité d'un autre domaine est refusée.
+func resolveUnitInDomain(unit string, domain UnitDomain) (UnitInfo, error) {
+	if info, ok := UnitConversions[unit]; ok && info.Domain == domain {
+		return info, nil
+	}
+	if factor, ok := siPrefixes[unit]; ok {
+		if symbol, ok := domainSIUnits[domain]; ok {
+			return UnitInfo{Domain: domain, ToBaseFactor: factor * UnitConversions[symbol].ToBaseFactor}, nil
+		}
+	}
+	info, known := LookupUnit(unit)
+	if known && info.Domain == domain {
+		return info, nil
+	}
+
+	accepted := GetAcceptedUnitsForDomain(domain)
+	sort.Strings(accepted)
+	if known {
+		return UnitInfo{}, fmt.Errorf("unité '%s' (%s) incompatible avec le domaine %s (unités: %s)",
+			unit, info.Domain, domain, strings.Join(accepted, ", "))
+	}
+	return UnitInfo{}, fmt.Errorf("unité '%s' non reconnue pour le domaine %s (unités: %s)",
+		unit, domain, strings.Join(accepted, ", "))
+}
+
+// NormalizeValueInDomain convertit une valeur vers l'unité de base du domaine attendu d'un champ
+// (DomainNone: comme NormalizeValue). Sans unité, defaultUnit s'applique, sinon l'unité de base.
+func NormalizeValueInDomain(input string, defaultUnit string, domain UnitDomain) (*NormalizeResult, error) {
+	if domain == DomainNone {
+		return NormalizeValue(input, defaultUnit)
+	}
+
+	parsed, ok := parseCapacitorCode(strings.TrimSpace(input), domain == DomainCapacite)
+	if !ok {
+		var err error
+		if parsed, err = ParseValueWithUnit(input); err != nil {
+			return nil, err
+		}
+	}
+	unit := parsed.Unit
+	if !parsed.HasUnit {
+		unit = defaultUnit
+		if unit == "" {
+			unit = BaseUnits[domain]
+		}
+	}
+
+	info, err := resolveUnitInDomain(unit, domain)
+	if err != nil {
+		return nil, err
+	}
+	return &NormalizeResult{
+		Value:    roundSignificant(parsed.Value * info.ToBaseFactor),
+		Domain:   domain,
+		BaseUnit: BaseUnits[domain],
+	}, nil
+}
+
 // getSuggestionsForUnit retourne des suggestions basées sur le domaine probable
 func getSuggestionsForUnit(unit string) string {
 	lower := strings.ToLower(unit)
@@ -207,9 +447,13 @@ func getSuggestionsForUnit(unit string) string {
 		"pression":    {"bar", "psi", "Pa", "kPa"},
 		"vitesse_rot": {"rpm", "tr/min", "tpm"},
 		"puissance":   {"W", "kW", "mW", "watt"},
+		"frequence":   {"Hz", "kHz", "MHz", "GHz"},
 	}
 
 	// Patterns pour deviner le domaine
+	if strings.Contains(lower, "hz") {
+		return "Unités de fréquence valides: " + strings.Join(suggestions["frequence"], ", ")
+	}
 	if strings.Contains(lower, "m") || strings.Contains(lower, "inch") || strings.Contains(lower, "pouce") {
 		return "Unités de dimension valides: " + strings.Join(suggestions["dimension"], ", ")
 	}
@@ -311,6 +555,14 @@ func GetDefaultUnitForField(fieldName string) string {
 		}
 	}
 
+	// Champs de fréquence
+	frequenceFields := []string{"frequence", "frequency", "freq"}
+	for _, f := range frequenceFields {
+		if lower == f || strings.Contains(lower, f) {
+			return "Hz"
+		}
+	}
+
 	return "" // Pas d'unité par défaut
 }
 
@@ -336,9 +588,26 @@ func isTextOnlyField(fieldName string) bool {
 }
 
 // NormalizeProps normalise toutes les propriétés numériques d'un map
-// Les valeurs peuvent être des nombres, des chaînes avec unités, ou du texte libre
-func NormalizeProps(props map[string]interface{}, fieldUnits map[string]string) (map[string]interface{}, error) {
+// Les valeurs peuvent être des nombres, des chaînes avec unités, ou du texte libre.
+// Le domaine d'un champ (fieldDomains, sinon celui de son unité par défaut) restreint les unités
+// acceptées et donne leur sens aux préfixes seuls ("12V" est refusé pour d_int, "4.7k" pour une résistance).
+// Une désignation de filetage dans le diamètre d'une vis ("M6x1x25") renseigne aussi pas et longueur.
+func NormalizeProps(props map[string]interface{}, fieldUnits map[string]string, fieldDomains map[string]UnitDomain) (map[string]interface{}, error) {
+	normalized, _, err := NormalizePropsWithUnits(props, fieldUnits, fieldDomains)
+	return normalized, err
+}
+
+// NormalizePropsWithUnits normalise comme NormalizeProps et retourne aussi, pour chaque prop
+// numérique, son unité de base et la saisie d'origine ("1/4 inch" stocké 6.35, unité mm)
+func NormalizePropsWithUnits(props map[string]interface{}, fieldUnits map[string]string, fieldDomains map[string]UnitDomain) (map[string]interface{}, map[string]PropUnit, error) {
 	normalized := make(map[string]interface{})
+	units := make(map[string]PropUnit)
+
+	raw := props
+	props, err := expandThreadDesignations(props)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	for key, value := range props {
 		// Champs texte: ne pas essayer de normaliser
@@ -357,6 +626,13 @@ func NormalizeProps(props map[string]interface{}, fieldUnits map[string]string)
 		if defaultUnit == "" {
 			defaultUnit = GetDefaultUnitForField(key)
 		}
+		domain := fieldDomains[key]
+		if domain == DomainNone && defaultUnit != "" {
+			domain = UnitConversions[defaultUnit].Domain
+		}
+		if domain == DomainCapacite {
+			value = capacitorCodeNumber(value)
+		}
 
 		// Traiter selon le type de valeur
 		switch v := value.(type) {
@@ -365,9 +641,10 @@ func NormalizeProps(props map[string]interface{}, fieldUnits map[string]string)
 			if defaultUnit != "" {
 				result, err := NormalizeValue(fmt.Sprintf("%g%s", v, defaultUnit), "")
 				if err != nil {
-					return nil, fmt.Errorf("champ '%s': %v", key, err)
+					return nil, nil, fmt.Errorf("champ '%s': %v", key, err)
 				}
 				normalized[key] = result.Value
+				units[key] = PropUnit{Unit: result.BaseUnit}
 			} else {
 				normalized[key] = v
 			}
@@ -377,28 +654,48 @@ func NormalizeProps(props map[string]interface{}, fieldUnits map[string]string)
 			if defaultUnit != "" {
 				result, err := NormalizeValue(fmt.Sprintf("%d%s", v, defaultUnit), "")
 				if err != nil {
-					return nil, fmt.Errorf("champ '%s': %v", key, err)
+					return nil, nil, fmt.Errorf("champ '%s': %v", key, err)
 				}
 				normalized[key] = result.Value
+				units[key] = PropUnit{Unit: result.BaseUnit}
 			} else {
 				normalized[key] = float64(v)
 			}
 
 		case string:
+			// Plage, tolérance, valeurs multiples ou courant AC/DC: valeur structurée (voir ranges.go)
+			structured, err := parseRangeValue(v, defaultUnit, domain)
+			if err != nil {
+				return nil, nil, fmt.Errorf("champ '%s': %v", key, err)
+			}
+			if structured != nil {
+				normalized[key] = *structured
+				units[key] = PropUnit{Unit: BaseUnits[structured.Domain]}
+				continue
+			}
+
 			// Chaîne: essayer de parser comme valeur + unité
-			_, parseErr := ParseValueWithUnit(v)
+			parsed, parseErr := ParseValueWithUnit(v)
 			if parseErr != nil {
 				// Pas un nombre, garder comme texte
 				normalized[key] = v
 				continue
 			}
 
-			// C'est une valeur numérique, normaliser
-			result, err := NormalizeValue(v, defaultUnit)
+			// C'est une valeur numérique: vérifier l'unité dans le domaine du champ, puis normaliser
+			if domain != DomainNone {
+				if err := ValidateUnitForField(key, parsed.Unit, domain); err != nil {
+					return nil, nil, err
+				}
+			}
+			result, err := NormalizeValueInDomain(v, defaultUnit, domain)
 			if err != nil {
-				return nil, fmt.Errorf("champ '%s': %v", key, err)
+				return nil, nil, fmt.Errorf("champ '%s': %v", key, err)
 			}
 			normalized[key] = result.Value
+			if result.BaseUnit != "" {
+				units[key] = PropUnit{Unit: result.BaseUnit}
+			}
 
 		default:
 			// Autre type: garder tel quel
@@ -406,7 +703,19 @@ func NormalizeProps(props map[string]interface{}, fieldUnits map[string]string)
 		}
 	}
 
-	return normalized, nil
+	// Saisie d'origine (avant décomposition d'un filetage: "M6x1x25" pour le diamètre),
+	// sauf si elle est déjà la valeur stockée ("47" ou "47mm")
+	for key, unit := range units {
+		if s, ok := raw[key].(string); ok {
+			stored := formatNameValue(normalized[key])
+			if compact := strings.ReplaceAll(s, " ", ""); compact != stored && compact != stored+unit.Unit {
+				unit.Original = s
+				units[key] = unit
+			}
+		}
+	}
+
+	return normalized, units, nil
 }
 
 // GetAcceptedUnitsForDomain retourne les unités acceptées pour un domaine
@@ -420,27 +729,28 @@ func GetAcceptedUnitsForDomain(domain UnitDomain) []string {
 	return units
 }
 
-// ValidateUnitForField vérifie qu'une unité est valide pour un champ donné
-func ValidateUnitForField(fieldName string, unit string) error {
+// ValidateUnitForField vérifie qu'une unité est valide pour un champ donné, dans son domaine
+// (domaine du template, sinon déduit du nom du champ)
+func ValidateUnitForField(fieldName string, unit string, domain UnitDomain) error {
 	if unit == "" {
 		return nil
 	}
 
-	info, exists := UnitConversions[unit]
-	if !exists {
-		return fmt.Errorf("unité '%s' non reconnue", unit)
+	if domain == DomainNone {
+		if expectedUnit := GetDefaultUnitForField(fieldName); expectedUnit != "" {
+			domain = UnitConversions[expectedUnit].Domain
+		}
 	}
-
-	// Vérifier la cohérence avec le champ
-	expectedUnit := GetDefaultUnitForField(fieldName)
-	if expectedUnit == "" {
+	if domain == DomainNone {
+		if _, exists := LookupUnit(unit); !exists {
+			return fmt.Errorf("unité '%s' non reconnue", unit)
+		}
 		return nil // Champ sans domaine spécifique
 	}
 
-	expectedInfo, _ := UnitConversions[expectedUnit]
-	if info.Domain != expectedInfo.Domain {
-		return fmt.Errorf("unité '%s' incompatible avec le champ '%s' (attendu: %s)", 
-			unit, fieldName, BaseUnits[expectedInfo.Domain])
+	// Vérifier la cohérence avec le champ
+	if _, err := resolveUnitInDomain(unit, domain); err != nil {
+		return fmt.Errorf("champ '%s': %v (attendu: %s)", fieldName, err, BaseUnits[domain])
 	}
 
 	return nil
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"math"
 	"testing"
 )
 
@@ -74,7 +75,7 @@ func TestNormalizePropsWithDefaults(t *testing.T) {
 		"width": "mm",
 	}
 
-	norm, err := NormalizeProps(props, fieldUnits)
+	norm, err := NormalizeProps(props, fieldUnits, nil)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -101,7 +102,7 @@ func TestNormalizePropsTextOnly(t *testing.T) {
 		"reference": "6001ZZ",
 		"d_int":     "10mm",
 	}
-	norm, err := NormalizeProps(props, map[string]string{"d_int": "mm"})
+	norm, err := NormalizeProps(props, map[string]string{"d_int": "mm"}, nil)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -112,3 +113,171 @@ func TestNormalizePropsTextOnly(t *testing.T) {
 		t.Fatalf("expected d_int=10, got %v", norm["d_int"])
 	}
 }
+
+func TestNormalizePropsRejectsCrossDomainUnit(t *testing.T) {
+	domains := map[string]UnitDomain{"d_int": DomainDimension, "volts": DomainTension}
+	for _, props := range []map[string]interface{}{
+		{"d_int": "12V"},
+		{"volts": "5mm"},
+		{"d_int": "10 zork"},
+	} {
+		if _, err := NormalizeProps(props, map[string]string{"d_int": "mm", "volts": "V"}, domains); err == nil {
+			t.Fatalf("%v: expected cross-domain error", props)
+		}
+	}
+}
+
+func TestNormalizePropsResolvesPrefixInDomain(t *testing.T) {
+	props := map[string]interface{}{
+		"resistance": "4.7k",
+		"volts":      "500m",
+		"courant":    "20m",
+		"capacite":   "100n",
+		"longueur":   "2m",
+	}
+	fieldUnits := map[string]string{"resistance": "Ohm", "volts": "V", "courant": "A", "capacite": "uF", "longueur": "mm"}
+	domains := map[string]UnitDomain{
+		"resistance": DomainResistance,
+		"volts":      DomainTension,
+		"courant":    DomainCourant,
+		"capacite":   DomainCapacite,
+		"longueur":   DomainDimension,
+	}
+	norm, err := NormalizeProps(props, fieldUnits, domains)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]float64{"resistance": 4700, "volts": 0.5, "courant": 0.02, "capacite": 0.1, "longueur": 2000}
+	for field, expected := range want {
+		got, _ := norm[field].(float64)
+		if math.Abs(got-expected) > 1e-9 {
+			t.Fatalf("%s: expected %v, got %v", field, expected, norm[field])
+		}
+	}
+}
+
+func TestNormalizePartPropsUsesTemplateDomain(t *testing.T) {
+	seedTemplates()
+	if _, err := NormalizePartProps("bearing", map[string]interface{}{"d_int": "12V", "d_ext": "47"}); err == nil {
+		t.Fatalf("expected 12V to be rejected for d_int")
+	}
+	norm, err := NormalizePartProps("bearing", map[string]interface{}{"d_int": "2cm", "d_ext": "47"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if norm["d_int"] != 20.0 {
+		t.Fatalf("expected d_int=20, got %v", norm["d_int"])
+	}
+}
+
+func TestLookupUnitSIPrefixes(t *testing.T) {
+	cases := []struct {
+		unit   string
+		domain UnitDomain
+		factor float64
+	}{
+		{"MΩ", DomainResistance, 1e6},
+		{"µA", DomainCourant, 1e-6},
+		{"GHz", DomainFrequence, 1e9},
+		{"nm", DomainDimension, 1e-6},
+		{"kOhm", DomainResistance, 1e3},
+		{"mm", DomainDimension, 1},
+	}
+	for _, c := range cases {
+		info, ok := LookupUnit(c.unit)
+		if !ok || info.Domain != c.domain || math.Abs(info.ToBaseFactor-c.factor) > c.factor*1e-9 {
+			t.Fatalf("%s: unexpected unit info %+v (found=%v)", c.unit, info, ok)
+		}
+	}
+	if _, ok := LookupUnit("kzork"); ok {
+		t.Fatalf("expected unknown prefixed unit")
+	}
+}
+
+func TestNormalizeValueEngineeringNotations(t *testing.T) {
+	cases := []struct {
+		input, equivalent string
+		want              float64
+		domain            UnitDomain
+	}{
+		{"4k7", "4.7kΩ", 4700, DomainResistance},
+		{"2R2", "2.2Ω", 2.2, DomainResistance},
+		{"R47", "0.47Ω", 0.47, DomainResistance},
+		{"1M", "1MΩ", 1e6, DomainResistance},
+		{"100n", "100nF", 0.1, DomainCapacite},
+		{"473J", "47nF", 0.047, DomainCapacite},
+		{"4u7", "4.7µF", 4.7, DomainCapacite},
+	}
+	for _, c := range cases {
+		res, err := NormalizeValue(c.input, "")
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", c.input, err)
+		}
+		same, err := NormalizeValue(c.equivalent, "")
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", c.equivalent, err)
+		}
+		if *res != *same || res.Value != c.want || res.Domain != c.domain {
+			t.Fatalf("%s: expected %v %s like %s, got %+v / %+v", c.input, c.want, c.domain, c.equivalent, res, same)
+		}
+	}
+}
+
+func TestEngineeringNotationKeepsThreads(t *testing.T) {
+	// Sans partie entière, seul R est une notation: "M3" et "m5" ne sont pas des valeurs
+	for _, input := range []string{"M3", "m5", "k47"} {
+		if v, err := ParseValueWithUnit(input); err == nil {
+			t.Fatalf("%s: expected no value, got %+v", input, v)
+		}
+	}
+	if !looksLikeThread("M3") {
+		t.Fatalf("M3 should still be a thread designation")
+	}
+}
+
+func TestCapacitorCodeNeedsCapacitanceContext(t *testing.T) {
+	if v, err := ParseValueWithUnit("104"); err != nil || v.Value != 104 || v.HasUnit {
+		t.Fatalf("expected plain number outside capacitance context, got %+v (%v)", v, err)
+	}
+	if v, err := ParseValueWithUnit("473K"); err != nil || v.Unit != "K" {
+		t.Fatalf("expected 473K to stay kilo outside capacitance context, got %+v (%v)", v, err)
+	}
+
+	for input, want := range map[string]float64{"104": 0.1, "473K": 0.047, "100": 100, "22": 22} {
+		res, err := NormalizeValueInDomain(input, "uF", DomainCapacite)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", input, err)
+		}
+		if res.Value != want {
+			t.Fatalf("%s: expected %v uF, got %v", input, want, res.Value)
+		}
+	}
+}
+
+func TestCapacitorCodeSameForNumberAndText(t *testing.T) {
+	units := map[string]string{"capacite": "nF"}
+	domains := map[string]UnitDomain{"capacite": DomainCapacite}
+
+	// Même saisie en nombre JSON ou en texte: même valeur stockée (uF)
+	for _, c := range []struct {
+		number, text interface{}
+		want         float64
+	}{
+		{104.0, "104", 0.1},
+		{104, "104", 0.1},
+		{100.0, "100", 0.1},
+		{4.7, "4.7", 0.0047},
+	} {
+		fromNumber, err := NormalizeProps(map[string]interface{}{"capacite": c.number}, units, domains)
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", c.number, err)
+		}
+		fromText, err := NormalizeProps(map[string]interface{}{"capacite": c.text}, units, domains)
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", c.text, err)
+		}
+		if fromNumber["capacite"] != c.want || fromText["capacite"] != c.want {
+			t.Fatalf("%v / %q: expected %v uF, got %v / %v", c.number, c.text, c.want, fromNumber["capacite"], fromText["capacite"])
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Valeurs structurées des props numériques: plage ("12-24V"), tolérance ("5V ±5%"), valeurs
+// multiples ("3.3/5V") et nature du courant ("AC 230V"). Elles sont stockées en objet JSON,
+// dans l'unité de base du domaine, ex: {"min":12,"max":24} ou {"min":4.75,"max":5.25,"nominal":5,"tolerance":"5%"}.
+
+// RangeValue est une valeur numérique structurée (Min et Max toujours renseignés)
+type RangeValue struct {
+	Min       float64   `json:"min"`
+	Max       float64   `json:"max"`
+	Nominal   *float64  `json:"nominal,omitempty"`
+	Values    []float64 `json:"values,omitempty"`    // Valeurs discrètes (3.3/5V), seules acceptées
+	Tolerance string    `json:"tolerance,omitempty"` // En % ("5%") ou dans l'unité de base ("0.1")
+	Current   string    `json:"current,omitempty"`   // AC ou DC
+
+	Domain UnitDomain `json:"-"` // Domaine détecté à la saisie (non stocké)
+}
+
+var (
+	// currentRegex isole la nature du courant, avant ou après la valeur: "AC 230V", "12VDC"
+	currentRegex = regexp.MustCompile(`(?i)^(?:(AC|DC)\s*)?(.*?)\s*(AC|DC)?$`)
+
+	// toleranceRegex: "5V ±5%", "10mm +/- 0.1", "47k ±5%"
+	toleranceRegex = regexp.MustCompile(`^(.+?)\s*(?:±|\+/-|\+-)\s*(\d*\.?\d+)\s*(%|[a-zA-ZΩµ"]*)$`)
+
+	// rangeRegex: "12-24V", "12V-24V", "100..240 V", "5 à 12V"
+	rangeRegex = regexp.MustCompile(`^([-+]?\d*\.?\d+)\s*([a-zA-ZΩµ"]*)\s*(?:-|–|\.\.|à|to)\s*(\d*\.?\d+)\s*([a-zA-ZΩµ"]*)$`)
+
+	// singleValueRegex: un nombre et son unité optionnelle, élément d'une liste "3.3/5V"
+	singleValueRegex = regexp.MustCompile(`^\s*(\d*\.?\d+)\s*([a-zA-ZΩµ"]*)\s*$`)
+)
+
+// parseRangeValue reconnaît une valeur structurée et la convertit dans l'unité de base du domaine.
+// Retourne nil (sans erreur) pour une valeur simple ou du texte, à traiter par NormalizeValue.
+func parseRangeValue(input, defaultUnit string, domain UnitDomain) (*RangeValue, error) {
+	matches := currentRegex.FindStringSubmatch(strings.TrimSpace(input))
+	current := strings.ToUpper(matches[1] + matches[3])
+	if len(current) > 2 {
+		return nil, nil // AC et DC à la fois: texte libre
+	}
+	body := strings.TrimSpace(matches[2]) // "12VDC" → "12V"
+	if _, err := ParseValueWithUnit(body); err == nil && current == "" {
+		return nil, nil // Valeur simple: "1/4 inch", "1500tr/min"
+	}
+
+	var rv *RangeValue
+	if m := toleranceRegex.FindStringSubmatch(body); m != nil {
+		nominal, err := normalizeRangeBound(m[1], defaultUnit, domain)
+		if nominal == nil {
+			return nil, err
+		}
+		var delta float64
+		tolerance := m[2] + "%"
+		if m[3] == "%" {
+			pct, _ := strconv.ParseFloat(m[2], 64)
+			delta = nominal.Value * pct / 100
+		} else {
+			unit := m[3]
+			if unit == "" {
+				unit = unitOf(m[1], defaultUnit)
+			}
+			abs, err := NormalizeValueInDomain(m[2]+unit, defaultUnit, nominal.Domain)
+			if err != nil {
+				return nil, err
+			}
+			delta = abs.Value
+			tolerance = strconv.FormatFloat(delta, 'f', -1, 64)
+		}
+		if delta < 0 {
+			delta = -delta
+		}
+		n := nominal.Value
+		rv = &RangeValue{Min: roundSignificant(n - delta), Max: roundSignificant(n + delta), Nominal: &n, Tolerance: tolerance, Domain: nominal.Domain}
+	} else if m := rangeRegex.FindStringSubmatch(body); m != nil {
+		unit := m[4]
+		if unit == "" {
+			unit = defaultUnit
+		}
+		lowUnit := m[2]
+		if lowUnit == "" {
+			lowUnit = unit
+		}
+		low, errLow := NormalizeValueInDomain(m[1]+lowUnit, defaultUnit, domain)
+		high, errHigh := NormalizeValueInDomain(m[3]+unit, defaultUnit, domain)
+		if errLow != nil || errHigh != nil {
+			if domain == DomainNone && defaultUnit == "" {
+				return nil, nil // Champ libre: "6204-2RS" reste du texte
+			}
+			if errLow != nil {
+				return nil, errLow
+			}
+			return nil, errHigh
+		}
+		if low.Domain != high.Domain {
+			return nil, fmt.Errorf("plage '%s': unités incompatibles", input)
+		}
+		if low.Value > high.Value {
+			return nil, nil // "2024-05" n'est pas une plage
+		}
+		rv = &RangeValue{Min: low.Value, Max: high.Value, Domain: low.Domain}
+	} else if items := strings.Split(body, "/"); len(items) > 1 {
+		unit := defaultUnit
+		last := singleValueRegex.FindStringSubmatch(items[len(items)-1])
+		if last == nil {
+			return nil, nil // "1500tr/min" est une valeur simple
+		}
+		if last[2] != "" {
+			unit = last[2]
+		}
+		rv = &RangeValue{}
+		for i, item := range items {
+			m := singleValueRegex.FindStringSubmatch(item)
+			if m == nil {
+				return nil, nil
+			}
+			itemUnit := m[2]
+			if itemUnit == "" {
+				itemUnit = unit
+			}
+			result, err := NormalizeValueInDomain(m[1]+itemUnit, defaultUnit, domain)
+			if err != nil {
+				return nil, err
+			}
+			rv.Values = append(rv.Values, result.Value)
+			rv.Domain = result.Domain
+			if i == 0 || result.Value < rv.Min {
+				rv.Min = result.Value
+			}
+			if i == 0 || result.Value > rv.Max {
+				rv.Max = result.Value
+			}
+		}
+	} else if current != "" {
+		result, err := normalizeRangeBound(body, defaultUnit, domain)
+		if result == nil {
+			return nil, err // "Mac" n'est pas une valeur "M" en courant alternatif
+		}
+		n := result.Value
+		rv = &RangeValue{Min: n, Max: n, Nominal: &n, Domain: result.Domain}
+	}
+
+	if rv == nil {
+		return nil, nil
+	}
+	rv.Current = current
+	return rv, nil
+}
+
+// normalizeRangeBound convertit une valeur simple d'une valeur structurée dans l'unité de base
+// (nil sans erreur si ce n'est pas un nombre: la saisie reste alors du texte)
+func normalizeRangeBound(input, defaultUnit string, domain UnitDomain) (*NormalizeResult, error) {
+	if _, err := ParseValueWithUnit(input); err != nil {
+		return nil, nil
+	}
+	return NormalizeValueInDomain(input, defaultUnit, domain)
+}
+
+// unitOf retourne l'unité saisie avec une valeur ("5V" → "V"), defaultUnit si aucune
+func unitOf(input, defaultUnit string) string {
+	if parsed, err := ParseValueWithUnit(input); err == nil && parsed.HasUnit {
+		return parsed.Unit
+	}
+	return defaultUnit
+}
+
+// AsRangeValue retrouve une valeur structurée dans une prop, telle que normalisée (RangeValue)
+// ou relue depuis la base (objet JSON avec min et max)
+func AsRangeValue(value interface{}) (RangeValue, bool) {
+	switch v := value.(type) {
+	case RangeValue:
+		return v, true
+	case *RangeValue:
+		return *v, v != nil
+	case map[string]interface{}:
+		if _, ok := v["min"]; !ok {
+			return RangeValue{}, false
+		}
+		if _, ok := v["max"]; !ok {
+			return RangeValue{}, false
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			return RangeValue{}, false
+		}
+		var rv RangeValue
+		if err := json.Unmarshal(data, &rv); err != nil {
+			return RangeValue{}, false
+		}
+		return rv, true
+	}
+	return RangeValue{}, false
+}
+
+// Overlaps indique si la valeur recoupe l'intervalle [lo, hi]: une plage 12-24V est trouvée par
+// volts:12 ou volts:20..30, des valeurs multiples 3.3/5V par volts:5 mais pas par volts:4
+func (r RangeValue) Overlaps(lo, hi float64) bool {
+	if len(r.Values) > 0 {
+		for _, v := range r.Values {
+			if v >= lo && v <= hi {
+				return true
+			}
+		}
+		return false
+	}
+	return r.Min <= hi && r.Max >= lo
+}
+
+// Number retourne la valeur représentative (nominale, ou unique) pour les calculs
+func (r RangeValue) Number() (float64, bool) {
+	if r.Nominal != nil {
+		return *r.Nominal, true
+	}
+	if r.Min == r.Max {
+		return r.Min, true
+	}
+	return 0, false
+}
+
+// String formate la valeur pour l'affichage et les noms: "12-24", "5 ±5%", "3.3/5", "AC 230"
+func (r RangeValue) String() string {
+	format := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+
+	var s string
+	switch {
+	case len(r.Values) > 0:
+		items := make([]string, len(r.Values))
+		for i, v := range r.Values {
+			items[i] = format(v)
+		}
+		s = strings.Join(items, "/")
+	case r.Nominal != nil && r.Tolerance != "":
+		s = format(*r.Nominal) + " ±" + r.Tolerance
+	case r.Nominal != nil:
+		s = format(*r.Nominal)
+	default:
+		s = format(r.Min) + "-" + format(r.Max)
+	}
+	if r.Current != "" {
+		s = r.Current + " " + s
+	}
+	return s
+}
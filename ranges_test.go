@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseRangeValue(t *testing.T) {
+	five := 5.0
+	twoThirty := 230.0
+	cases := []struct {
+		input  string
+		domain UnitDomain
+		want   RangeValue
+	}{
+		{"12-24V", DomainTension, RangeValue{Min: 12, Max: 24, Domain: DomainTension}},
+		{"500mV..1.5V", DomainTension, RangeValue{Min: 0.5, Max: 1.5, Domain: DomainTension}},
+		{"5V ±5%", DomainTension, RangeValue{Min: 4.75, Max: 5.25, Nominal: &five, Tolerance: "5%", Domain: DomainTension}},
+		{"3.3/5V", DomainTension, RangeValue{Min: 3.3, Max: 5, Values: []float64{3.3, 5}, Domain: DomainTension}},
+		{"AC 230V", DomainTension, RangeValue{Min: 230, Max: 230, Nominal: &twoThirty, Current: "AC", Domain: DomainTension}},
+		{"12-24VDC", DomainTension, RangeValue{Min: 12, Max: 24, Current: "DC", Domain: DomainTension}},
+		{"10cm +/- 1mm", DomainDimension, RangeValue{Min: 99, Max: 101, Nominal: func() *float64 { v := 100.0; return &v }(), Tolerance: "1", Domain: DomainDimension}},
+	}
+	for _, c := range cases {
+		rv, err := parseRangeValue(c.input, BaseUnits[c.domain], c.domain)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", c.input, err)
+		}
+		if rv == nil || !reflect.DeepEqual(*rv, c.want) {
+			t.Fatalf("%s: expected %+v, got %+v", c.input, c.want, rv)
+		}
+	}
+
+	// Valeurs simples et texte: laissées à NormalizeValue
+	for _, input := range []string{"12V", "1500tr/min", "Mac", "2024-05", "6204-2RS"} {
+		if rv, err := parseRangeValue(input, "", DomainNone); err != nil || rv != nil {
+			t.Fatalf("%s: expected no structured value, got %+v (%v)", input, rv, err)
+		}
+	}
+	if _, err := parseRangeValue("12V-24mm", "V", DomainTension); err == nil {
+		t.Fatalf("expected cross-domain range to be rejected")
+	}
+}
+
+func TestFractionIsNotMultiValue(t *testing.T) {
+	seedTemplates()
+
+	// "1/4 inch" est une fraction de pouce, pas les valeurs multiples 1 et 4 pouces
+	for input, want := range map[string]float64{"1/4 inch": 6.35, `1/2"`: 12.7} {
+		if rv, err := parseRangeValue(input, "mm", DomainDimension); err != nil || rv != nil {
+			t.Fatalf("%s: expected no structured value, got %+v (%v)", input, rv, err)
+		}
+		props, err := NormalizePartProps("bearing", map[string]interface{}{"d_int": input, "d_ext": 47.0, "width": 14.0})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", input, err)
+		}
+		if props["d_int"] != want {
+			t.Fatalf("%s: expected d_int=%v, got %#v", input, want, props["d_int"])
+		}
+	}
+}
+
+func TestRangeValueOverlapsAndFormat(t *testing.T) {
+	rv, _ := parseRangeValue("12-24V", "V", DomainTension)
+	if !rv.Overlaps(12, 12) || !rv.Overlaps(20, 30) || rv.Overlaps(5, 11) {
+		t.Fatalf("unexpected overlap for %+v", rv)
+	}
+	multi, _ := parseRangeValue("3.3/5V", "V", DomainTension)
+	if !multi.Overlaps(5, 5) || multi.Overlaps(4, 4) {
+		t.Fatalf("discrete values should only match themselves: %+v", multi)
+	}
+
+	for input, want := range map[string]string{"12-24V": "12-24", "5V ±5%": "5 ±5%", "3.3/5V": "3.3/5", "AC 230V": "AC 230"} {
+		rv, _ := parseRangeValue(input, "V", DomainTension)
+		if got := formatNameValue(*rv); got != want {
+			t.Fatalf("%s: expected %q, got %q", input, want, got)
+		}
+	}
+
+	// Relue depuis la base: objet JSON
+	var decoded map[string]interface{}
+	data, _ := json.Marshal(map[string]interface{}{"volts": *rv})
+	json.Unmarshal(data, &decoded)
+	if back, ok := AsRangeValue(decoded["volts"]); !ok || back.Min != 12 || back.Max != 24 {
+		t.Fatalf("expected range read back from JSON, got %+v", back)
+	}
+}
+
+func TestValidatePropsRangeBounds(t *testing.T) {
+	min, max := 0.0, 48.0
+	raw := map[string]*Template{
+		"alim": {Name: "alim", Strict: true, Fields: map[string]FieldDef{
+			"volts": {Type: FieldNumber, Domain: "tension", DefaultUnit: "V", Min: &min, Max: &max},
+		}},
+	}
+	resolved, err := ResolveTemplates(raw)
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	Templates.Set(resolved)
+
+	if err := ValidateProps("alim", map[string]interface{}{"volts": "12-24V"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := ValidateProps("alim", map[string]interface{}{"volts": "AC 100-240V"}); err == nil {
+		t.Fatalf("expected range above max to be rejected")
+	}
+}
+
+func TestSearchMatchesOverlappingRange(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+
+	for name, volts := range map[string]string{"Moteur 12-24V": "12-24V", "Moteur 230V AC": "AC 230V", "Module 3.3/5V": "3.3/5V", "Moteur 6V": "6V"} {
+		props, err := NormalizeProps(map[string]interface{}{"volts": volts}, map[string]string{"volts": "V"}, nil)
+		if err != nil {
+			t.Fatalf("%s: normalize: %v", volts, err)
+		}
+		data, _ := json.Marshal(props)
+		if _, err := CreatePart(db, "moteur", name, string(data), nil, 1); err != nil {
+			t.Fatalf("create part: %v", err)
+		}
+	}
+
+	search := func(prop string) []string {
+		t.Helper()
+		criteria, err := ParseSearchProp(prop)
+		if err != nil {
+			t.Fatalf("parse %s: %v", prop, err)
+		}
+		parts, err := SearchPartsDB(db, SearchFilters{Type: "moteur", Criteria: criteria})
+		if err != nil {
+			t.Fatalf("search %s: %v", prop, err)
+		}
+		var names []string
+		for _, p := range parts {
+			names = append(names, p.Name)
+		}
+		return names
+	}
+
+	if got := search("volts:12"); !reflect.DeepEqual(got, []string{"Moteur 12-24V"}) {
+		t.Fatalf("volts:12: unexpected results %v", got)
+	}
+	if got := search("volts:4..6"); len(got) != 2 {
+		t.Fatalf("volts:4..6: expected 3.3/5V and 6V, got %v", got)
+	}
+	if got := search("volts:4..4.5"); len(got) != 0 {
+		t.Fatalf("volts:4..4.5: expected no discrete match, got %v", got)
+	}
+	if got := search("volts:200..250"); !reflect.DeepEqual(got, []string{"Moteur 230V AC"}) {
+		t.Fatalf("volts:200..250: unexpected results %v", got)
+	}
+}
@@ -0,0 +1,407 @@
+package main
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Templates par défaut, embarqués dans le binaire
+//
+//go:embed templates/*.yaml
+var embeddedTemplates embed.FS
+
+// Origines d'un template, de la moins à la plus prioritaire: un fichier du dossier templates
+// remplace le template embarqué du même nom, un template en base remplace le fichier
+const (
+	TemplateSourceEmbedded = "embedded"
+	TemplateSourceFile     = "file"
+	TemplateSourceDB       = "db"
+)
+
+var templateNameRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9_]*$`)
+
+// TemplateRegistry contient les templates résolus (héritage appliqué).
+// Il est rechargé après chaque modification et peut l'être à chaud par le serveur.
+type TemplateRegistry struct {
+	mu          sync.RWMutex
+	templates   map[string]*Template
+	sources     map[string]string // Origine de chaque template
+	definitions map[string]string // Définition YAML d'origine
+}
+
+// NewTemplateRegistry crée un registre vide
+func NewTemplateRegistry() *TemplateRegistry {
+	return &TemplateRegistry{
+		templates:   make(map[string]*Template),
+		sources:     make(map[string]string),
+		definitions: make(map[string]string),
+	}
+}
+
+// Templates est le registre des templates chargés
+var Templates = NewTemplateRegistry()
+
+// Get retourne un template par son nom
+func (r *TemplateRegistry) Get(name string) (*Template, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	tmpl, ok := r.templates[name]
+	return tmpl, ok
+}
+
+// Names retourne les noms des templates, triés
+func (r *TemplateRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.templates))
+	for name := range r.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Len retourne le nombre de templates chargés
+func (r *TemplateRegistry) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.templates)
+}
+
+// Source retourne l'origine d'un template (embedded, file, db)
+func (r *TemplateRegistry) Source(name string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.sources[name]
+}
+
+// Definition retourne la définition YAML d'origine d'un template
+func (r *TemplateRegistry) Definition(name string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.definitions[name]
+}
+
+// Set remplace le contenu du registre par des templates déjà résolus
+func (r *TemplateRegistry) Set(templates map[string]*Template) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.templates = templates
+	r.sources = make(map[string]string)
+	r.definitions = make(map[string]string)
+}
+
+// Register ajoute ou remplace un template déjà résolu
+func (r *TemplateRegistry) Register(tmpl *Template) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.templates[tmpl.Name] = tmpl
+}
+
+// ErrTemplatesSkipped signale des templates en base écartés au chargement (les autres sont chargés)
+var ErrTemplatesSkipped = errors.New("templates en base ignorés")
+
+// Reload relit les templates embarqués, les fichiers du dossier templates et ceux de la base
+// (db nil: pas de couche base). Un template en base illisible ou incohérent (parent inconnu,
+// cycle) est écarté: les autres sont chargés et l'erreur retournée enveloppe ErrTemplatesSkipped.
+// Pour toute autre erreur, le registre garde son contenu précédent.
+func (r *TemplateRegistry) Reload(db *sql.DB) error {
+	layers, err := readTemplateLayers(nil, "")
+	if err != nil {
+		return err
+	}
+	stored, skipped, err := readDBTemplates(db, "")
+	if err != nil {
+		return err
+	}
+
+	// Ajout des templates en base tant que l'ensemble se résout: un enfant enregistré
+	// avant son parent est repris au tour suivant
+	resolved, err := ResolveTemplates(layers.raw)
+	if err != nil {
+		return err
+	}
+	for progress := true; progress && len(stored) > 0; {
+		progress = false
+		var pending []dbTemplate
+		for _, entry := range stored {
+			candidate := layers.with(entry)
+			result, err := ResolveTemplates(candidate.raw)
+			if err != nil {
+				entry.err = err
+				pending = append(pending, entry)
+				continue
+			}
+			layers, resolved, progress = candidate, result, true
+		}
+		stored = pending
+	}
+	for _, entry := range stored {
+		skipped = append(skipped, fmt.Errorf("template %s (base): %v", entry.tmpl.Name, entry.err))
+	}
+
+	r.mu.Lock()
+	r.templates = resolved
+	r.sources = layers.sources
+	r.definitions = layers.definitions
+	r.mu.Unlock()
+
+	if len(skipped) > 0 {
+		return fmt.Errorf("%w: %v", ErrTemplatesSkipped, errors.Join(skipped...))
+	}
+	return nil
+}
+
+// templateLayers est la superposition des templates bruts (avant résolution de l'héritage)
+type templateLayers struct {
+	raw         map[string]*Template
+	sources     map[string]string
+	definitions map[string]string
+}
+
+func (l *templateLayers) add(tmpl *Template, source, definition string) {
+	l.raw[tmpl.Name] = tmpl
+	l.sources[tmpl.Name] = source
+	l.definitions[tmpl.Name] = definition
+}
+
+// with retourne une copie des couches complétée d'un template en base
+func (l *templateLayers) with(entry dbTemplate) *templateLayers {
+	copied := &templateLayers{
+		raw:         make(map[string]*Template, len(l.raw)+1),
+		sources:     make(map[string]string, len(l.sources)+1),
+		definitions: make(map[string]string, len(l.definitions)+1),
+	}
+	for name := range l.raw {
+		copied.add(l.raw[name], l.sources[name], l.definitions[name])
+	}
+	copied.add(entry.tmpl, TemplateSourceDB, entry.definition)
+	return copied
+}
+
+// dbTemplate est un template lu dans la table templates
+type dbTemplate struct {
+	tmpl       *Template
+	definition string
+	err        error // Dernière erreur de résolution (template écarté)
+}
+
+// readTemplateLayers superpose les templates embarqués, les fichiers et la base.
+// skipDB écarte un template de la couche base (simulation d'une suppression).
+func readTemplateLayers(db *sql.DB, skipDB string) (*templateLayers, error) {
+	layers := &templateLayers{
+		raw:         make(map[string]*Template),
+		sources:     make(map[string]string),
+		definitions: make(map[string]string),
+	}
+
+	if err := readTemplateDir(layers, embeddedTemplates, templatesDir, TemplateSourceEmbedded); err != nil {
+		return nil, err
+	}
+	if err := readTemplateDir(layers, os.DirFS("."), templatesDir, TemplateSourceFile); err != nil {
+		return nil, err
+	}
+
+	stored, invalid, err := readDBTemplates(db, skipDB)
+	if err != nil {
+		return nil, err
+	}
+	if len(invalid) > 0 {
+		return nil, invalid[0]
+	}
+	for _, entry := range stored {
+		layers.add(entry.tmpl, TemplateSourceDB, entry.definition)
+	}
+	return layers, nil
+}
+
+// readDBTemplates lit les templates enregistrés en base (db nil: aucun), sauf skip.
+// Les définitions illisibles sont retournées à part.
+func readDBTemplates(db *sql.DB, skip string) ([]dbTemplate, []error, error) {
+	if db == nil {
+		return nil, nil, nil
+	}
+	rows, err := db.Query("SELECT name, definition FROM templates ORDER BY name")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var stored []dbTemplate
+	var invalid []error
+	for rows.Next() {
+		var name, definition string
+		if err := rows.Scan(&name, &definition); err != nil {
+			return nil, nil, err
+		}
+		if name == skip {
+			continue
+		}
+		tmpl, err := ParseTemplate(definition)
+		if err != nil {
+			invalid = append(invalid, fmt.Errorf("template %s (base): %v", name, err))
+			continue
+		}
+		stored = append(stored, dbTemplate{tmpl: tmpl, definition: definition})
+	}
+	return stored, invalid, rows.Err()
+}
+
+// readTemplateDir lit les fichiers YAML d'un dossier (absent: aucun template)
+func readTemplateDir(layers *templateLayers, fsys fs.FS, dir, source string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".yaml") {
+			continue
+		}
+
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+		tmpl, err := ParseTemplate(string(data))
+		if err != nil {
+			return fmt.Errorf("erreur parsing %s: %v", entry.Name(), err)
+		}
+		layers.add(tmpl, source, string(data))
+	}
+	return nil
+}
+
+// ParseTemplate lit une définition de template (YAML, ou JSON avec les mêmes clés)
+func ParseTemplate(definition string) (*Template, error) {
+	var tmpl Template
+	if err := yaml.Unmarshal([]byte(definition), &tmpl); err != nil {
+		return nil, err
+	}
+	if !templateNameRegex.MatchString(tmpl.Name) {
+		return nil, fmt.Errorf("nom de template invalide '%s' (minuscules, chiffres et _)", tmpl.Name)
+	}
+	return &tmpl, nil
+}
+
+// AddTemplate enregistre en base un nouveau type de pièce
+func AddTemplate(db *sql.DB, definition string) (*Template, error) {
+	tmpl, err := ParseTemplate(definition)
+	if err != nil {
+		return nil, err
+	}
+	if _, exists := Templates.Get(tmpl.Name); exists {
+		return nil, fmt.Errorf("le template %s existe déjà (templates edit pour le modifier)", tmpl.Name)
+	}
+	return saveTemplate(db, tmpl, definition)
+}
+
+// UpdateTemplate remplace la définition d'un template. Un template embarqué ou fichier
+// est surchargé en base (le supprimer de la base rétablit la version d'origine).
+func UpdateTemplate(db *sql.DB, name, definition string) (*Template, error) {
+	tmpl, err := ParseTemplate(definition)
+	if err != nil {
+		return nil, err
+	}
+	if tmpl.Name != name {
+		return nil, fmt.Errorf("le nom du template ne peut pas changer (%s → %s)", name, tmpl.Name)
+	}
+	if _, exists := Templates.Get(name); !exists {
+		return nil, fmt.Errorf("template inconnu: %s", name)
+	}
+	return saveTemplate(db, tmpl, definition)
+}
+
+// saveTemplate vérifie le template avec les autres (héritage, champs, migrations),
+// l'enregistre en base puis recharge le registre
+func saveTemplate(db *sql.DB, tmpl *Template, definition string) (*Template, error) {
+	layers, err := readTemplateLayers(db, "")
+	if err != nil {
+		return nil, err
+	}
+	layers.add(tmpl, TemplateSourceDB, definition)
+	if _, err := ResolveTemplates(layers.raw); err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO templates (name, definition) VALUES (?, ?)
+		ON CONFLICT(name) DO UPDATE SET definition = excluded.definition, updated_at = CURRENT_TIMESTAMP
+	`, tmpl.Name, definition)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := Templates.Reload(db); err != nil {
+		return nil, err
+	}
+	saved, _ := Templates.Get(tmpl.Name)
+	return saved, nil
+}
+
+// DeleteTemplate supprime un template enregistré en base. Si un fichier ou un template embarqué
+// du même nom existe, il redevient actif; sinon le type ne doit plus être utilisé.
+func DeleteTemplate(db *sql.DB, name string) error {
+	var stored int
+	if err := db.QueryRow("SELECT COUNT(*) FROM templates WHERE name = ?", name).Scan(&stored); err != nil {
+		return err
+	}
+	if stored == 0 {
+		if _, exists := Templates.Get(name); exists {
+			return fmt.Errorf("le template %s vient de %s: seuls les templates enregistrés en base peuvent être supprimés",
+				name, templateSourceLabel(Templates.Source(name)))
+		}
+		return fmt.Errorf("template inconnu: %s", name)
+	}
+
+	layers, err := readTemplateLayers(db, name)
+	if err != nil {
+		return err
+	}
+	if _, fallback := layers.raw[name]; !fallback {
+		var parts int
+		if err := db.QueryRow("SELECT COUNT(*) FROM parts WHERE type = ?", name).Scan(&parts); err != nil {
+			return err
+		}
+		if parts > 0 {
+			return fmt.Errorf("%d pièce(s) de type %s (corbeille incluse): changez leur type avant de supprimer le template", parts, name)
+		}
+	}
+	// Refuser si d'autres templates en héritent
+	if _, err := ResolveTemplates(layers.raw); err != nil {
+		return err
+	}
+
+	if _, err := db.Exec("DELETE FROM templates WHERE name = ?", name); err != nil {
+		return err
+	}
+	return Templates.Reload(db)
+}
+
+// templateSourceLabel traduit l'origine d'un template pour l'affichage
+func templateSourceLabel(source string) string {
+	switch source {
+	case TemplateSourceEmbedded:
+		return "la configuration embarquée"
+	case TemplateSourceFile:
+		return "le dossier " + templatesDir
+	case TemplateSourceDB:
+		return "la base"
+	default:
+		return source
+	}
+}
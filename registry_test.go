@@ -0,0 +1,192 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const capteurYAML = `name: capteur
+description: Capteur
+fields:
+  sortie:
+    required: true
+    type: enum
+    values: [analogique, I2C]
+`
+
+func TestRegistryReloadLayers(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+
+	if err := LoadTemplates(db); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if _, ok := Templates.Get("moteur"); !ok {
+		t.Fatalf("expected moteur template to be loaded")
+	}
+	if src := Templates.Source("moteur"); src != TemplateSourceFile && src != TemplateSourceEmbedded {
+		t.Fatalf("unexpected source for moteur: %q", src)
+	}
+	if !strings.Contains(Templates.Definition("moteur"), "name: moteur") {
+		t.Fatalf("expected moteur definition to be kept")
+	}
+}
+
+func TestAddUpdateDeleteTemplate(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	if err := LoadTemplates(db); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	tmpl, err := AddTemplate(db, capteurYAML)
+	if err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	if tmpl.Name != "capteur" || Templates.Source("capteur") != TemplateSourceDB {
+		t.Fatalf("expected capteur stored in db, got %+v (%s)", tmpl, Templates.Source("capteur"))
+	}
+	if _, err := AddTemplate(db, capteurYAML); err == nil {
+		t.Fatalf("expected duplicate template to be rejected")
+	}
+
+	updated, err := UpdateTemplate(db, "capteur", capteurYAML+`  portee:
+    domain: dimension
+    default_unit: mm
+`)
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if _, ok := updated.Fields["portee"]; !ok {
+		t.Fatalf("expected new field after update: %v", updated.Fields)
+	}
+
+	// Le registre est relu depuis la base (ex: autre processus, redémarrage)
+	Templates.Set(map[string]*Template{})
+	if err := Templates.Reload(db); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if tmpl, ok := Templates.Get("capteur"); !ok || len(tmpl.Fields) != 2 {
+		t.Fatalf("expected capteur reloaded from db, got %+v", tmpl)
+	}
+
+	if _, err := CreatePart(db, "capteur", "BMP280", `{"sortie":"I2C"}`, nil, 1); err != nil {
+		t.Fatalf("create part: %v", err)
+	}
+	if err := DeleteTemplate(db, "capteur"); err == nil || !strings.Contains(err.Error(), "pièce(s)") {
+		t.Fatalf("expected delete to be refused while parts use the type, got %v", err)
+	}
+}
+
+func TestTemplateOverrideFallsBackOnDelete(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	if err := LoadTemplates(db); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	original := Templates.Source("moteur")
+
+	if err := DeleteTemplate(db, "moteur"); err == nil {
+		t.Fatalf("expected non-db template deletion to be refused")
+	}
+
+	if _, err := UpdateTemplate(db, "moteur", "name: moteur\ndescription: Moteur (atelier)\nfields:\n  volts:\n    required: true\n"); err != nil {
+		t.Fatalf("override: %v", err)
+	}
+	if tmpl, _ := Templates.Get("moteur"); tmpl.Description != "Moteur (atelier)" || Templates.Source("moteur") != TemplateSourceDB {
+		t.Fatalf("expected db override, got %q (%s)", tmpl.Description, Templates.Source("moteur"))
+	}
+
+	if err := DeleteTemplate(db, "moteur"); err != nil {
+		t.Fatalf("delete override: %v", err)
+	}
+	if tmpl, _ := Templates.Get("moteur"); tmpl.Description != "Moteur électrique" || Templates.Source("moteur") != original {
+		t.Fatalf("expected original moteur restored, got %q (%s)", tmpl.Description, Templates.Source("moteur"))
+	}
+}
+
+func TestSaveTemplateValidatesAgainstRegistry(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	if err := LoadTemplates(db); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	if _, err := AddTemplate(db, "name: orphelin\nextends: inexistant\n"); err == nil {
+		t.Fatalf("expected unknown parent to be rejected")
+	}
+	if _, err := AddTemplate(db, "name: Mauvais Nom\n"); err == nil {
+		t.Fatalf("expected invalid name to be rejected")
+	}
+	if _, err := UpdateTemplate(db, "moteur", capteurYAML); err == nil {
+		t.Fatalf("expected rename through update to be rejected")
+	}
+
+	if _, err := AddTemplate(db, capteurYAML); err != nil {
+		t.Fatalf("add parent: %v", err)
+	}
+	if _, err := AddTemplate(db, "name: capteur_temp\nextends: capteur\n"); err != nil {
+		t.Fatalf("add child: %v", err)
+	}
+	if err := DeleteTemplate(db, "capteur"); err == nil {
+		t.Fatalf("expected parent deletion to be refused while a template extends it")
+	}
+}
+
+func TestReloadSkipsInvalidDBTemplates(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+
+	// Enfant enregistré avant son parent (ordre alphabétique), puis deux entrées invalides
+	for name, definition := range map[string]string{
+		"capteur":   capteurYAML,
+		"a_capteur": "name: a_capteur\nextends: capteur\n",
+		"orphelin":  "name: orphelin\nextends: inexistant\n",
+		"illisible": "name: [illisible\n",
+	} {
+		if _, err := db.Exec("INSERT INTO templates (name, definition) VALUES (?, ?)", name, definition); err != nil {
+			t.Fatalf("insert %s: %v", name, err)
+		}
+	}
+
+	err := LoadTemplates(db)
+	if !errors.Is(err, ErrTemplatesSkipped) || !strings.Contains(err.Error(), "orphelin") || !strings.Contains(err.Error(), "illisible") {
+		t.Fatalf("expected invalid db templates to be reported, got %v", err)
+	}
+	for _, name := range []string{"moteur", "capteur", "a_capteur"} {
+		if _, ok := Templates.Get(name); !ok {
+			t.Fatalf("expected %s to be loaded despite invalid db templates", name)
+		}
+	}
+	if _, ok := Templates.Get("orphelin"); ok {
+		t.Fatalf("expected orphelin to be skipped")
+	}
+}
+
+func TestTemplatesSurviveBackupRestore(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	if err := LoadTemplates(db); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if _, err := AddTemplate(db, capteurYAML); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+
+	file := filepath.Join(t.TempDir(), "backup.json")
+	if err := CreateBackup(db, file); err != nil {
+		t.Fatalf("backup: %v", err)
+	}
+	if _, err := db.Exec("DELETE FROM templates"); err != nil {
+		t.Fatalf("delete templates: %v", err)
+	}
+	if err := RestoreFromBackup(db, file); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+
+	if !strings.Contains(Templates.Definition("capteur"), "name: capteur") || Templates.Source("capteur") != TemplateSourceDB {
+		t.Fatalf("expected capteur restored from db, got %q (%s)", Templates.Definition("capteur"), Templates.Source("capteur"))
+	}
+}
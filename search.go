@@ -57,6 +57,15 @@ func ParseSearchProp(prop string) (*SearchCriteria, error) {
 
 // MatchesCriteria vérifie si une valeur correspond au critère
 func (c *SearchCriteria) MatchesCriteria(propVal interface{}) bool {
+	// Valeur structurée (plage, tolérance...): le critère doit la recouper
+	if rv, ok := AsRangeValue(propVal); ok {
+		if c.IsRange {
+			return rv.Overlaps(c.MinVal, c.MaxVal)
+		}
+		n, err := strconv.ParseFloat(c.ExactVal, 64)
+		return err == nil && rv.Overlaps(n, n)
+	}
+
 	if c.IsRange {
 		numVal, ok := toFloat64(propVal)
 		if !ok {
@@ -3,12 +3,14 @@ package main
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"html/template"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -18,12 +20,18 @@ import (
 
 // PartAPIResponse représente une pièce renvoyée par l'API
 type PartAPIResponse struct {
-	ID       int             `json:"id"`
-	Type     string          `json:"type"`
-	Name     string          `json:"name"`
-	Props    json.RawMessage `json:"props"`
-	Location string          `json:"location,omitempty"`
-	Source   string          `json:"source,omitempty"` // "local" ou nom du peer
+	ID        int             `json:"id"`
+	Type      string          `json:"type"`
+	Name      string          `json:"name"`
+	Props     json.RawMessage `json:"props"`
+	Quantity  int             `json:"quantity"`
+	Available int             `json:"available"` // Quantité non prêtée (0: pièce sortie, indisponible)
+	State     string          `json:"state"`
+	Tags      []string        `json:"tags,omitempty"`
+	Location  string          `json:"location,omitempty"`
+	Source    string          `json:"source,omitempty"` // "local" ou nom du peer
+
+	Display map[string]DisplayValue `json:"display,omitempty"` // Props dans les unités demandées (?units=imperial)
 }
 
 // LocationAPIResponse représente une localisation renvoyée par l'API
@@ -40,9 +48,38 @@ type LocationAPIResponse struct {
 func cmdServe(db *sql.DB, args []string) error {
 	fs := flag.NewFlagSet("serve", flag.ExitOnError)
 	port := fs.Int("port", 8080, "Port HTTP")
+	actor := fs.String("actor", "web", "Auteur enregistré dans l'historique pour les modifications via l'API")
+	alertsEvery := fs.Duration("alerts-every", 0, "Intervalle des alertes de stock minimum (ex: 1h, 0: désactivé)")
+	notify := fs.String("notify", "log", "Destination des alertes (log, webhook, smtp)")
+	notifyURL := fs.String("notify-url", "", "URL du webhook (--notify=webhook)")
+	smtpAddr := fs.String("smtp-addr", "localhost:25", "Relais SMTP (--notify=smtp)")
+	smtpFrom := fs.String("smtp-from", "", "Expéditeur des e-mails d'alerte")
+	smtpTo := fs.String("smtp-to", "", "Destinataire(s) des e-mails d'alerte, séparés par des virgules")
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
+	historyActor = *actor
+
+	if *alertsEvery > 0 {
+		var recipients []string
+		for _, to := range strings.Split(*smtpTo, ",") {
+			if to = strings.TrimSpace(to); to != "" {
+				recipients = append(recipients, to)
+			}
+		}
+		notifier, err := NewNotifier(NotifierConfig{
+			Kind:       *notify,
+			WebhookURL: *notifyURL,
+			SMTPAddr:   *smtpAddr,
+			SMTPFrom:   *smtpFrom,
+			SMTPTo:     recipients,
+		})
+		if err != nil {
+			return err
+		}
+		log.Printf("Alertes de stock: toutes les %s (%s)", *alertsEvery, *notify)
+		go watchStockAlerts(db, notifier, *alertsEvery)
+	}
 
 	// charger les templates HTML embarqués
 	mustLoadWebTemplates()
@@ -168,10 +205,25 @@ func cmdServe(db *sql.DB, args []string) error {
 			return
 		}
 		if !meta.Found {
+			// Pièce fusionnée: les anciennes étiquettes redirigent vers la pièce survivante
+			if newID, ok, _ := ResolvePartRedirect(db, id); ok {
+				http.Redirect(w, r, fmt.Sprintf("/view/%d", newID), http.StatusMovedPermanently)
+				return
+			}
 			http.NotFound(w, r)
 			return
 		}
-		if err := tplView.ExecuteTemplate(w, "view", meta); err != nil {
+		display, err := displayUnitsFromQuery(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		data := struct {
+			*PartMeta
+			Error  string
+			Fields []FieldDisplay
+		}{PartMeta: meta, Fields: meta.DisplayFields(display)}
+		if err := tplView.ExecuteTemplate(w, "view", data); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 		}
 	})
@@ -179,15 +231,17 @@ func cmdServe(db *sql.DB, args []string) error {
 	// partial recherche (htmx)
 	mux.HandleFunc("/partials/search", func(w http.ResponseWriter, r *http.Request) {
 		q := r.URL.Query().Get("q")
-		typeName := ""
-		nameSearch := q
-		propSearch := ""
+		filters := SearchFilters{Name: q}
 		// si la requête contient un ':' on le traite comme critère prop
 		if strings.Contains(q, ":") {
-			propSearch = q
-			nameSearch = ""
+			criteria, err := MustCriteriaFromProp(q)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			filters = SearchFilters{Criteria: criteria}
 		}
-		results, err := searchParts(db, typeName, nameSearch, propSearch)
+		results, err := searchParts(db, filters, DisplayUnits{})
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
@@ -212,40 +266,39 @@ func cmdServe(db *sql.DB, args []string) error {
 		}
 		typeName := r.URL.Query().Get("type")
 		if typeName == "" {
+			if r.Header.Get("HX-Request") == "true" {
+				return
+			}
 			writeJSON(w, http.StatusOK, map[string]interface{}{"fields": []interface{}{}})
 			return
 		}
 
-		template, exists := Templates[typeName]
+		template, exists := Templates.Get(typeName)
 		if !exists {
 			http.Error(w, "template not found", http.StatusNotFound)
 			return
 		}
 
-		fields := []map[string]interface{}{}
-		for fieldName, fieldDef := range template.Fields {
-			field := map[string]interface{}{
-				"name":        fieldName,
-				"description": fieldDef.Description,
-				"required":    fieldDef.Required,
-				"type":        "text",
-			}
+		fields := TemplateFieldSchemas(template, requestLang(r))
 
-			if fieldDef.Domain != "" {
-				field["domain"] = fieldDef.Domain
-				if fieldDef.Domain == "tension" || fieldDef.Domain == "puissance" || fieldDef.Domain == "vitesse_rot" || fieldDef.Domain == "dimension" {
-					field["type"] = "number"
-				}
-			}
-
-			if fieldDef.DefaultUnit != "" {
-				field["unit"] = fieldDef.DefaultUnit
+		// Requête HTMX (formulaire web d'ajout): renvoyer directement les contrôles de saisie
+		if r.Header.Get("HX-Request") == "true" {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			if err := tplTemplateFields.ExecuteTemplate(w, "partials_template_fields", fields); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
 			}
-
-			fields = append(fields, field)
+			return
 		}
 
-		writeJSON(w, http.StatusOK, map[string]interface{}{"fields": fields})
+		response := map[string]interface{}{"fields": fields}
+		if template.NamePattern != "" {
+			response["name_pattern"] = template.NamePattern
+		}
+		if len(template.Ancestors) > 0 {
+			response["extends"] = template.Extends
+			response["ancestors"] = template.Ancestors
+		}
+		writeJSON(w, http.StatusOK, response)
 	})
 
 	// Récupération des types de pièces disponibles: /api/part-types
@@ -255,28 +308,145 @@ func cmdServe(db *sql.DB, args []string) error {
 			return
 		}
 
-		types := GetAvailableTypes()
+		types := GetAvailableTypes(requestLang(r))
 		writeJSON(w, http.StatusOK, map[string]interface{}{"types": types})
 	})
 
-	// Recherche: /api/search?type=...&name=...&prop=...
+	// Registre des templates: GET /api/templates (liste), POST /api/templates (création, corps YAML ou JSON)
+	mux.HandleFunc("/api/templates", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			templates := []map[string]interface{}{}
+			for _, name := range Templates.Names() {
+				tmpl, _ := Templates.Get(name)
+				templates = append(templates, templateResponse(tmpl, requestLang(r)))
+			}
+			writeJSON(w, http.StatusOK, map[string]interface{}{"templates": templates})
+		case http.MethodPost:
+			definition, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 1<<20))
+			if err != nil {
+				http.Error(w, "invalid body", http.StatusBadRequest)
+				return
+			}
+			tmpl, err := AddTemplate(db, string(definition))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			writeJSON(w, http.StatusCreated, templateResponse(tmpl, requestLang(r)))
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	// Rechargement à chaud (fichiers du dossier templates modifiés): POST /api/templates/reload
+	// Un template: GET (avec sa définition), PUT (remplacement), DELETE /api/templates/{name}
+	mux.HandleFunc("/api/templates/", func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/api/templates/")
+		if name == "" || strings.Contains(name, "/") {
+			http.NotFound(w, r)
+			return
+		}
+
+		if name == "reload" {
+			if r.Method != http.MethodPost {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			result := map[string]interface{}{"reloaded": true}
+			if err := Templates.Reload(db); err != nil {
+				if !errors.Is(err, ErrTemplatesSkipped) {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+				result["warning"] = err.Error()
+			}
+			result["count"] = Templates.Len()
+			writeJSON(w, http.StatusOK, result)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			tmpl, exists := Templates.Get(name)
+			if !exists {
+				http.Error(w, "template not found", http.StatusNotFound)
+				return
+			}
+			resp := templateResponse(tmpl, requestLang(r))
+			resp["definition"] = Templates.Definition(name)
+			writeJSON(w, http.StatusOK, resp)
+		case http.MethodPut:
+			if _, exists := Templates.Get(name); !exists {
+				http.Error(w, "template not found", http.StatusNotFound)
+				return
+			}
+			definition, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 1<<20))
+			if err != nil {
+				http.Error(w, "invalid body", http.StatusBadRequest)
+				return
+			}
+			tmpl, err := UpdateTemplate(db, name, string(definition))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			writeJSON(w, http.StatusOK, templateResponse(tmpl, requestLang(r)))
+		case http.MethodDelete:
+			if _, exists := Templates.Get(name); !exists {
+				http.Error(w, "template not found", http.StatusNotFound)
+				return
+			}
+			if err := DeleteTemplate(db, name); err != nil {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+			_, restored := Templates.Get(name)
+			writeJSON(w, http.StatusOK, map[string]interface{}{"name": name, "deleted": true, "restored": restored})
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	// Alertes de stock: GET /api/alerts (pièces sous leur seuil, par localisation)
+	mux.HandleFunc("/api/alerts", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		groups, err := ListStockAlerts(db)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, groups)
+	})
+
+	// Recherche: /api/search?type=...&name=...&prop=...&state=...&tag=...
 	mux.HandleFunc("/api/search", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
-		typeName := r.URL.Query().Get("type")
-		nameSearch := r.URL.Query().Get("name")
-		propSearch := r.URL.Query().Get("prop")
+		filters, err := searchFiltersFromQuery(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		display, err := displayUnitsFromQuery(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
-		results, err := searchParts(db, typeName, nameSearch, propSearch)
+		results, err := searchParts(db, filters, display)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 		// Fan-out fédéré si aucun résultat local
 		if len(results) == 0 {
-			fed, _ := fetchFederated(db, httpClient, typeName, nameSearch, propSearch)
+			fed, _ := fetchFederated(db, httpClient, r.URL.Query())
 			results = append(results, fed...)
 		}
 		writeJSON(w, http.StatusOK, results)
@@ -297,10 +467,19 @@ func cmdServe(db *sql.DB, args []string) error {
 			http.Error(w, "unauthorized", http.StatusUnauthorized)
 			return
 		}
-		typeName := r.URL.Query().Get("type")
-		nameSearch := r.URL.Query().Get("name")
-		propSearch := r.URL.Query().Get("prop")
-		results, err := searchParts(db, typeName, nameSearch, propSearch)
+		filters, err := searchFiltersFromQuery(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// Les pairs ne voient que les pièces testées et fonctionnelles
+		filters.State = StateWorking
+		display, err := displayUnitsFromQuery(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		results, err := searchParts(db, filters, display)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
@@ -327,15 +506,52 @@ func cmdServe(db *sql.DB, args []string) error {
 
 		// Récupérer les valeurs du formulaire
 		payload := struct {
-			Type  string
-			Name  string
-			Props map[string]interface{}
-			Loc   string
-		}{}
+			Type     string
+			Name     string
+			Props    map[string]interface{}
+			Loc      string
+			Quantity  int
+			DonorID   int
+			UnitValue *float64
+			Currency  string
+		}{Quantity: 1}
 
 		payload.Type = r.FormValue("type")
 		payload.Name = r.FormValue("name")
 		payload.Loc = r.FormValue("loc")
+		if qtyStr := r.FormValue("quantity"); qtyStr != "" {
+			qty, err := strconv.Atoi(qtyStr)
+			if err != nil || qty < 0 {
+				http.Error(w, "invalid quantity", http.StatusBadRequest)
+				return
+			}
+			payload.Quantity = qty
+		}
+		if donorStr := r.FormValue("donor_id"); donorStr != "" {
+			donorID, err := strconv.Atoi(donorStr)
+			if err != nil {
+				http.Error(w, "invalid donor_id", http.StatusBadRequest)
+				return
+			}
+			if _, err := GetDonor(db, donorID); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			payload.DonorID = donorID
+		}
+		if valueStr := r.FormValue("unit_value"); valueStr != "" {
+			value, err := strconv.ParseFloat(valueStr, 64)
+			if err != nil || value < 0 {
+				http.Error(w, "invalid unit_value", http.StatusBadRequest)
+				return
+			}
+			payload.UnitValue = &value
+		}
+		payload.Currency = r.FormValue("currency")
+		if _, err := NormalizeCurrency(payload.Currency); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
 		// Parser les propriétés JSON
 		propsStr := r.FormValue("props")
@@ -348,11 +564,7 @@ func cmdServe(db *sql.DB, args []string) error {
 			payload.Props = map[string]interface{}{}
 		}
 
-		// Validation de base
-		if payload.Name == "" {
-			http.Error(w, "name is required", http.StatusBadRequest)
-			return
-		}
+		// Validation de base (le nom peut venir du name_pattern du template)
 		if payload.Type != "" && !TypeExists(payload.Type) {
 			http.Error(w, fmt.Sprintf("type '%s' inconnu. Utilisez un template existant", payload.Type), http.StatusBadRequest)
 			return
@@ -365,12 +577,19 @@ func cmdServe(db *sql.DB, args []string) error {
 				return
 			}
 		}
-		fieldUnits := GetFieldUnits(payload.Type)
-		normProps, err := NormalizeProps(payload.Props, fieldUnits)
+		normProps, propUnits, err := NormalizePartPropsWithUnits(payload.Type, payload.Props)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		ComputeProps(payload.Type, normProps)
+		if payload.Name == "" {
+			payload.Name = GeneratePartName(payload.Type, normProps)
+		}
+		if payload.Name == "" {
+			http.Error(w, "name is required", http.StatusBadRequest)
+			return
+		}
 		propsJSON, err := json.Marshal(normProps)
 		if err != nil {
 			http.Error(w, "erreur sérialisation", http.StatusInternalServerError)
@@ -398,7 +617,20 @@ func cmdServe(db *sql.DB, args []string) error {
 		}
 
 		// Créer la pièce
-		id, err := CreatePart(db, payload.Type, payload.Name, string(propsJSON), locationID)
+		part := NewPart{
+			Type:       payload.Type,
+			Name:       payload.Name,
+			PropsJSON:  string(propsJSON),
+			Units:      propUnits,
+			LocationID: locationID,
+			Quantity:   payload.Quantity,
+			UnitValue:  payload.UnitValue,
+			Currency:   payload.Currency,
+		}
+		if payload.DonorID > 0 {
+			part.DonorID = &payload.DonorID
+		}
+		id, err := CreatePartWith(db, part)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -455,12 +687,221 @@ func cmdServe(db *sql.DB, args []string) error {
 		}
 
 		writeJSON(w, http.StatusCreated, map[string]interface{}{
-			"id":   id,
-			"type": payload.Type,
-			"name": payload.Name,
+			"id":       id,
+			"type":     payload.Type,
+			"name":     payload.Name,
+			"quantity": payload.Quantity,
 		})
 	})
 
+	// Opérations sur une pièce: PUT/PATCH/DELETE /api/parts/{id}, /api/parts/{id}/...
+	mux.HandleFunc("/api/parts/", func(w http.ResponseWriter, r *http.Request) {
+		id, sub, ok := parsePartPath(r.URL.Path)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		switch sub {
+		case "":
+			// Suppression réversible: DELETE place la pièce dans la corbeille
+			if r.Method == http.MethodDelete {
+				if err := TrashPart(db, id); err != nil {
+					status := http.StatusNotFound
+					if errors.Is(err, ErrPartLoaned) {
+						status = http.StatusConflict
+					}
+					http.Error(w, err.Error(), status)
+					return
+				}
+				writeJSON(w, http.StatusOK, map[string]interface{}{"id": id, "trashed": true})
+				return
+			}
+			// Modification: PUT remplace les props, PATCH les fusionne (JSON merge patch)
+			if r.Method != http.MethodPut && r.Method != http.MethodPatch {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			var body struct {
+				Type  *string                `json:"type"`
+				Name  *string                `json:"name"`
+				Props map[string]interface{} `json:"props"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				http.Error(w, "invalid JSON body", http.StatusBadRequest)
+				return
+			}
+			upd := PartUpdate{
+				Type:         body.Type,
+				Name:         body.Name,
+				Props:        body.Props,
+				ReplaceProps: r.Method == http.MethodPut && body.Props != nil,
+			}
+			meta, err := EditPart(db, id, upd)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			writeJSON(w, http.StatusOK, partMetaResponse(meta, DisplayUnits{}))
+		case "history":
+			// Historique des modifications: GET /api/parts/{id}/history
+			if r.Method != http.MethodGet {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			entries, err := ListHistory(db, HistoryEntityPart, id)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			resp := []map[string]interface{}{}
+			for _, h := range entries {
+				entry := map[string]interface{}{
+					"id":         h.ID,
+					"action":     h.Action,
+					"actor":      h.Actor,
+					"created_at": h.CreatedAt,
+					"before":     nil,
+					"after":      nil,
+				}
+				if h.Before.Valid {
+					entry["before"] = json.RawMessage(h.Before.String)
+				}
+				if h.After.Valid {
+					entry["after"] = json.RawMessage(h.After.String)
+				}
+				resp = append(resp, entry)
+			}
+			writeJSON(w, http.StatusOK, resp)
+		case "stock":
+			// Mouvement de stock: POST /api/parts/{id}/stock (kind, qty, reason)
+			// GET retourne le registre des mouvements
+			switch r.Method {
+			case http.MethodGet:
+				movements, err := ListStockMovements(db, id)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+				resp := []map[string]interface{}{}
+				for _, m := range movements {
+					resp = append(resp, map[string]interface{}{
+						"id":         m.ID,
+						"kind":       m.Kind,
+						"delta":      m.Delta,
+						"balance":    m.Balance,
+						"reason":     m.Reason,
+						"created_at": m.CreatedAt,
+					})
+				}
+				writeJSON(w, http.StatusOK, resp)
+			case http.MethodPost:
+				balance, err := applyStockForm(db, id, r)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				writeJSON(w, http.StatusOK, map[string]interface{}{"id": id, "quantity": balance})
+			default:
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			}
+		case "children":
+			// Nomenclature: GET /api/parts/{id}/children (arbre des sous-composants)
+			// POST ajoute un enfant (child_id, quantity), DELETE le retire (?child_id=)
+			switch r.Method {
+			case http.MethodGet:
+				tree, err := GetBOMTree(db, id)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusNotFound)
+					return
+				}
+				writeJSON(w, http.StatusOK, tree.Children)
+			case http.MethodPost:
+				childID, err := strconv.Atoi(r.FormValue("child_id"))
+				if err != nil {
+					http.Error(w, "invalid child_id", http.StatusBadRequest)
+					return
+				}
+				qty := 1
+				if qtyStr := r.FormValue("quantity"); qtyStr != "" {
+					if qty, err = strconv.Atoi(qtyStr); err != nil {
+						http.Error(w, "invalid quantity", http.StatusBadRequest)
+						return
+					}
+				}
+				if err := AddComponent(db, id, childID, qty); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				writeJSON(w, http.StatusCreated, map[string]interface{}{"parent_id": id, "child_id": childID, "quantity": qty})
+			case http.MethodDelete:
+				childID, err := strconv.Atoi(r.URL.Query().Get("child_id"))
+				if err != nil {
+					http.Error(w, "invalid child_id", http.StatusBadRequest)
+					return
+				}
+				if err := RemoveComponent(db, id, childID); err != nil {
+					http.Error(w, err.Error(), http.StatusNotFound)
+					return
+				}
+				writeJSON(w, http.StatusOK, map[string]interface{}{"parent_id": id, "child_id": childID, "removed": true})
+			default:
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			}
+		case "state":
+			// Changement d'état: POST /api/parts/{id}/state (state)
+			if r.Method != http.MethodPost {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			if err := SetPartState(db, id, r.FormValue("state")); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			meta, err := GetPartMeta(db, id)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			writeJSON(w, http.StatusOK, partMetaResponse(meta, DisplayUnits{}))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	// partial stock de la page détail (htmx): POST /partials/stock?id={id}
+	mux.HandleFunc("/partials/stock", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		id, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil || id <= 0 {
+			http.Error(w, "invalid part id", http.StatusBadRequest)
+			return
+		}
+		data := struct {
+			*PartMeta
+			Error string
+		}{}
+		if _, err := applyStockForm(db, id, r); err != nil {
+			data.Error = err.Error()
+		}
+		meta, err := GetPartMeta(db, id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !meta.Found {
+			http.NotFound(w, r)
+			return
+		}
+		data.PartMeta = meta
+		if err := tplView.ExecuteTemplate(w, "view_stock", data); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+
 	// Récupération d'une pièce par ID: GET /api/part?id={id}
 	mux.HandleFunc("/api/part", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
@@ -480,6 +921,11 @@ func cmdServe(db *sql.DB, args []string) error {
 			http.Error(w, "invalid part id", http.StatusBadRequest)
 			return
 		}
+		display, err := displayUnitsFromQuery(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
 		part, err := GetPartMeta(db, id)
 		if err != nil {
@@ -488,33 +934,15 @@ func cmdServe(db *sql.DB, args []string) error {
 		}
 
 		if !part.Found {
+			if newID, ok, _ := ResolvePartRedirect(db, id); ok {
+				http.Redirect(w, r, fmt.Sprintf("/api/part?id=%d", newID), http.StatusMovedPermanently)
+				return
+			}
 			http.Error(w, "part not found", http.StatusNotFound)
 			return
 		}
 
-		// Parser les propriétés JSON
-		var props interface{}
-		if part.PropsJSON != "" {
-			if err := json.Unmarshal([]byte(part.PropsJSON), &props); err != nil {
-				log.Printf("Warning: cannot parse props JSON for part %d: %v", id, err)
-				props = map[string]interface{}{}
-			}
-		} else {
-			props = map[string]interface{}{}
-		}
-
-		response := map[string]interface{}{
-			"id":   part.ID,
-			"type": part.Type,
-			"name": part.Name,
-			"props": props,
-		}
-
-		if part.LocationPath != "" {
-			response["location"] = part.LocationPath
-		}
-
-		writeJSON(w, http.StatusOK, response)
+		writeJSON(w, http.StatusOK, partMetaResponse(part, display))
 	})
 
 	// Localisations: GET /api/locations?search=...&id=...&path=...
@@ -673,15 +1101,46 @@ func cmdServe(db *sql.DB, args []string) error {
 	return http.ListenAndServe(addr, enableCORS(mux))
 }
 
-func searchParts(db *sql.DB, typeName, nameSearch, propSearch string) ([]PartAPIResponse, error) {
-	criteria, err := MustCriteriaFromProp(propSearch)
+// searchFiltersFromQuery construit les filtres de recherche depuis les paramètres d'URL
+func searchFiltersFromQuery(q url.Values) (SearchFilters, error) {
+	criteria, err := MustCriteriaFromProp(q.Get("prop"))
+	if err != nil {
+		return SearchFilters{}, err
+	}
+	return SearchFilters{
+		Type:     q.Get("type"),
+		Name:     q.Get("name"),
+		Criteria: criteria,
+		State:    q.Get("state"),
+		Tags:     ParseTagList(strings.Join(q["tag"], ",")),
+
+		IncludeSubtypes: q.Get("subtypes") == "1" || q.Get("subtypes") == "true",
+	}, nil
+}
+
+// displayUnitsFromQuery lit les unités d'affichage: ?units=imperial et ?unit=d_int:cm (répétable)
+func displayUnitsFromQuery(q url.Values) (DisplayUnits, error) {
+	return ParseDisplayUnits(q.Get("units"), q["unit"])
+}
+
+func searchParts(db *sql.DB, filters SearchFilters, display DisplayUnits) ([]PartAPIResponse, error) {
+	parts, err := SearchPartsDB(db, filters)
 	if err != nil {
 		return nil, err
 	}
-	parts, err := SearchPartsDB(db, typeName, nameSearch, criteria)
+	var partIDs []int
+	for _, p := range parts {
+		partIDs = append(partIDs, p.ID)
+	}
+	tagsMap, err := GetTagsForParts(db, partIDs)
 	if err != nil {
 		return nil, err
 	}
+	loaned, err := GetLoanedQuantities(db, partIDs)
+	if err != nil {
+		return nil, err
+	}
+
 	var results []PartAPIResponse
 	for _, p := range parts {
 		var locPath string
@@ -689,23 +1148,33 @@ func searchParts(db *sql.DB, typeName, nameSearch, propSearch string) ([]PartAPI
 			locPath, _ = GetFullPath(db, int(p.LocationID.Int64))
 		}
 		propJSON := json.RawMessage("{}")
+		var displayProps map[string]DisplayValue
 		if p.Props.Valid {
 			propJSON = json.RawMessage(p.Props.String)
+			props := map[string]interface{}{}
+			if err := json.Unmarshal(propJSON, &props); err == nil {
+				displayProps = DisplayProps(p.Type, props, decodePropUnits(p.PropUnits), display)
+			}
 		}
 		results = append(results, PartAPIResponse{
-			ID:       p.ID,
-			Type:     p.Type,
-			Name:     p.Name,
-			Props:    propJSON,
-			Location: locPath,
-			Source:   "local",
+			ID:        p.ID,
+			Type:      p.Type,
+			Name:      p.Name,
+			Props:     propJSON,
+			Quantity:  p.Quantity,
+			Available: p.Quantity - loaned[p.ID],
+			State:     p.State,
+			Tags:      tagsMap[p.ID],
+			Location:  locPath,
+			Source:    "local",
+			Display:   displayProps,
 		})
 	}
 	return results, nil
 }
 
 // fetchFederated interroge les peers avec timeout et agrège les résultats
-func fetchFederated(db *sql.DB, client *http.Client, typeName, nameSearch, propSearch string) ([]PartAPIResponse, error) {
+func fetchFederated(db *sql.DB, client *http.Client, query url.Values) ([]PartAPIResponse, error) {
 	peers, err := ListPeers(db)
 	if err != nil {
 		return nil, err
@@ -722,11 +1191,15 @@ func fetchFederated(db *sql.DB, client *http.Client, typeName, nameSearch, propS
 	for _, peer := range peers {
 		p := peer
 		go func() {
-			url := fmt.Sprintf("%s/api/federated/search?type=%s&name=%s&prop=%s",
+			url := fmt.Sprintf("%s/api/federated/search?type=%s&name=%s&prop=%s&tag=%s&subtypes=%s&units=%s&unit=%s",
 				strings.TrimRight(p.URL, "/"),
-				urlQueryEscape(typeName),
-				urlQueryEscape(nameSearch),
-				urlQueryEscape(propSearch),
+				urlQueryEscape(query.Get("type")),
+				urlQueryEscape(query.Get("name")),
+				urlQueryEscape(query.Get("prop")),
+				urlQueryEscape(strings.Join(query["tag"], ",")),
+				urlQueryEscape(query.Get("subtypes")),
+				urlQueryEscape(query.Get("units")),
+				urlQueryEscape(strings.Join(query["unit"], ",")),
 			)
 			req, err := http.NewRequest(http.MethodGet, url, nil)
 			if err != nil {
@@ -764,6 +1237,110 @@ func fetchFederated(db *sql.DB, client *http.Client, typeName, nameSearch, propS
 	return aggregated, nil
 }
 
+// templateResponse construit la représentation JSON d'un template (schéma aplati des champs)
+func templateResponse(tmpl *Template, lang string) map[string]interface{} {
+	resp := map[string]interface{}{
+		"name":        tmpl.Name,
+		"label":       tmpl.Label(lang),
+		"description": tmpl.LocalizedDescription(lang),
+		"version":     tmpl.version(),
+		"min_stock":   tmpl.MinStock,
+		"source":      Templates.Source(tmpl.Name),
+		"fields":      TemplateFieldSchemas(tmpl, lang),
+	}
+	if len(tmpl.Aliases) > 0 {
+		resp["aliases"] = tmpl.Aliases
+	}
+	if tmpl.NamePattern != "" {
+		resp["name_pattern"] = tmpl.NamePattern
+	}
+	if len(tmpl.Ancestors) > 0 {
+		resp["extends"] = tmpl.Extends
+		resp["ancestors"] = tmpl.Ancestors
+	}
+	return resp
+}
+
+// requestLang retourne la langue demandée (?lang=en, sinon en-tête Accept-Language)
+func requestLang(r *http.Request) string {
+	if lang := r.URL.Query().Get("lang"); lang != "" {
+		return normalizeLang(lang)
+	}
+	return normalizeLang(r.Header.Get("Accept-Language"))
+}
+
+// partMetaResponse construit la réponse JSON détaillée d'une pièce, props converties
+// dans les unités d'affichage demandées (clé display)
+func partMetaResponse(part *PartMeta, display DisplayUnits) map[string]interface{} {
+	// Parser les propriétés JSON
+	var props interface{}
+	if part.PropsJSON != "" {
+		if err := json.Unmarshal([]byte(part.PropsJSON), &props); err != nil {
+			log.Printf("Warning: cannot parse props JSON for part %d: %v", part.ID, err)
+			props = map[string]interface{}{}
+		}
+	} else {
+		props = map[string]interface{}{}
+	}
+
+	response := map[string]interface{}{
+		"id":        part.ID,
+		"type":      part.Type,
+		"name":      part.Name,
+		"props":     props,
+		"quantity":  part.Quantity,
+		"available": part.Available(),
+		"min_stock": EffectiveMinStock(part.Type, part.MinStock),
+		"state":     part.State,
+		"tags":      part.Tags,
+		"loans":     part.Loans,
+	}
+
+	if values, ok := props.(map[string]interface{}); ok && len(values) > 0 {
+		response["display"] = DisplayProps(part.Type, values, part.PropUnits, display)
+	}
+	if len(part.PropUnits) > 0 {
+		response["units"] = part.PropUnits
+	}
+	if part.LocationPath != "" {
+		response["location"] = part.LocationPath
+	}
+	if part.DonorID.Valid {
+		response["donor_id"] = part.DonorID.Int64
+	}
+	if part.UnitValue.Valid {
+		response["unit_value"] = part.UnitValue.Float64
+		response["currency"] = part.Currency
+	}
+
+	return response
+}
+
+// parsePartPath découpe un chemin /api/parts/{id}[/{sub}] en ID et sous-ressource
+func parsePartPath(path string) (int, string, bool) {
+	rest := strings.Trim(strings.TrimPrefix(path, "/api/parts/"), "/")
+	segments := strings.SplitN(rest, "/", 2)
+	id, err := strconv.Atoi(segments[0])
+	if err != nil || id <= 0 {
+		return 0, "", false
+	}
+	sub := ""
+	if len(segments) == 2 {
+		sub = segments[1]
+	}
+	return id, sub, true
+}
+
+// applyStockForm applique un mouvement de stock à partir des champs kind, qty et reason d'un formulaire
+func applyStockForm(db *sql.DB, id int, r *http.Request) (int, error) {
+	kind := r.FormValue("kind")
+	qty, err := strconv.Atoi(r.FormValue("qty"))
+	if err != nil {
+		return 0, fmt.Errorf("quantité invalide")
+	}
+	return RecordStockMovement(db, id, kind, qty, r.FormValue("reason"))
+}
+
 func urlQueryEscape(s string) string {
 	if s == "" {
 		return ""
@@ -783,7 +1360,7 @@ func writeJSON(w http.ResponseWriter, status int, payload interface{}) {
 func enableCORS(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
+		w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, PATCH, DELETE")
 		w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, Authorization")
 		if r.Method == http.MethodOptions {
 			return
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Types de mouvements de stock
+const (
+	MovementIn     = "in"     // Entrée en stock (don, récupération, achat)
+	MovementOut    = "out"    // Sortie de stock (utilisation, vente, don)
+	MovementAdjust = "adjust" // Inventaire: la quantité est fixée à une valeur absolue
+)
+
+// StockMovement représente une ligne du registre des mouvements de stock
+type StockMovement struct {
+	ID        int
+	PartID    int
+	Kind      string
+	Delta     int // Variation appliquée à la quantité
+	Balance   int // Quantité après le mouvement
+	Reason    string
+	CreatedAt string
+}
+
+// IsValidMovementKind indique si un type de mouvement est connu
+func IsValidMovementKind(kind string) bool {
+	switch kind {
+	case MovementIn, MovementOut, MovementAdjust:
+		return true
+	}
+	return false
+}
+
+// RecordStockMovement applique un mouvement sur une pièce et l'enregistre dans le registre.
+// Pour "in"/"out", qty est la quantité entrée/sortie; pour "adjust", qty est la nouvelle quantité.
+// Retourne la quantité résultante.
+func RecordStockMovement(db *sql.DB, partID int, kind string, qty int, reason string) (int, error) {
+	kind = strings.ToLower(kind)
+	if !IsValidMovementKind(kind) {
+		return 0, fmt.Errorf("type de mouvement inconnu: %s (in, out, adjust)", kind)
+	}
+	if qty < 0 {
+		return 0, fmt.Errorf("quantité négative: %d", qty)
+	}
+	if kind != MovementAdjust && qty == 0 {
+		return 0, fmt.Errorf("quantité nulle pour un mouvement '%s'", kind)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var current, loaned int
+	err = tx.QueryRow(`
+		SELECT p.quantity,
+			(SELECT COALESCE(SUM(l.quantity), 0) FROM loans l WHERE l.part_id = p.id AND l.returned_at IS NULL)
+		FROM parts p
+		WHERE p.id = ? AND p.deleted_at IS NULL
+	`, partID).Scan(&current, &loaned)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("pièce ID %d introuvable", partID)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var delta int
+	switch kind {
+	case MovementIn:
+		delta = qty
+	case MovementOut:
+		if qty > current {
+			return 0, fmt.Errorf("stock insuffisant: %d en stock, %d demandé(s)", current, qty)
+		}
+		delta = -qty
+	case MovementAdjust:
+		delta = qty - current
+	}
+	balance := current + delta
+	// Les exemplaires prêtés restent comptés dans le stock jusqu'à leur retour
+	if kind != MovementIn && balance < loaned {
+		return 0, fmt.Errorf("stock insuffisant: %d prêté(s) non rendu(s), le stock ne peut descendre à %d", loaned, balance)
+	}
+
+	before, err := snapshotPart(tx, partID)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec("UPDATE parts SET quantity = ? WHERE id = ?", balance, partID); err != nil {
+		return 0, err
+	}
+	if err := insertStockMovement(tx, partID, kind, delta, balance, reason); err != nil {
+		return 0, err
+	}
+	after, err := snapshotPart(tx, partID)
+	if err != nil {
+		return 0, err
+	}
+	if err := recordHistory(tx, HistoryEntityPart, partID, "stock", before, after); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return balance, nil
+}
+
+// insertStockMovement ajoute une ligne au registre (dans une transaction existante)
+func insertStockMovement(tx *sql.Tx, partID int, kind string, delta, balance int, reason string) error {
+	_, err := tx.Exec(`
+		INSERT INTO stock_movements (part_id, kind, delta, balance, reason)
+		VALUES (?, ?, ?, ?, ?)
+	`, partID, kind, delta, balance, reason)
+	return err
+}
+
+// ListStockMovements retourne l'historique des mouvements d'une pièce (plus récent en premier)
+func ListStockMovements(db *sql.DB, partID int) ([]StockMovement, error) {
+	rows, err := db.Query(`
+		SELECT id, part_id, kind, delta, balance, reason, created_at
+		FROM stock_movements
+		WHERE part_id = ?
+		ORDER BY id DESC
+	`, partID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var movements []StockMovement
+	for rows.Next() {
+		var m StockMovement
+		if err := rows.Scan(&m.ID, &m.PartID, &m.Kind, &m.Delta, &m.Balance, &m.Reason, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		movements = append(movements, m)
+	}
+
+	return movements, nil
+}
+
+// PrintStockMovements affiche le registre des mouvements d'une pièce
+func PrintStockMovements(db *sql.DB, partID int) error {
+	meta, err := GetPartMeta(db, partID)
+	if err != nil {
+		return err
+	}
+	if !meta.Found {
+		return fmt.Errorf("pièce ID %d introuvable", partID)
+	}
+
+	movements, err := ListStockMovements(db, partID)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\n📦 Stock de: %s (ID: %d) — quantité actuelle: %d\n", meta.Name, meta.ID, meta.Quantity)
+	fmt.Println(strings.Repeat("─", 60))
+
+	if len(movements) == 0 {
+		fmt.Println("  Aucun mouvement enregistré")
+		return nil
+	}
+
+	for _, m := range movements {
+		reason := ""
+		if m.Reason != "" {
+			reason = " — " + m.Reason
+		}
+		fmt.Printf("  %s  %-6s %+4d → %d%s\n", m.CreatedAt, m.Kind, m.Delta, m.Balance, reason)
+	}
+
+	fmt.Println()
+	return nil
+}
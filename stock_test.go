@@ -0,0 +1,155 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCreatePartRecordsInitialQuantity(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+
+	id, err := CreatePart(db, "", "Vis M4x12", "{}", nil, 40)
+	if err != nil {
+		t.Fatalf("create part: %v", err)
+	}
+
+	meta, err := GetPartMeta(db, int(id))
+	if err != nil {
+		t.Fatalf("get part: %v", err)
+	}
+	if meta.Quantity != 40 {
+		t.Fatalf("expected quantity 40, got %d", meta.Quantity)
+	}
+
+	movements, err := ListStockMovements(db, int(id))
+	if err != nil {
+		t.Fatalf("list movements: %v", err)
+	}
+	if len(movements) != 1 || movements[0].Kind != MovementIn || movements[0].Delta != 40 {
+		t.Fatalf("expected one initial 'in' movement of 40, got %+v", movements)
+	}
+}
+
+func TestRecordStockMovement(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+
+	id, err := CreatePart(db, "", "Roulement 6204", "{}", nil, 6)
+	if err != nil {
+		t.Fatalf("create part: %v", err)
+	}
+	partID := int(id)
+
+	if qty, err := RecordStockMovement(db, partID, MovementOut, 2, "projet vélo"); err != nil || qty != 4 {
+		t.Fatalf("expected 4 after out, got %d (err=%v)", qty, err)
+	}
+	if qty, err := RecordStockMovement(db, partID, MovementIn, 3, ""); err != nil || qty != 7 {
+		t.Fatalf("expected 7 after in, got %d (err=%v)", qty, err)
+	}
+	if qty, err := RecordStockMovement(db, partID, MovementAdjust, 5, "inventaire"); err != nil || qty != 5 {
+		t.Fatalf("expected 5 after adjust, got %d (err=%v)", qty, err)
+	}
+
+	movements, err := ListStockMovements(db, partID)
+	if err != nil {
+		t.Fatalf("list movements: %v", err)
+	}
+	if len(movements) != 4 {
+		t.Fatalf("expected 4 movements, got %d", len(movements))
+	}
+	if movements[0].Kind != MovementAdjust || movements[0].Delta != -2 || movements[0].Balance != 5 {
+		t.Fatalf("unexpected last movement: %+v", movements[0])
+	}
+}
+
+func TestRecordStockMovementRejectsInvalid(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+
+	id, err := CreatePart(db, "", "Moteur 12V", "{}", nil, 1)
+	if err != nil {
+		t.Fatalf("create part: %v", err)
+	}
+
+	if _, err := RecordStockMovement(db, int(id), MovementOut, 2, ""); err == nil {
+		t.Fatalf("expected error when taking out more than in stock")
+	}
+	if _, err := RecordStockMovement(db, int(id), "steal", 1, ""); err == nil {
+		t.Fatalf("expected error for unknown movement kind")
+	}
+	if _, err := RecordStockMovement(db, 999, MovementIn, 1, ""); err == nil {
+		t.Fatalf("expected error for unknown part")
+	}
+
+	meta, _ := GetPartMeta(db, int(id))
+	if meta.Quantity != 1 {
+		t.Fatalf("quantity should be unchanged after failed movements, got %d", meta.Quantity)
+	}
+}
+
+func TestRecordStockMovementKeepsLoanedUnits(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+
+	id, err := CreatePart(db, "", "Perceuse Bosch", "{}", nil, 3)
+	if err != nil {
+		t.Fatalf("create part: %v", err)
+	}
+	if _, err := CheckoutPart(db, int(id), "Alice", 2, "2999-01-01", ""); err != nil {
+		t.Fatalf("checkout: %v", err)
+	}
+
+	if _, err := RecordStockMovement(db, int(id), MovementOut, 2, ""); err == nil {
+		t.Fatalf("expected error when taking out loaned units")
+	}
+	if _, err := RecordStockMovement(db, int(id), MovementAdjust, 1, "inventaire"); err == nil {
+		t.Fatalf("expected error when adjusting below loaned units")
+	}
+	if qty, err := RecordStockMovement(db, int(id), MovementOut, 1, ""); err != nil || qty != 2 {
+		t.Fatalf("expected 2 after taking out the available unit, got %d (err=%v)", qty, err)
+	}
+}
+
+func TestImportQuantityIsStockEntry(t *testing.T) {
+	seedTemplates()
+	db := newTestDB(t)
+	defer db.Close()
+
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "stock.csv")
+	if err := os.WriteFile(csvPath, []byte("name,d_int,d_ext,width,quantity\n6204,20,47,14,5\n608,8,22,7,\n"), 0644); err != nil {
+		t.Fatalf("write csv: %v", err)
+	}
+	jsonPath := filepath.Join(dir, "stock.json")
+	if err := os.WriteFile(jsonPath, []byte(`[{"name":"6000","d_int":10,"d_ext":26,"width":8,"quantity":3}]`), 0644); err != nil {
+		t.Fatalf("write json: %v", err)
+	}
+	for _, path := range []string{csvPath, jsonPath} {
+		if _, err := ImportFromFile(db, ImportOptions{FilePath: path, TypeName: "bearing", StopOnErr: true}); err != nil {
+			t.Fatalf("import %s: %v", path, err)
+		}
+	}
+
+	for id, want := range map[int]int{1: 5, 2: 1, 3: 3} {
+		meta, _ := GetPartMeta(db, id)
+		if meta.Quantity != want {
+			t.Fatalf("part %d: expected quantity %d, got %d", id, want, meta.Quantity)
+		}
+		if strings.Contains(meta.PropsJSON, "quantity") {
+			t.Fatalf("part %d: quantity must not be a prop: %s", id, meta.PropsJSON)
+		}
+		movements, _ := ListStockMovements(db, id)
+		if len(movements) != 1 || movements[0].Kind != MovementIn || movements[0].Balance != want {
+			t.Fatalf("part %d: expected one 'in' movement to %d, got %+v", id, want, movements)
+		}
+	}
+
+	bad := filepath.Join(dir, "bad.csv")
+	os.WriteFile(bad, []byte("name,d_int,d_ext,width,quantity\n6205,25,52,15,2.5\n"), 0644)
+	if _, err := ImportFromFile(db, ImportOptions{FilePath: bad, TypeName: "bearing", StopOnErr: true}); err == nil {
+		t.Fatalf("expected fractional quantity to be rejected")
+	}
+}
@@ -2,7 +2,10 @@ package main
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
 )
 
 // PartRecord représente une ligne de la table parts avec la localisation optionnelle
@@ -11,7 +14,10 @@ type PartRecord struct {
 	Type       string
 	Name       string
 	Props      sql.NullString
+	PropUnits  sql.NullString // Saisie d'origine et unité des props (voir units.go)
 	LocationID sql.NullInt64
+	Quantity   int
+	State      string
 }
 
 // PartMeta pour affichage et QR
@@ -20,17 +26,35 @@ type PartMeta struct {
 	Type         string
 	Name         string
 	PropsJSON    string
+	PropUnits    map[string]PropUnit // Saisie d'origine et unité de base des props numériques
 	LocationID   sql.NullInt64
 	LocationPath string
+	Quantity     int
+	State        string
+	DonorID      sql.NullInt64
+	MinStock     sql.NullInt64 // Seuil propre à la pièce (sinon celui du template)
+	UnitValue    sql.NullFloat64
+	Currency     string
+	Tags         []string
+	Loans        []Loan // Prêts en cours
 	Found        bool
 }
 
-// GetPartMeta retourne les infos d'une pièce par ID
+// Available retourne la quantité disponible (stock moins les unités prêtées)
+func (p *PartMeta) Available() int {
+	available := p.Quantity
+	for _, l := range p.Loans {
+		available -= l.Quantity
+	}
+	return available
+}
+
+// GetPartMeta retourne les infos d'une pièce par ID (les pièces de la corbeille sont introuvables)
 func GetPartMeta(db *sql.DB, id int) (*PartMeta, error) {
 	var p PartMeta
-	var props sql.NullString
-	err := db.QueryRow(`SELECT id, type, name, props, location_id FROM parts WHERE id = ?`, id).
-		Scan(&p.ID, &p.Type, &p.Name, &props, &p.LocationID)
+	var props, units sql.NullString
+	err := db.QueryRow(`SELECT id, type, name, props, props_units, location_id, quantity, state, donor_id, min_stock, unit_value, COALESCE(currency, '') FROM parts WHERE id = ? AND deleted_at IS NULL`, id).
+		Scan(&p.ID, &p.Type, &p.Name, &props, &units, &p.LocationID, &p.Quantity, &p.State, &p.DonorID, &p.MinStock, &p.UnitValue, &p.Currency)
 	if err == sql.ErrNoRows {
 		return &PartMeta{Found: false}, nil
 	}
@@ -40,64 +64,264 @@ func GetPartMeta(db *sql.DB, id int) (*PartMeta, error) {
 	if props.Valid {
 		p.PropsJSON = props.String
 	}
+	p.PropUnits = decodePropUnits(units)
 	if p.LocationID.Valid {
 		path, _ := GetFullPath(db, int(p.LocationID.Int64))
 		p.LocationPath = path
 	}
+	tags, err := GetPartTags(db, p.ID)
+	if err != nil {
+		return nil, err
+	}
+	p.Tags = tags
+	loans, err := ListLoans(db, p.ID, false, false)
+	if err != nil {
+		return nil, err
+	}
+	p.Loans = loans
 	p.Found = true
 	return &p, nil
 }
 
-// CreatePart insère une pièce et retourne son ID
-func CreatePart(db *sql.DB, typeName, name, propsJSON string, locationID *int) (int64, error) {
-	var res sql.Result
-	var err error
-	if locationID != nil {
-		res, err = db.Exec("INSERT INTO parts (type, name, props, location_id) VALUES (?, ?, ?, ?)",
-			typeName, name, propsJSON, *locationID)
-	} else {
-		res, err = db.Exec("INSERT INTO parts (type, name, props) VALUES (?, ?, ?)",
-			typeName, name, propsJSON)
+// NewPart décrit une pièce à créer (voir CreatePartWith)
+type NewPart struct {
+	Type       string
+	Name       string
+	PropsJSON  string
+	Units      map[string]PropUnit // Unité de base et saisie d'origine des props
+	LocationID *int
+	Quantity   int
+	DonorID    *int     // Appareil donneur d'origine
+	UnitValue  *float64 // Valeur unitaire estimée, dans la devise Currency
+	Currency   string
+}
+
+// CreatePart insère une pièce avec sa quantité initiale et retourne son ID.
+// La quantité initiale est enregistrée comme premier mouvement "in" du registre.
+func CreatePart(db *sql.DB, typeName, name, propsJSON string, locationID *int, quantity int) (int64, error) {
+	return CreatePartWith(db, NewPart{Type: typeName, Name: name, PropsJSON: propsJSON, LocationID: locationID, Quantity: quantity})
+}
+
+// CreatePartWith insère une pièce avec ses unités, son appareil d'origine et sa valeur
+// en une seule transaction: une seule entrée "create" dans l'historique, rien en cas d'erreur.
+func CreatePartWith(db *sql.DB, p NewPart) (int64, error) {
+	if p.Quantity < 0 {
+		return 0, fmt.Errorf("quantité négative: %d", p.Quantity)
+	}
+	var currency interface{}
+	if p.UnitValue != nil {
+		if *p.UnitValue < 0 {
+			return 0, fmt.Errorf("valeur invalide: %g", *p.UnitValue)
+		}
+		code, err := NormalizeCurrency(p.Currency)
+		if err != nil {
+			return 0, err
+		}
+		currency = code
+	}
+	units, err := encodePropUnits(p.Units)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
 	}
+	defer tx.Rollback()
+
+	if p.DonorID != nil {
+		var found int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM donors WHERE id = ?", *p.DonorID).Scan(&found); err != nil {
+			return 0, err
+		}
+		if found == 0 {
+			return 0, fmt.Errorf("appareil donneur ID %d introuvable", *p.DonorID)
+		}
+	}
+
+	res, err := tx.Exec(`
+		INSERT INTO parts (type, name, props, props_units, location_id, quantity, donor_id, unit_value, currency, template_version)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, p.Type, p.Name, p.PropsJSON, units, p.LocationID, p.Quantity, p.DonorID, p.UnitValue, currency, TemplateVersion(p.Type))
 	if err != nil {
 		return 0, err
 	}
 	id, _ := res.LastInsertId()
+
+	after, err := snapshotPart(tx, int(id))
+	if err != nil {
+		return 0, err
+	}
+	if err := recordHistory(tx, HistoryEntityPart, int(id), "create", nil, after); err != nil {
+		return 0, err
+	}
+
+	if p.Quantity > 0 {
+		if err := insertStockMovement(tx, int(id), MovementIn, p.Quantity, p.Quantity, "création"); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
 	return id, nil
 }
 
+// UpdatePart remplace le type, le nom et les propriétés d'une pièce
+func UpdatePart(db *sql.DB, id int, typeName, name, propsJSON string, units map[string]PropUnit) error {
+	unitsJSON, err := encodePropUnits(units)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := snapshotPart(tx, id)
+	if err != nil {
+		return err
+	}
+
+	// Un changement de type reprend les props selon la version courante du nouveau template
+	res, err := tx.Exec(`UPDATE parts SET type = ?, name = ?, props = ?, props_units = ?,
+		template_version = CASE WHEN type = ? THEN template_version ELSE ? END
+		WHERE id = ? AND deleted_at IS NULL`,
+		typeName, name, propsJSON, unitsJSON, typeName, TemplateVersion(typeName), id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("pièce ID %d introuvable", id)
+	}
+
+	after, err := snapshotPart(tx, id)
+	if err != nil {
+		return err
+	}
+	if err := recordHistory(tx, HistoryEntityPart, id, "update", before, after); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SearchFilters regroupe les filtres de recherche (valeur vide = pas de filtre)
+type SearchFilters struct {
+	Type     string
+	Name     string
+	Criteria *SearchCriteria
+	State    string
+	Tags     []string // La pièce doit porter tous ces tags
+
+	IncludeSubtypes bool // Le filtre Type inclut les types qui en héritent (extends)
+}
+
+// searchTypes retourne les types couverts par le filtre Type: le type, ses équivalents
+// par alias (bearing ↔ roulement) et, si demandé, leurs sous-types
+func (f SearchFilters) searchTypes() []string {
+	types := []string{}
+	if f.Type == "" {
+		return types
+	}
+	for _, typeName := range TypeFamily(f.Type) {
+		if f.IncludeSubtypes {
+			types = append(types, Subtypes(typeName)...)
+		} else {
+			types = append(types, typeName)
+		}
+	}
+	return types
+}
+
 // SearchPartsDB exécute la recherche (CLI + API) en réutilisant la même requête
-func SearchPartsDB(db *sql.DB, typeName, nameSearch string, criteria *SearchCriteria) ([]PartRecord, error) {
+func SearchPartsDB(db *sql.DB, f SearchFilters) ([]PartRecord, error) {
 	var propName, propExact string
 	var propMin, propMax float64
 	var isRange bool
 
-	if criteria != nil {
-		propName = criteria.PropName
-		propExact = criteria.ExactVal
-		propMin = criteria.MinVal
-		propMax = criteria.MaxVal
-		isRange = criteria.IsRange
+	if f.Criteria != nil {
+		propName = f.Criteria.PropName
+		propExact = f.Criteria.ExactVal
+		propMin = f.Criteria.MinVal
+		propMax = f.Criteria.MaxVal
+		isRange = f.Criteria.IsRange
+	}
+
+	// Bornes du critère pour les valeurs structurées (plage 12-24V...): la valeur exacte
+	// volts:12 recoupe une plage; NULL si le critère n'est pas numérique
+	var overlapMin, overlapMax interface{}
+	if isRange {
+		overlapMin, overlapMax = propMin, propMax
+	} else if n, err := strconv.ParseFloat(propExact, 64); err == nil {
+		overlapMin, overlapMax = n, n
+	}
+
+	if f.State != "" && !IsValidState(f.State) {
+		return nil, fmt.Errorf("état inconnu: %s (%s)", f.State, strings.Join(ValidStates(), ", "))
+	}
+
+	types := f.searchTypes()
+	typesJSON, err := json.Marshal(types)
+	if err != nil {
+		return nil, err
+	}
+
+	// Le critère de prop porte aussi sur les champs équivalents (largeur ↔ width)
+	propNames := []string{}
+	if propName != "" {
+		propNames = EquivalentFields(types, propName)
+	}
+	propNamesJSON, err := json.Marshal(propNames)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := NormalizeTags(f.Tags)
+	if tags == nil {
+		tags = []string{}
+	}
+	tagsJSON, err := json.Marshal(tags)
+	if err != nil {
+		return nil, err
 	}
 
 	query := `
 		WITH 
 		params AS (
 			SELECT 
-				? AS filter_type,
+				? AS filter_types,
 				? AS filter_name,
-				? AS prop_name,
+				? AS prop_names,
 				? AS prop_exact,
 				? AS prop_min,
 				? AS prop_max,
-				? AS is_range
+				? AS is_range,
+				? AS overlap_min,
+				? AS overlap_max,
+				? AS filter_state,
+				? AS filter_tags
 		),
 		
 		filtered_by_type AS (
 			SELECT p.* 
 			FROM parts p, params
-			WHERE params.filter_type = '' 
-			   OR p.type = params.filter_type
+			WHERE p.deleted_at IS NULL
+			  AND (json_array_length(params.filter_types) = 0
+			       OR p.type IN (SELECT value FROM json_each(params.filter_types)))
+			  AND (params.filter_state = ''
+			       OR p.state = params.filter_state)
+			  AND (json_array_length(params.filter_tags) = 0
+			       OR (SELECT COUNT(*)
+			           FROM part_tags pt
+			           JOIN tags t ON t.id = pt.tag_id
+			           WHERE pt.part_id = p.id
+			             AND t.name IN (SELECT value FROM json_each(params.filter_tags))
+			          ) = json_array_length(params.filter_tags))
 		),
 		
 		filtered_by_name AS (
@@ -110,24 +334,36 @@ func SearchPartsDB(db *sql.DB, typeName, nameSearch string, criteria *SearchCrit
 		filtered_by_prop AS (
 			SELECT f.* 
 			FROM filtered_by_name f, params
-			WHERE params.prop_name = ''
-			   OR (
-			       CASE 
+			WHERE json_array_length(params.prop_names) = 0
+			   OR EXISTS (
+			       SELECT 1 FROM json_each(params.prop_names) pn
+			       WHERE CASE 
+			           WHEN json_type(f.props, '$.' || pn.value) = 'object' THEN
+			               CASE
+			                   WHEN json_type(f.props, '$.' || pn.value || '.values') = 'array' THEN
+			                       EXISTS (
+			                           SELECT 1 FROM json_each(f.props, '$.' || pn.value || '.values') v
+			                           WHERE v.value BETWEEN params.overlap_min AND params.overlap_max
+			                       )
+			                   ELSE
+			                       json_extract(f.props, '$.' || pn.value || '.min') <= params.overlap_max
+			                       AND json_extract(f.props, '$.' || pn.value || '.max') >= params.overlap_min
+			               END
 			           WHEN params.is_range THEN
-			               CAST(json_extract(f.props, '$.' || params.prop_name) AS REAL) 
+			               CAST(json_extract(f.props, '$.' || pn.value) AS REAL) 
 			               BETWEEN params.prop_min AND params.prop_max
 			           ELSE
-			               CAST(json_extract(f.props, '$.' || params.prop_name) AS TEXT) = params.prop_exact
+			               CAST(json_extract(f.props, '$.' || pn.value) AS TEXT) = params.prop_exact
 			       END
 			   )
 		)
 		
-		SELECT id, type, name, props, location_id
+		SELECT id, type, name, props, props_units, location_id, quantity, state
 		FROM filtered_by_prop
 		ORDER BY id
 	`
 
-	rows, err := db.Query(query, typeName, nameSearch, propName, propExact, propMin, propMax, isRange)
+	rows, err := db.Query(query, string(typesJSON), f.Name, string(propNamesJSON), propExact, propMin, propMax, isRange, overlapMin, overlapMax, f.State, string(tagsJSON))
 	if err != nil {
 		return nil, err
 	}
@@ -136,7 +372,7 @@ func SearchPartsDB(db *sql.DB, typeName, nameSearch string, criteria *SearchCrit
 	var parts []PartRecord
 	for rows.Next() {
 		var p PartRecord
-		if err := rows.Scan(&p.ID, &p.Type, &p.Name, &p.Props, &p.LocationID); err != nil {
+		if err := rows.Scan(&p.ID, &p.Type, &p.Name, &p.Props, &p.PropUnits, &p.LocationID, &p.Quantity, &p.State); err != nil {
 			return nil, err
 		}
 		parts = append(parts, p)
@@ -145,9 +381,9 @@ func SearchPartsDB(db *sql.DB, typeName, nameSearch string, criteria *SearchCrit
 	return parts, nil
 }
 
-// ListAllParts retourne toutes les pièces
+// ListAllParts retourne toutes les pièces (hors corbeille)
 func ListAllParts(db *sql.DB) ([]PartRecord, error) {
-	rows, err := db.Query("SELECT id, type, name, props, location_id FROM parts ORDER BY id")
+	rows, err := db.Query("SELECT id, type, name, props, props_units, location_id, quantity, state FROM parts WHERE deleted_at IS NULL ORDER BY id")
 	if err != nil {
 		return nil, err
 	}
@@ -156,7 +392,7 @@ func ListAllParts(db *sql.DB) ([]PartRecord, error) {
 	var parts []PartRecord
 	for rows.Next() {
 		var p PartRecord
-		if err := rows.Scan(&p.ID, &p.Type, &p.Name, &p.Props, &p.LocationID); err != nil {
+		if err := rows.Scan(&p.ID, &p.Type, &p.Name, &p.Props, &p.PropUnits, &p.LocationID, &p.Quantity, &p.State); err != nil {
 			return nil, err
 		}
 		parts = append(parts, p)
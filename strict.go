@@ -0,0 +1,191 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Mode strict d'un template (clé "strict: true"): les champs inconnus sont refusés (avec
+// suggestion du champ le plus proche) et les champs à domaine (dimension, tension...) doivent
+// contenir un nombre, avec une unité de leur domaine. Hérité par les templates enfants.
+
+// LintIssue regroupe les violations de template d'une pièce existante
+type LintIssue struct {
+	PartID   int
+	Type     string
+	Name     string
+	Problems []string
+}
+
+// fieldViolations retourne les violations de contraintes des props d'une pièce, triées par champ
+func fieldViolations(tmpl *Template, props map[string]interface{}, strict bool) []string {
+	var errs []string
+	for _, name := range sortedKeys(props) {
+		def, ok := tmpl.Fields[name]
+		if !ok {
+			if strict {
+				errs = append(errs, unknownFieldError(tmpl, name))
+			}
+			continue
+		}
+		if err := validateField(name, def, props[name]); err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if strict {
+			if err := checkStrictValue(name, def, props[name]); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+	}
+	return errs
+}
+
+// unknownFieldError décrit un champ inconnu, avec le champ le plus proche si c'est une faute de frappe
+func unknownFieldError(tmpl *Template, name string) string {
+	msg := fmt.Sprintf("champ '%s': inconnu pour le type %s", name, tmpl.Name)
+	if suggestion := suggestField(tmpl, name); suggestion != "" {
+		msg += fmt.Sprintf(" (vouliez-vous dire '%s' ?)", suggestion)
+	}
+	return msg
+}
+
+// suggestField retourne le champ du template le plus proche d'un nom inconnu: champ dont c'est
+// un alias (width → largeur), sinon distance d'édition d'au plus 2 (d_itn → d_int)
+func suggestField(tmpl *Template, name string) string {
+	lower := strings.ToLower(name)
+	fields := make([]string, 0, len(tmpl.Fields))
+	for field, def := range tmpl.Fields {
+		fields = append(fields, field)
+		for _, alias := range def.Aliases {
+			if strings.EqualFold(alias, name) {
+				return field
+			}
+		}
+	}
+	sort.Strings(fields)
+
+	best, bestDist := "", 3
+	for _, field := range fields {
+		if dist := levenshtein([]rune(lower), []rune(strings.ToLower(field))); dist < bestDist {
+			best, bestDist = field, dist
+		}
+	}
+	return best
+}
+
+// checkStrictValue vérifie qu'un champ à domaine contient un nombre, dont l'unité éventuelle
+// appartient au domaine du champ ("abc" ou "12mm" sont refusés pour volts)
+func checkStrictValue(name string, def FieldDef, value interface{}) error {
+	if def.Domain == "" || (def.Type != "" && def.Type != FieldNumber && def.Type != FieldInteger) {
+		return nil
+	}
+
+	if _, ok := AsRangeValue(value); ok {
+		return nil
+	}
+
+	switch v := value.(type) {
+	case float64, int:
+		return nil
+	case string:
+		if rv, err := parseRangeValue(v, def.DefaultUnit, UnitDomain(def.Domain)); err != nil {
+			return fmt.Errorf("champ '%s': %v", name, err)
+		} else if rv != nil {
+			return nil
+		}
+		parsed, err := ParseValueWithUnit(v)
+		if err != nil {
+			return fmt.Errorf("champ '%s': valeur numérique attendue (%s), reçu '%s'", name, def.Domain, v)
+		}
+		if !parsed.HasUnit {
+			return nil
+		}
+		if _, err := resolveUnitInDomain(parsed.Unit, UnitDomain(def.Domain)); err != nil {
+			return fmt.Errorf("champ '%s': unité '%s' hors du domaine %s", name, parsed.Unit, def.Domain)
+		}
+		return nil
+	}
+	return fmt.Errorf("champ '%s': valeur numérique attendue (%s), reçu %v", name, def.Domain, value)
+}
+
+// LintParts vérifie les pièces existantes (hors corbeille) d'un type et de ses sous-types
+// (typeName vide: toutes) contre leur template. forceStrict applique les règles du mode strict
+// à tous les templates, pour préparer son activation.
+func LintParts(db *sql.DB, typeName string, forceStrict bool) ([]LintIssue, error) {
+	var parts []PartRecord
+	var err error
+	if typeName != "" {
+		if !TypeExists(typeName) {
+			return nil, fmt.Errorf("type inconnu: %s", typeName)
+		}
+		parts, err = SearchPartsDB(db, SearchFilters{Type: typeName, IncludeSubtypes: true})
+	} else {
+		parts, err = ListAllParts(db)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var issues []LintIssue
+	for _, p := range parts {
+		if problems := lintPart(p, forceStrict); len(problems) > 0 {
+			issues = append(issues, LintIssue{PartID: p.ID, Type: p.Type, Name: p.Name, Problems: problems})
+		}
+	}
+	return issues, nil
+}
+
+// lintPart retourne toutes les violations d'une pièce (type inconnu, champs requis, contraintes)
+func lintPart(p PartRecord, forceStrict bool) []string {
+	if p.Type == "" {
+		return nil
+	}
+	tmpl, ok := Templates.Get(p.Type)
+	if !ok {
+		return []string{fmt.Sprintf("type inconnu: %s", p.Type)}
+	}
+
+	props := make(map[string]interface{})
+	if p.Props.Valid && p.Props.String != "" {
+		if err := json.Unmarshal([]byte(p.Props.String), &props); err != nil {
+			return []string{fmt.Sprintf("props invalides: %v", err)}
+		}
+	}
+
+	var problems []string
+	for _, req := range tmpl.Required {
+		if tmpl.Fields[req].Compute != "" {
+			continue
+		}
+		if _, ok := props[req]; !ok {
+			problems = append(problems, fmt.Sprintf("propriété requise manquante: %s", req))
+		}
+	}
+	return append(problems, fieldViolations(tmpl, props, tmpl.Strict || forceStrict)...)
+}
+
+// PrintLintReport affiche les violations trouvées par LintParts
+func PrintLintReport(issues []LintIssue) {
+	fmt.Println("\n🔎 Vérification des pièces:")
+	fmt.Println(strings.Repeat("─", 60))
+
+	if len(issues) == 0 {
+		fmt.Println("  Aucune violation")
+		fmt.Println()
+		return
+	}
+
+	count := 0
+	for _, issue := range issues {
+		fmt.Printf("  [%d] %s (%s)\n", issue.PartID, issue.Name, issue.Type)
+		for _, problem := range issue.Problems {
+			fmt.Printf("      %s\n", problem)
+			count++
+		}
+	}
+	fmt.Printf("\n%d violation(s) sur %d pièce(s)\n\n", count, len(issues))
+}
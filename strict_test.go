@@ -0,0 +1,107 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// seedStrictTemplates ajoute un template roulement strict (hérité par roulement_etanche) et un moteur libre
+func seedStrictTemplates(t *testing.T) {
+	t.Helper()
+	raw := map[string]*Template{
+		"roulement": {Name: "roulement", Strict: true, Fields: map[string]FieldDef{
+			"d_int":   {Required: true, Domain: "dimension", DefaultUnit: "mm"},
+			"d_ext":   {Required: true, Domain: "dimension", DefaultUnit: "mm"},
+			"largeur": {Domain: "dimension", DefaultUnit: "mm", Aliases: []string{"width"}},
+			"marque":  {},
+		}},
+		"roulement_etanche": {Name: "roulement_etanche", Extends: "roulement", Fields: map[string]FieldDef{
+			"etancheite": {Type: FieldEnum, Values: []string{"2RS", "ZZ"}},
+		}},
+		"moteur": {Name: "moteur", Fields: map[string]FieldDef{
+			"volts": {Required: true, Domain: "tension", DefaultUnit: "V"},
+		}},
+	}
+	setTestTemplates(t, raw)
+}
+
+func TestValidatePropsStrict(t *testing.T) {
+	seedStrictTemplates(t)
+
+	if err := ValidateProps("roulement", map[string]interface{}{"d_int": "20mm", "d_ext": float64(47), "marque": "SKF"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cases := []struct {
+		props map[string]interface{}
+		want  string
+	}{
+		{map[string]interface{}{"d_itn": float64(20)}, "vouliez-vous dire 'd_int'"},
+		{map[string]interface{}{"width": float64(14)}, "vouliez-vous dire 'largeur'"},
+		{map[string]interface{}{"couleur": "bleu"}, "champ 'couleur': inconnu"},
+		{map[string]interface{}{"largeur": "abc"}, "valeur numérique attendue"},
+		{map[string]interface{}{"largeur": "12V"}, "hors du domaine dimension"},
+	}
+	for _, c := range cases {
+		props := map[string]interface{}{"d_int": float64(20), "d_ext": float64(47)}
+		for k, v := range c.props {
+			props[k] = v
+		}
+		err := ValidateProps("roulement", props)
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Fatalf("%v: expected error containing %q, got %v", c.props, c.want, err)
+		}
+	}
+
+	// Le mode strict s'hérite; les templates non stricts restent libres
+	if err := ValidateProps("roulement_etanche", map[string]interface{}{"d_int": float64(20), "d_ext": float64(47), "graisse": "HT"}); err == nil {
+		t.Fatalf("expected strict mode inherited by roulement_etanche")
+	}
+	if err := ValidateProps("moteur", map[string]interface{}{"volts": "abc", "notes": "x"}); err != nil {
+		t.Fatalf("non-strict template should accept free values: %v", err)
+	}
+}
+
+func TestLintParts(t *testing.T) {
+	seedStrictTemplates(t)
+	db := newTestDB(t)
+	defer db.Close()
+
+	// Pièces saisies avant l'activation du mode strict (CreatePart ne valide pas)
+	ok, _ := CreatePart(db, "roulement", "Roulement OK", `{"d_int":20,"d_ext":47}`, nil, 1)
+	bad, _ := CreatePart(db, "roulement_etanche", "Roulement KO", `{"d_itn":20,"d_ext":"abc","etancheite":"XX"}`, nil, 1)
+	moteur, _ := CreatePart(db, "moteur", "Moteur", `{"volts":"abc","notes":"x"}`, nil, 1)
+	CreatePart(db, "inconnu", "Pièce orpheline", `{}`, nil, 1)
+
+	issues, err := LintParts(db, "", false)
+	if err != nil {
+		t.Fatalf("lint: %v", err)
+	}
+	byID := map[int]LintIssue{}
+	for _, issue := range issues {
+		byID[issue.PartID] = issue
+	}
+	if _, found := byID[int(ok)]; found {
+		t.Fatalf("valid part should not be reported: %+v", byID[int(ok)])
+	}
+	if _, found := byID[int(moteur)]; found {
+		t.Fatalf("non-strict part should not be reported without --strict")
+	}
+	problems := strings.Join(byID[int(bad)].Problems, "\n")
+	for _, want := range []string{"requise manquante: d_int", "'d_itn'", "'d_ext': valeur numérique attendue", "'etancheite': valeur 'XX'"} {
+		if !strings.Contains(problems, want) {
+			t.Fatalf("expected %q in problems:\n%s", want, problems)
+		}
+	}
+	if len(issues) != 2 {
+		t.Fatalf("expected 2 parts reported (incl. unknown type), got %+v", issues)
+	}
+
+	issues, err = LintParts(db, "moteur", true)
+	if err != nil {
+		t.Fatalf("lint strict: %v", err)
+	}
+	if len(issues) != 1 || len(issues[0].Problems) != 2 {
+		t.Fatalf("expected forced strict mode to report moteur twice, got %+v", issues)
+	}
+}
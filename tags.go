@@ -0,0 +1,233 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// TagCount représente un tag et le nombre de pièces (hors corbeille) qui le portent
+type TagCount struct {
+	Name  string
+	Count int
+}
+
+// NormalizeTag met un tag sous forme canonique: minuscules, espaces remplacés par des tirets
+func NormalizeTag(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	return strings.Join(strings.Fields(tag), "-")
+}
+
+// NormalizeTags normalise une liste de tags, en ignorant les vides et les doublons
+func NormalizeTags(tags []string) []string {
+	seen := make(map[string]bool)
+	var result []string
+	for _, t := range tags {
+		t = NormalizeTag(t)
+		if t == "" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		result = append(result, t)
+	}
+	return result
+}
+
+// ParseTagList découpe une liste de tags séparés par des virgules (ex: "to-sort,high-value")
+func ParseTagList(s string) []string {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	return NormalizeTags(strings.Split(s, ","))
+}
+
+// AddPartTags ajoute des tags à une pièce (les tags inconnus sont créés)
+func AddPartTags(db *sql.DB, partID int, tags []string) error {
+	tags = NormalizeTags(tags)
+	if len(tags) == 0 {
+		return fmt.Errorf("aucun tag fourni")
+	}
+
+	return updatePartTags(db, partID, "tag_add", func(tx *sql.Tx) error {
+		for _, tag := range tags {
+			if _, err := tx.Exec("INSERT OR IGNORE INTO tags (name) VALUES (?)", tag); err != nil {
+				return err
+			}
+			_, err := tx.Exec(`
+				INSERT OR IGNORE INTO part_tags (part_id, tag_id)
+				SELECT ?, id FROM tags WHERE name = ?
+			`, partID, tag)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// RemovePartTags retire des tags d'une pièce (les tags qui ne sont plus utilisés sont supprimés)
+func RemovePartTags(db *sql.DB, partID int, tags []string) error {
+	tags = NormalizeTags(tags)
+	if len(tags) == 0 {
+		return fmt.Errorf("aucun tag fourni")
+	}
+
+	return updatePartTags(db, partID, "tag_rm", func(tx *sql.Tx) error {
+		for _, tag := range tags {
+			_, err := tx.Exec(`
+				DELETE FROM part_tags
+				WHERE part_id = ? AND tag_id = (SELECT id FROM tags WHERE name = ?)
+			`, partID, tag)
+			if err != nil {
+				return err
+			}
+		}
+		_, err := tx.Exec("DELETE FROM tags WHERE id NOT IN (SELECT tag_id FROM part_tags)")
+		return err
+	})
+}
+
+// updatePartTags applique une modification des tags d'une pièce et l'enregistre dans l'historique
+func updatePartTags(db *sql.DB, partID int, action string, apply func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var count int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM parts WHERE id = ? AND deleted_at IS NULL", partID).Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("pièce ID %d introuvable", partID)
+	}
+
+	before, err := listPartTags(tx, partID)
+	if err != nil {
+		return err
+	}
+	if err := apply(tx); err != nil {
+		return err
+	}
+	after, err := listPartTags(tx, partID)
+	if err != nil {
+		return err
+	}
+
+	if err := recordHistory(tx, HistoryEntityPart, partID, action, before, after); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetPartTags retourne les tags d'une pièce (triés)
+func GetPartTags(db *sql.DB, partID int) ([]string, error) {
+	return listPartTags(db, partID)
+}
+
+func listPartTags(q queryer, partID int) ([]string, error) {
+	tags, err := listTagsForParts(q, []int{partID})
+	if err != nil {
+		return nil, err
+	}
+	if tags[partID] == nil {
+		return []string{}, nil
+	}
+	return tags[partID], nil
+}
+
+// GetTagsForParts récupère les tags de plusieurs pièces (pour l'affichage en lot)
+func GetTagsForParts(db *sql.DB, partIDs []int) (map[int][]string, error) {
+	return listTagsForParts(db, partIDs)
+}
+
+// listTagsForParts lit en une requête les tags (triés) des pièces demandées; les pièces sans tag sont absentes
+func listTagsForParts(q queryer, partIDs []int) (map[int][]string, error) {
+	result := make(map[int][]string)
+	if len(partIDs) == 0 {
+		return result, nil
+	}
+	ids, err := json.Marshal(partIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.Query(`
+		SELECT pt.part_id, t.name
+		FROM part_tags pt
+		JOIN tags t ON t.id = pt.tag_id
+		WHERE pt.part_id IN (SELECT value FROM json_each(?))
+		ORDER BY pt.part_id, t.name
+	`, string(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, err
+		}
+		result[id] = append(result[id], name)
+	}
+
+	return result, rows.Err()
+}
+
+// ListTags retourne tous les tags avec leur nombre de pièces
+func ListTags(db *sql.DB) ([]TagCount, error) {
+	rows, err := db.Query(`
+		SELECT t.name, COUNT(p.id)
+		FROM tags t
+		LEFT JOIN part_tags pt ON pt.tag_id = t.id
+		LEFT JOIN parts p ON p.id = pt.part_id AND p.deleted_at IS NULL
+		GROUP BY t.id
+		ORDER BY t.name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []TagCount
+	for rows.Next() {
+		var t TagCount
+		if err := rows.Scan(&t.Name, &t.Count); err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+
+	return tags, nil
+}
+
+// PrintTags affiche la liste des tags utilisés
+func PrintTags(db *sql.DB) error {
+	tags, err := ListTags(db)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("\n🏷️  Tags:")
+	fmt.Println(strings.Repeat("─", 60))
+
+	if len(tags) == 0 {
+		fmt.Println("  Aucun tag")
+		fmt.Println()
+		return nil
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].Count > tags[j].Count })
+	for _, t := range tags {
+		fmt.Printf("  %-24s %d pièce(s)\n", t.Name, t.Count)
+	}
+
+	fmt.Println()
+	return nil
+}
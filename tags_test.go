@@ -0,0 +1,111 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	got := NormalizeTags([]string{" To Sort ", "high-value", "to-sort", ""})
+	want := []string{"to-sort", "high-value"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("NormalizeTags = %v, want %v", got, want)
+	}
+}
+
+func TestSearchPartsByTag(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+
+	a, _ := CreatePart(db, "moteur", "Moteur essuie-glace", `{"volts":12,"watts":50}`, nil, 1)
+	b, _ := CreatePart(db, "moteur", "Moteur lève-vitre", `{"volts":12,"watts":30}`, nil, 1)
+	c, _ := CreatePart(db, "", "Carton de vis", "{}", nil, 1)
+
+	if err := AddPartTags(db, int(a), []string{"to-sort", "donated-by-fablab"}); err != nil {
+		t.Fatalf("add tags: %v", err)
+	}
+	if err := AddPartTags(db, int(b), []string{"to-sort"}); err != nil {
+		t.Fatalf("add tags: %v", err)
+	}
+	if err := AddPartTags(db, int(c), []string{"To Sort"}); err != nil {
+		t.Fatalf("add tags: %v", err)
+	}
+
+	parts, err := SearchPartsDB(db, SearchFilters{Tags: []string{"to-sort"}})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(parts) != 3 {
+		t.Fatalf("expected 3 parts tagged to-sort, got %d", len(parts))
+	}
+
+	parts, err = SearchPartsDB(db, SearchFilters{Type: "moteur", Tags: []string{"to-sort", "donated-by-fablab"}})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(parts) != 1 || parts[0].ID != int(a) {
+		t.Fatalf("expected only part %d, got %+v", a, parts)
+	}
+
+	if err := RemovePartTags(db, int(a), []string{"donated-by-fablab"}); err != nil {
+		t.Fatalf("remove tag: %v", err)
+	}
+	tags, _ := ListTags(db)
+	if len(tags) != 1 || tags[0].Name != "to-sort" || tags[0].Count != 3 {
+		t.Fatalf("expected unused tag to be dropped, got %+v", tags)
+	}
+}
+
+func TestTagsSurviveBackupRestore(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+
+	id, _ := CreatePart(db, "", "Alimentation 24V", "{}", nil, 1)
+	if err := AddPartTags(db, int(id), []string{"high-value", "to-sort"}); err != nil {
+		t.Fatalf("add tags: %v", err)
+	}
+
+	file := filepath.Join(t.TempDir(), "backup.json")
+	if err := CreateBackup(db, file); err != nil {
+		t.Fatalf("backup: %v", err)
+	}
+	if err := RemovePartTags(db, int(id), []string{"high-value", "to-sort"}); err != nil {
+		t.Fatalf("remove tags: %v", err)
+	}
+	if err := RestoreFromBackup(db, file); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+
+	tags, err := GetPartTags(db, int(id))
+	if err != nil {
+		t.Fatalf("get tags: %v", err)
+	}
+	if !reflect.DeepEqual(tags, []string{"high-value", "to-sort"}) {
+		t.Fatalf("expected tags restored, got %v", tags)
+	}
+}
+
+func TestGetTagsForParts(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+
+	a, _ := CreatePart(db, "", "Alimentation 24V", "{}", nil, 1)
+	b, _ := CreatePart(db, "", "Moteur 12V", "{}", nil, 1)
+	c, _ := CreatePart(db, "", "Vis M3", "{}", nil, 1)
+	if err := AddPartTags(db, int(a), []string{"to-sort", "high-value"}); err != nil {
+		t.Fatalf("add tags: %v", err)
+	}
+	if err := AddPartTags(db, int(b), []string{"to-sort"}); err != nil {
+		t.Fatalf("add tags: %v", err)
+	}
+
+	tags, err := GetTagsForParts(db, []int{int(a), int(b), int(c)})
+	if err != nil {
+		t.Fatalf("get tags: %v", err)
+	}
+	want := map[int][]string{int(a): {"high-value", "to-sort"}, int(b): {"to-sort"}}
+	if !reflect.DeepEqual(tags, want) {
+		t.Fatalf("expected %v, got %v", want, tags)
+	}
+}
@@ -0,0 +1,387 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Opérations de migration des props (clé "op" d'une étape)
+const (
+	MigrationRename  = "rename"  // Renommer le champ field en to
+	MigrationDefault = "default" // Donner la valeur value au champ field s'il est absent
+	MigrationConvert = "convert" // Convertir les valeurs de field, saisies en from, vers l'unité de base du domaine
+	MigrationDrop    = "drop"    // Supprimer le champ field
+)
+
+// TemplateMigration fait passer les props d'une pièce à la version Version du template
+type TemplateMigration struct {
+	Version int             `yaml:"version"`
+	Steps   []MigrationStep `yaml:"steps"`
+}
+
+// MigrationStep est une étape déclarative d'une migration
+type MigrationStep struct {
+	Op    string      `yaml:"op"`
+	Field string      `yaml:"field"`
+	To    string      `yaml:"to"`    // rename: nouveau nom, convert: unité cible (forcément l'unité de base)
+	From  string      `yaml:"from"`  // convert: unité dans laquelle les valeurs ont été saisies
+	Value interface{} `yaml:"value"` // default: valeur à appliquer
+}
+
+// MigratedPart décrit une pièce migrée (ou à migrer en simulation)
+type MigratedPart struct {
+	ID      int
+	Type    string
+	Name    string
+	From    int
+	To      int
+	Changes []string // Modifications des props (vide: seule la version change)
+}
+
+// version retourne la version courante du schéma d'un template
+func (t *Template) version() int {
+	if t.Version > 0 {
+		return t.Version
+	}
+	return 1
+}
+
+// TemplateVersion retourne la version courante du template d'un type (1 si inconnu ou sans version)
+func TemplateVersion(typeName string) int {
+	if tmpl, ok := Templates.Get(typeName); ok {
+		return tmpl.version()
+	}
+	return 1
+}
+
+// checkMigrations vérifie les migrations d'un template (au chargement des templates)
+func checkMigrations(t *Template) error {
+	if t.Version < 0 {
+		return fmt.Errorf("version invalide: %d", t.Version)
+	}
+
+	previous := 1
+	for _, m := range t.Migrations {
+		if m.Version <= previous || m.Version > t.version() {
+			return fmt.Errorf("migration vers la version %d: versions croissantes attendues, entre 2 et %d", m.Version, t.version())
+		}
+		previous = m.Version
+
+		for _, step := range m.Steps {
+			if err := checkMigrationStep(step); err != nil {
+				return fmt.Errorf("migration vers la version %d: %v", m.Version, err)
+			}
+		}
+	}
+	return nil
+}
+
+func checkMigrationStep(step MigrationStep) error {
+	if step.Field == "" {
+		return fmt.Errorf("%s: champ requis (field)", step.Op)
+	}
+	switch step.Op {
+	case MigrationRename:
+		if step.To == "" || step.To == step.Field {
+			return fmt.Errorf("rename %s: nouveau nom requis (to)", step.Field)
+		}
+	case MigrationDefault:
+		if step.Value == nil {
+			return fmt.Errorf("default %s: valeur requise (value)", step.Field)
+		}
+	case MigrationConvert:
+		from, ok := LookupUnit(step.From)
+		if !ok {
+			return fmt.Errorf("convert %s: unité source inconnue '%s' (from)", step.Field, step.From)
+		}
+		if step.To != "" {
+			to, ok := LookupUnit(step.To)
+			if !ok {
+				return fmt.Errorf("convert %s: unité cible inconnue '%s' (to)", step.Field, step.To)
+			}
+			if to.Domain != from.Domain {
+				return fmt.Errorf("convert %s: unités incompatibles (%s → %s)", step.Field, step.From, step.To)
+			}
+			// Les props sont stockées en unité de base: une autre cible fausserait l'affichage
+			if to.ToBaseFactor != 1 {
+				return fmt.Errorf("convert %s: les valeurs sont stockées en %s, pas en %s (to)", step.Field, BaseUnits[from.Domain], step.To)
+			}
+		}
+	case MigrationDrop:
+	default:
+		return fmt.Errorf("opération inconnue '%s' (rename, default, convert, drop)", step.Op)
+	}
+	return nil
+}
+
+// migrateProps applique à des props (et à leurs unités) les migrations d'un template postérieures
+// à la version from. Retourne la description des modifications effectuées.
+func migrateProps(t *Template, props map[string]interface{}, units map[string]PropUnit, from int) ([]string, error) {
+	var changes []string
+	for _, m := range t.Migrations {
+		if m.Version <= from {
+			continue
+		}
+		for _, step := range m.Steps {
+			change, err := applyMigrationStep(t, props, units, step)
+			if err != nil {
+				return nil, err
+			}
+			if change != "" {
+				changes = append(changes, fmt.Sprintf("v%d: %s", m.Version, change))
+			}
+		}
+	}
+	return changes, nil
+}
+
+// applyMigrationStep applique une étape à des props et à leurs unités (description vide: rien à faire)
+func applyMigrationStep(t *Template, props map[string]interface{}, units map[string]PropUnit, step MigrationStep) (string, error) {
+	value, present := props[step.Field]
+
+	switch step.Op {
+	case MigrationRename:
+		if !present {
+			return "", nil
+		}
+		if existing, ok := props[step.To]; ok && existing != nil && existing != "" {
+			return "", fmt.Errorf("rename %s → %s: le champ %s existe déjà (%v)", step.Field, step.To, step.To, existing)
+		}
+		props[step.To] = value
+		delete(props, step.Field)
+		if unit, ok := units[step.Field]; ok {
+			units[step.To] = unit
+			delete(units, step.Field)
+		}
+		return fmt.Sprintf("%s → %s", step.Field, step.To), nil
+
+	case MigrationDefault:
+		if present && value != nil && value != "" {
+			return "", nil
+		}
+		normalized, normalizedUnits, err := NormalizePartPropsWithUnits(t.Name, map[string]interface{}{step.Field: step.Value})
+		if err != nil {
+			return "", fmt.Errorf("default %s: %v", step.Field, err)
+		}
+		props[step.Field] = normalized[step.Field]
+		if unit, ok := normalizedUnits[step.Field]; ok {
+			units[step.Field] = unit
+		} else {
+			delete(units, step.Field)
+		}
+		return fmt.Sprintf("%s = %v (défaut)", step.Field, normalized[step.Field]), nil
+
+	case MigrationConvert:
+		if !present || value == nil || value == "" {
+			return "", nil
+		}
+		from, _ := LookupUnit(step.From)
+		to := BaseUnits[from.Domain]
+		converted, err := convertUnit(value, step.From, to)
+		if err != nil {
+			return "", fmt.Errorf("convert %s: %v", step.Field, err)
+		}
+		props[step.Field] = converted
+		units[step.Field] = PropUnit{Unit: to}
+		return fmt.Sprintf("%s: %v %s → %g %s", step.Field, value, step.From, converted, to), nil
+
+	case MigrationDrop:
+		if !present {
+			return "", nil
+		}
+		delete(props, step.Field)
+		delete(units, step.Field)
+		return fmt.Sprintf("%s supprimé (était: %v)", step.Field, value), nil
+	}
+	return "", fmt.Errorf("opération inconnue '%s'", step.Op)
+}
+
+// convertUnit convertit une valeur saisie dans l'unité from (ou dans l'unité qu'elle précise) vers to
+func convertUnit(value interface{}, from, to string) (float64, error) {
+	var amount float64
+	unit := from
+	switch v := value.(type) {
+	case float64:
+		amount = v
+	case string:
+		parsed, err := ParseValueWithUnit(v)
+		if err != nil {
+			return 0, err
+		}
+		amount = parsed.Value
+		if parsed.HasUnit {
+			unit = parsed.Unit
+		}
+	default:
+		return 0, fmt.Errorf("nombre attendu, reçu %v", value)
+	}
+
+	src, ok := LookupUnit(unit)
+	if !ok {
+		return 0, fmt.Errorf("unité '%s' non reconnue", unit)
+	}
+	dst, ok := LookupUnit(to)
+	if !ok {
+		return 0, fmt.Errorf("unité '%s' non reconnue", to)
+	}
+	if src.Domain != dst.Domain {
+		return 0, fmt.Errorf("unités incompatibles (%s → %s)", unit, to)
+	}
+
+	// Arrondi pour éviter les artefacts flottants (0.1 * 10 = 1.0000000000000002)
+	converted := amount * src.ToBaseFactor / dst.ToBaseFactor
+	return math.Round(converted*1e9) / 1e9, nil
+}
+
+// MigrateTemplates met les props des pièces (corbeille incluse) à la version courante de leur template,
+// dans une seule transaction. typeName vide: tous les types. En simulation (dryRun), rien n'est écrit.
+func MigrateTemplates(db *sql.DB, typeName string, dryRun bool) ([]MigratedPart, error) {
+	var types []string
+	if typeName != "" {
+		if _, ok := Templates.Get(typeName); !ok {
+			return nil, fmt.Errorf("type inconnu: %s", typeName)
+		}
+		types = []string{typeName}
+	} else {
+		types = Templates.Names()
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var migrated []MigratedPart
+	for _, name := range types {
+		tmpl, _ := Templates.Get(name)
+
+		rows, err := tx.Query(`
+			SELECT id, name, props, props_units, template_version
+			FROM parts
+			WHERE type = ? AND template_version < ?
+			ORDER BY id
+		`, name, tmpl.version())
+		if err != nil {
+			return nil, err
+		}
+
+		type pending struct {
+			part  MigratedPart
+			props string
+			units sql.NullString
+		}
+		var parts []pending
+		for rows.Next() {
+			var p pending
+			var props sql.NullString
+			if err := rows.Scan(&p.part.ID, &p.part.Name, &props, &p.units, &p.part.From); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			p.part.Type = name
+			p.part.To = tmpl.version()
+			p.props = props.String
+			parts = append(parts, p)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+
+		for _, p := range parts {
+			props := make(map[string]interface{})
+			if p.props != "" {
+				if err := json.Unmarshal([]byte(p.props), &props); err != nil {
+					return nil, fmt.Errorf("pièce %d: props invalides: %v", p.part.ID, err)
+				}
+			}
+
+			units := decodePropUnits(p.units)
+			changes, err := migrateProps(tmpl, props, units, p.part.From)
+			if err != nil {
+				return nil, fmt.Errorf("pièce %d (%s): %v", p.part.ID, p.part.Name, err)
+			}
+			p.part.Changes = changes
+			migrated = append(migrated, p.part)
+
+			if dryRun {
+				continue
+			}
+			if err := updateMigratedPart(tx, p.part.ID, props, units, p.part.To); err != nil {
+				return nil, fmt.Errorf("pièce %d: %v", p.part.ID, err)
+			}
+		}
+	}
+
+	if dryRun {
+		return migrated, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return migrated, nil
+}
+
+// updateMigratedPart écrit les props migrées (et leurs unités) et la nouvelle version, avec historique
+func updateMigratedPart(tx *sql.Tx, partID int, props map[string]interface{}, units map[string]PropUnit, version int) error {
+	propsJSON, err := json.Marshal(props)
+	if err != nil {
+		return err
+	}
+	unitsJSON, err := encodePropUnits(units)
+	if err != nil {
+		return err
+	}
+
+	before, err := snapshotPart(tx, partID)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE parts SET props = ?, props_units = ?, template_version = ? WHERE id = ?", string(propsJSON), unitsJSON, version, partID); err != nil {
+		return err
+	}
+	after, err := snapshotPart(tx, partID)
+	if err != nil {
+		return err
+	}
+	return recordHistory(tx, HistoryEntityPart, partID, "migrate", before, after)
+}
+
+// PrintMigrationReport affiche les pièces migrées (ou à migrer en simulation)
+func PrintMigrationReport(parts []MigratedPart, dryRun bool) {
+	title := "🔧 Migration des templates"
+	if dryRun {
+		title += " (simulation, aucune écriture)"
+	}
+	fmt.Println("\n" + title + ":")
+	fmt.Println(strings.Repeat("─", 60))
+
+	if len(parts) == 0 {
+		fmt.Println("  Toutes les pièces sont à jour")
+		fmt.Println()
+		return
+	}
+
+	changed := 0
+	for _, p := range parts {
+		fmt.Printf("  [%d] %s (%s v%d → v%d)\n", p.ID, p.Name, p.Type, p.From, p.To)
+		if len(p.Changes) == 0 {
+			fmt.Println("      aucune modification des propriétés")
+			continue
+		}
+		changed++
+		for _, change := range p.Changes {
+			fmt.Printf("      %s\n", change)
+		}
+	}
+
+	verb := "migrée(s)"
+	if dryRun {
+		verb = "à migrer"
+	}
+	fmt.Printf("\n%d pièce(s) %s, dont %d avec des propriétés modifiées\n\n", len(parts), verb, changed)
+}
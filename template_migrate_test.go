@@ -0,0 +1,248 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// bumpBearingTemplate passe le template bearing en version 2 (largeur renommée en width, etc.)
+func bumpBearingTemplate() {
+	bearing, _ := Templates.Get("bearing")
+	bearing.Version = 2
+	bearing.Migrations = []TemplateMigration{{
+		Version: 2,
+		Steps: []MigrationStep{
+			{Op: MigrationRename, Field: "largeur", To: "width"},
+			{Op: MigrationDefault, Field: "brand", Value: "SKF"},
+			{Op: MigrationConvert, Field: "d_ext", From: "in"},
+			{Op: MigrationDrop, Field: "obsolete"},
+		},
+	}}
+}
+
+func partProps(t *testing.T, db *sql.DB, id int) map[string]interface{} {
+	t.Helper()
+	meta, err := GetPartMeta(db, id)
+	if err != nil {
+		t.Fatalf("get part: %v", err)
+	}
+	props := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(meta.PropsJSON), &props); err != nil {
+		t.Fatalf("parse props: %v", err)
+	}
+	return props
+}
+
+func partTemplateVersion(t *testing.T, db *sql.DB, id int) int {
+	t.Helper()
+	var version int
+	if err := db.QueryRow("SELECT template_version FROM parts WHERE id = ?", id).Scan(&version); err != nil {
+		t.Fatalf("read template_version: %v", err)
+	}
+	return version
+}
+
+func TestMigrateTemplatesDryRunThenApply(t *testing.T) {
+	seedTemplates()
+	db := newTestDB(t)
+	defer db.Close()
+
+	id, err := CreatePart(db, "bearing", "Roulement ancien", `{"d_int":20,"d_ext":2,"largeur":14,"obsolete":"x"}`, nil, 1)
+	if err != nil {
+		t.Fatalf("create part: %v", err)
+	}
+	bumpBearingTemplate()
+
+	parts, err := MigrateTemplates(db, "", true)
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if len(parts) != 1 || parts[0].From != 1 || parts[0].To != 2 || len(parts[0].Changes) != 4 {
+		t.Fatalf("unexpected dry-run report: %+v", parts)
+	}
+	if props := partProps(t, db, int(id)); props["largeur"] != float64(14) || partTemplateVersion(t, db, int(id)) != 1 {
+		t.Fatalf("dry run should not write: %v", props)
+	}
+
+	if _, err := MigrateTemplates(db, "", false); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	props := partProps(t, db, int(id))
+	if props["width"] != float64(14) || props["largeur"] != nil {
+		t.Fatalf("expected largeur renamed to width: %v", props)
+	}
+	if props["brand"] != "SKF" {
+		t.Fatalf("expected default brand: %v", props)
+	}
+	if props["d_ext"] != 50.8 {
+		t.Fatalf("expected d_ext converted from inches, got %v", props["d_ext"])
+	}
+	if _, ok := props["obsolete"]; ok {
+		t.Fatalf("expected obsolete dropped: %v", props)
+	}
+	if v := partTemplateVersion(t, db, int(id)); v != 2 {
+		t.Fatalf("expected template_version 2, got %d", v)
+	}
+
+	entries, err := ListHistory(db, HistoryEntityPart, int(id))
+	if err != nil {
+		t.Fatalf("list history: %v", err)
+	}
+	if entries[0].Action != "migrate" {
+		t.Fatalf("expected migrate history entry, got %s", entries[0].Action)
+	}
+
+	// Déjà à jour: plus rien à migrer
+	parts, err = MigrateTemplates(db, "", false)
+	if err != nil {
+		t.Fatalf("second migrate: %v", err)
+	}
+	if len(parts) != 0 {
+		t.Fatalf("expected nothing left to migrate, got %+v", parts)
+	}
+}
+
+func TestMigrateTemplatesSkipsPartsCreatedAtCurrentVersion(t *testing.T) {
+	seedTemplates()
+	db := newTestDB(t)
+	defer db.Close()
+
+	bumpBearingTemplate()
+	id, err := CreatePart(db, "bearing", "Roulement récent", `{"d_int":20,"d_ext":47,"width":14}`, nil, 1)
+	if err != nil {
+		t.Fatalf("create part: %v", err)
+	}
+	if v := partTemplateVersion(t, db, int(id)); v != 2 {
+		t.Fatalf("expected new part at version 2, got %d", v)
+	}
+
+	parts, err := MigrateTemplates(db, "bearing", false)
+	if err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if len(parts) != 0 {
+		t.Fatalf("expected no migration for a current part, got %+v", parts)
+	}
+	if props := partProps(t, db, int(id)); props["d_ext"] != float64(47) {
+		t.Fatalf("d_ext should not be converted again: %v", props)
+	}
+}
+
+func TestMigrateTemplatesRollsBackOnConflict(t *testing.T) {
+	seedTemplates()
+	db := newTestDB(t)
+	defer db.Close()
+
+	ok, err := CreatePart(db, "bearing", "A", `{"d_int":20,"d_ext":2,"largeur":14}`, nil, 1)
+	if err != nil {
+		t.Fatalf("create part: %v", err)
+	}
+	if _, err := CreatePart(db, "bearing", "B", `{"d_int":20,"d_ext":2,"largeur":14,"width":15}`, nil, 1); err != nil {
+		t.Fatalf("create part: %v", err)
+	}
+	bumpBearingTemplate()
+
+	_, err = MigrateTemplates(db, "", false)
+	if err == nil || !strings.Contains(err.Error(), "existe déjà") {
+		t.Fatalf("expected rename conflict, got %v", err)
+	}
+	if props := partProps(t, db, int(ok)); props["largeur"] != float64(14) || partTemplateVersion(t, db, int(ok)) != 1 {
+		t.Fatalf("expected whole migration rolled back: %v", props)
+	}
+}
+
+func TestResolveTemplatesRejectsInvalidMigrations(t *testing.T) {
+	cases := map[string]*Template{
+		"version hors plage": {Version: 2, Migrations: []TemplateMigration{{Version: 3}}},
+		"opération inconnue": {Version: 2, Migrations: []TemplateMigration{{Version: 2, Steps: []MigrationStep{{Op: "split", Field: "a"}}}}},
+		"rename sans cible":  {Version: 2, Migrations: []TemplateMigration{{Version: 2, Steps: []MigrationStep{{Op: MigrationRename, Field: "a"}}}}},
+		"unités incompatibles": {Version: 2, Migrations: []TemplateMigration{{Version: 2, Steps: []MigrationStep{
+			{Op: MigrationConvert, Field: "a", From: "in", To: "V"},
+		}}}},
+	}
+	for label, tmpl := range cases {
+		tmpl.Name = "t"
+		if _, err := ResolveTemplates(map[string]*Template{"t": tmpl}); err == nil {
+			t.Fatalf("%s: expected error", label)
+		}
+	}
+}
+
+func TestMigrateConvertStoresBaseUnit(t *testing.T) {
+	seedTemplates()
+	Templates.Register(&Template{
+		Name:   "condensateur",
+		Fields: map[string]FieldDef{"capacite": {Domain: "capacite", DefaultUnit: "nF"}},
+	})
+	db := newTestDB(t)
+	defer db.Close()
+
+	id, err := CreatePart(db, "condensateur", "C1", `{"capacite":100}`, nil, 1)
+	if err != nil {
+		t.Fatalf("create part: %v", err)
+	}
+	// Valeurs saisies en nF: stockées en uF (unité de base), pas dans l'unité par défaut du champ
+	tmpl := mustTemplate(t, "condensateur")
+	tmpl.Version = 2
+	tmpl.Migrations = []TemplateMigration{{Version: 2, Steps: []MigrationStep{{Op: MigrationConvert, Field: "capacite", From: "nF"}}}}
+
+	if _, err := MigrateTemplates(db, "condensateur", false); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if props := partProps(t, db, int(id)); props["capacite"] != 0.1 {
+		t.Fatalf("expected 0.1 uF, got %v", props["capacite"])
+	}
+
+	notBase := &Template{Name: "t", Version: 2, Migrations: []TemplateMigration{{Version: 2, Steps: []MigrationStep{
+		{Op: MigrationConvert, Field: "capacite", From: "pF", To: "nF"},
+	}}}}
+	if _, err := ResolveTemplates(map[string]*Template{"t": notBase}); err == nil {
+		t.Fatalf("expected error for a target that is not the base unit")
+	}
+}
+
+func TestMigrateTemplatesUpdatesPropUnits(t *testing.T) {
+	seedTemplates()
+	db := newTestDB(t)
+	defer db.Close()
+
+	id, err := CreatePart(db, "bearing", "Roulement ancien", `{"d_int":20,"d_ext":2,"largeur":14}`, nil, 1)
+	if err != nil {
+		t.Fatalf("create part: %v", err)
+	}
+	empty, err := CreatePart(db, "bearing", "Sans unités", `{"obsolete":5}`, nil, 1)
+	if err != nil {
+		t.Fatalf("create part: %v", err)
+	}
+	if _, err := db.Exec("UPDATE parts SET props_units = ? WHERE id = ?", `{"largeur":{"original":"14 mm","unit":"mm"},"d_ext":{"original":"2","unit":"mm"}}`, id); err != nil {
+		t.Fatalf("set units: %v", err)
+	}
+	if _, err := db.Exec("UPDATE parts SET props_units = ? WHERE id = ?", `{"obsolete":{"unit":"mm"}}`, empty); err != nil {
+		t.Fatalf("set units: %v", err)
+	}
+	bumpBearingTemplate()
+
+	if _, err := MigrateTemplates(db, "", false); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	meta, err := GetPartMeta(db, int(id))
+	if err != nil {
+		t.Fatalf("get part: %v", err)
+	}
+	if _, ok := meta.PropUnits["largeur"]; ok || meta.PropUnits["width"] != (PropUnit{Original: "14 mm", Unit: "mm"}) {
+		t.Fatalf("expected largeur unit moved to width: %+v", meta.PropUnits)
+	}
+	if meta.PropUnits["d_ext"] != (PropUnit{Unit: "mm"}) {
+		t.Fatalf("expected converted d_ext in base unit: %+v", meta.PropUnits["d_ext"])
+	}
+
+	var units sql.NullString
+	if err := db.QueryRow("SELECT props_units FROM parts WHERE id = ?", empty).Scan(&units); err != nil {
+		t.Fatalf("read props_units: %v", err)
+	}
+	if units.Valid {
+		t.Fatalf("expected NULL props_units once empty, got %q", units.String)
+	}
+}
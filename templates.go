@@ -1,12 +1,10 @@
 package main
 
 import (
+	"database/sql"
 	"fmt"
-	"os"
-	"path/filepath"
+	"sort"
 	"strings"
-
-	"gopkg.in/yaml.v3"
 )
 
 const templatesDir = "templates"
@@ -14,49 +12,116 @@ const strictTypes = true // refuser la création de catégories inconnues
 
 // FieldDef définit les métadonnées d'un champ
 type FieldDef struct {
-	Description string `yaml:"description"`
-	Required    bool   `yaml:"required"`
-	Domain      string `yaml:"domain"`       // dimension, tension, courant, etc.
-	DefaultUnit string `yaml:"default_unit"` // mm, V, A, etc.
+	Description string   `yaml:"description"`
+	Required    bool     `yaml:"required"`
+	Type        string   `yaml:"type"`   // text, number, integer, boolean, enum, list (voir fields.go)
+	Values      []string `yaml:"values"` // Valeurs autorisées (enum, éléments d'une list)
+	Min         *float64 `yaml:"min"`    // Bornes numériques, dans l'unité de base du domaine
+	Max         *float64 `yaml:"max"`
+	Pattern     string   `yaml:"pattern"`      // Expression régulière (text, éléments d'une list)
+	Domain      string   `yaml:"domain"`       // dimension, tension, courant, etc.
+	DefaultUnit string   `yaml:"default_unit"` // mm, V, A, etc.
+	Compute     string   `yaml:"compute"`      // Expression d'un champ calculé (voir expr.go)
+	Override    bool     `yaml:"override"`     // Champ calculé: une valeur saisie remplace le calcul
+
+	Aliases      []string          `yaml:"aliases"`      // Noms équivalents dans d'autres templates (largeur ↔ width)
+	Labels       map[string]string `yaml:"labels"`       // Libellé par langue (en: Width)
+	Descriptions map[string]string `yaml:"descriptions"` // Description par langue
 }
 
 // Template représente un archétype de pièce
 type Template struct {
 	Name        string              `yaml:"name"`
 	Description string              `yaml:"description"`
+	Extends     string              `yaml:"extends"` // Template parent dont les champs sont hérités
+	Aliases     []string            `yaml:"aliases"` // Types équivalents, ex: bearing pour roulement (voir i18n.go)
 	Fields      map[string]FieldDef `yaml:"fields"`
+	Strict      bool                `yaml:"strict"`       // Refuser les champs inconnus et les valeurs non numériques (voir strict.go)
+	MinStock    int                 `yaml:"min_stock"`    // Seuil d'alerte par défaut (0: aucun)
+	NamePattern string              `yaml:"name_pattern"` // Nom automatique, ex: "{marque} {reference}" (voir naming.go)
+	Version     int                 `yaml:"version"`      // Version du schéma (absente: 1)
+	Migrations  []TemplateMigration `yaml:"migrations"`   // Migrations des props vers chaque version (voir template_migrate.go)
+
+	Labels       map[string]string `yaml:"labels"`       // Libellé du type par langue
+	Descriptions map[string]string `yaml:"descriptions"` // Description par langue
 
 	// Champs calculés pour rétrocompatibilité
 	Required []string `yaml:"-"`
 	Optional []string `yaml:"-"`
+
+	// Calculés à la résolution de l'héritage
+	Ancestors []string          `yaml:"-"` // Chaîne des parents, du plus proche à la racine
+	FieldFrom map[string]string `yaml:"-"` // Template qui définit chaque champ
+	computed  []computedField   // Champs calculés, dans l'ordre d'évaluation
 }
 
-// Templates stocke tous les templates chargés
-var Templates = make(map[string]*Template)
+// LoadTemplates charge les templates (embarqués, dossier templates, base) dans le registre
+func LoadTemplates(db *sql.DB) error {
+	return Templates.Reload(db)
+}
 
-// LoadTemplates charge tous les fichiers YAML du dossier templates
-func LoadTemplates() error {
-	entries, err := os.ReadDir(templatesDir)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil // Pas de templates, c'est OK
+// ResolveTemplates résout l'héritage (extends) de templates bruts: chaque template reçoit
+// les champs de ses parents (un champ redéfini remplace celui du parent), puis ses listes
+// Required et Optional sont recalculées. Les parents inconnus et les cycles sont refusés.
+func ResolveTemplates(raw map[string]*Template) (map[string]*Template, error) {
+	resolved := make(map[string]*Template, len(raw))
+
+	var resolve func(name string, chain []string) (*Template, error)
+	resolve = func(name string, chain []string) (*Template, error) {
+		if tmpl, ok := resolved[name]; ok {
+			return tmpl, nil
+		}
+		for _, seen := range chain {
+			if seen == name {
+				return nil, fmt.Errorf("cycle d'héritage entre templates: %s", strings.Join(append(chain, name), " → "))
+			}
 		}
-		return err
-	}
 
-	for _, entry := range entries {
-		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".yaml") {
-			continue
+		src := raw[name]
+		tmpl := &Template{
+			Name:         src.Name,
+			Description:  src.Description,
+			Extends:      src.Extends,
+			Aliases:      src.Aliases,
+			Strict:       src.Strict,
+			MinStock:     src.MinStock,
+			NamePattern:  src.NamePattern,
+			Version:      src.Version,
+			Migrations:   src.Migrations,
+			Fields:       make(map[string]FieldDef),
+			FieldFrom:    make(map[string]string),
+			Labels:       src.Labels,
+			Descriptions: src.Descriptions,
 		}
 
-		data, err := os.ReadFile(filepath.Join(templatesDir, entry.Name()))
-		if err != nil {
-			return err
+		if src.Extends != "" {
+			if _, ok := raw[src.Extends]; !ok {
+				return nil, fmt.Errorf("template %s: parent inconnu '%s'", name, src.Extends)
+			}
+			parent, err := resolve(src.Extends, append(chain, name))
+			if err != nil {
+				return nil, err
+			}
+			for fieldName, fieldDef := range parent.Fields {
+				tmpl.Fields[fieldName] = fieldDef
+				tmpl.FieldFrom[fieldName] = parent.FieldFrom[fieldName]
+			}
+			tmpl.Ancestors = append([]string{parent.Name}, parent.Ancestors...)
+			if tmpl.MinStock == 0 {
+				tmpl.MinStock = parent.MinStock
+			}
+			if tmpl.NamePattern == "" {
+				tmpl.NamePattern = parent.NamePattern
+			}
+			tmpl.Strict = tmpl.Strict || parent.Strict
 		}
 
-		var tmpl Template
-		if err := yaml.Unmarshal(data, &tmpl); err != nil {
-			return fmt.Errorf("erreur parsing %s: %v", entry.Name(), err)
+		for fieldName, fieldDef := range src.Fields {
+			if err := checkFieldDef(fieldName, fieldDef); err != nil {
+				return nil, fmt.Errorf("template %s: %v", name, err)
+			}
+			tmpl.Fields[fieldName] = fieldDef
+			tmpl.FieldFrom[fieldName] = name
 		}
 
 		// Construire les listes Required et Optional à partir de Fields
@@ -67,22 +132,61 @@ func LoadTemplates() error {
 				tmpl.Optional = append(tmpl.Optional, fieldName)
 			}
 		}
+		sort.Strings(tmpl.Required)
+		sort.Strings(tmpl.Optional)
 
-		Templates[tmpl.Name] = &tmpl
+		if err := compileComputedFields(tmpl); err != nil {
+			return nil, fmt.Errorf("template %s: %v", name, err)
+		}
+		if err := checkNamePattern(tmpl); err != nil {
+			return nil, fmt.Errorf("template %s: %v", name, err)
+		}
+		if err := checkAliases(tmpl); err != nil {
+			return nil, fmt.Errorf("template %s: %v", name, err)
+		}
+
+		// Les migrations ne s'héritent pas: chaque template versionne son propre schéma
+		if err := checkMigrations(tmpl); err != nil {
+			return nil, fmt.Errorf("template %s: %v", name, err)
+		}
+
+		resolved[name] = tmpl
+		return tmpl, nil
 	}
 
-	return nil
+	for name := range raw {
+		if _, err := resolve(name, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return resolved, nil
+}
+
+// Subtypes retourne un type et tous les types qui en héritent (directement ou non)
+func Subtypes(typeName string) []string {
+	types := []string{typeName}
+	for _, name := range Templates.Names() {
+		tmpl, _ := Templates.Get(name)
+		for _, ancestor := range tmpl.Ancestors {
+			if ancestor == typeName {
+				types = append(types, name)
+				break
+			}
+		}
+	}
+	return types
 }
 
 // TypeExists indique si un type est connu (présent dans les templates)
 func TypeExists(typeName string) bool {
-	_, exists := Templates[typeName]
+	_, exists := Templates.Get(typeName)
 	return exists
 }
 
 // ValidateProps vérifie que les propriétés respectent le template et que le type est connu
 func ValidateProps(typeName string, props map[string]interface{}) error {
-	tmpl, exists := Templates[typeName]
+	tmpl, exists := Templates.Get(typeName)
 	if !exists {
 		if strictTypes {
 			return fmt.Errorf("type inconnu: %s (ajoutez un template ou désactivez le mode strict)", typeName)
@@ -90,18 +194,44 @@ func ValidateProps(typeName string, props map[string]interface{}) error {
 		return nil // Type libre si le mode strict est désactivé
 	}
 
+	// Une désignation de filetage (M6x1x25) renseigne aussi pas et longueur
+	props, err := expandThreadDesignations(props)
+	if err != nil {
+		return err
+	}
+
+	var errs []string
 	for _, req := range tmpl.Required {
+		if tmpl.Fields[req].Compute != "" {
+			continue // Renseigné par ComputeProps après normalisation
+		}
 		if _, ok := props[req]; !ok {
-			return fmt.Errorf("propriété requise manquante: %s (type %s)", req, typeName)
+			errs = append(errs, fmt.Sprintf("propriété requise manquante: %s (type %s)", req, typeName))
 		}
 	}
 
+	// Contraintes par champ (type, valeurs, bornes, format, mode strict): toutes les erreurs sont rapportées,
+	// pour qu'un champ requis manquant apparaisse avec la faute de frappe qui l'explique (d_itn)
+	errs = append(errs, fieldViolations(tmpl, props, tmpl.Strict)...)
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+
 	return nil
 }
 
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // GetFieldUnits retourne un map des unités par défaut pour chaque champ d'un template
 func GetFieldUnits(typeName string) map[string]string {
-	tmpl, exists := Templates[typeName]
+	tmpl, exists := Templates.Get(typeName)
 	if !exists {
 		return nil
 	}
@@ -116,9 +246,26 @@ func GetFieldUnits(typeName string) map[string]string {
 	return units
 }
 
+// GetFieldDomains retourne un map des domaines (dimension, tension...) des champs d'un template
+func GetFieldDomains(typeName string) map[string]UnitDomain {
+	tmpl, exists := Templates.Get(typeName)
+	if !exists {
+		return nil
+	}
+
+	domains := make(map[string]UnitDomain)
+	for fieldName := range tmpl.Fields {
+		if domain := GetFieldDomain(typeName, fieldName); domain != DomainNone {
+			domains[fieldName] = domain
+		}
+	}
+
+	return domains
+}
+
 // GetFieldDomain retourne le domaine d'un champ pour un template donné
 func GetFieldDomain(typeName, fieldName string) UnitDomain {
-	tmpl, exists := Templates[typeName]
+	tmpl, exists := Templates.Get(typeName)
 	if !exists {
 		return DomainNone
 	}
@@ -145,6 +292,8 @@ func GetFieldDomain(typeName, fieldName string) UnitDomain {
 		return DomainVitesseRot
 	case "puissance":
 		return DomainPuissance
+	case "frequence":
+		return DomainFrequence
 	default:
 		return DomainNone
 	}
@@ -157,17 +306,19 @@ type PartTypeInfo struct {
 	Description string `json:"description,omitempty"`
 }
 
-// GetAvailableTypes retourne la liste de tous les types de pièces disponibles
-func GetAvailableTypes() []PartTypeInfo {
+// GetAvailableTypes retourne la liste de tous les types de pièces disponibles,
+// avec libellés et descriptions dans la langue demandée
+func GetAvailableTypes(lang string) []PartTypeInfo {
 	types := []PartTypeInfo{
 		{Value: "", Label: "Autre"},
 	}
 
-	for name, template := range Templates {
+	for _, name := range Templates.Names() {
+		template, _ := Templates.Get(name)
 		types = append(types, PartTypeInfo{
 			Value:       name,
-			Label:       strings.Title(name), // Capitalise la première lettre
-			Description: template.Description,
+			Label:       template.Label(lang),
+			Description: template.LocalizedDescription(lang),
 		})
 	}
 
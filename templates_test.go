@@ -1,11 +1,15 @@
 package main
 
-import "testing"
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
 
 // helper to seed the in-memory templates map for tests
 func seedTemplates() {
-	Templates = make(map[string]*Template)
-	Templates["bearing"] = &Template{
+	Templates.Set(map[string]*Template{})
+	Templates.Register(&Template{
 		Name:        "bearing",
 		Description: "Roulement",
 		Fields: map[string]FieldDef{
@@ -17,7 +21,37 @@ func seedTemplates() {
 		},
 		Required: []string{"d_int", "d_ext", "width"},
 		Optional: []string{"brand", "type"},
+	})
+}
+
+// setTestTemplates résout des templates bruts et les installe à la place du registre,
+// rétabli à la fin du test
+func setTestTemplates(t *testing.T, raw map[string]*Template) {
+	t.Helper()
+	resolved, err := ResolveTemplates(raw)
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+
+	Templates.mu.RLock()
+	templates, sources, definitions := Templates.templates, Templates.sources, Templates.definitions
+	Templates.mu.RUnlock()
+	t.Cleanup(func() {
+		Templates.mu.Lock()
+		defer Templates.mu.Unlock()
+		Templates.templates, Templates.sources, Templates.definitions = templates, sources, definitions
+	})
+
+	Templates.Set(resolved)
+}
+
+func mustTemplate(t *testing.T, name string) *Template {
+	t.Helper()
+	tmpl, ok := Templates.Get(name)
+	if !ok {
+		t.Fatalf("template %s not loaded", name)
 	}
+	return tmpl
 }
 
 func TestTypeExists(t *testing.T) {
@@ -73,3 +107,69 @@ func TestGetFieldUnits(t *testing.T) {
 		t.Fatalf("expected no unit for non dimensional field")
 	}
 }
+
+func TestResolveTemplatesExtends(t *testing.T) {
+	raw := map[string]*Template{
+		"roulement": {
+			Name: "roulement",
+			Fields: map[string]FieldDef{
+				"d_int":  {Required: true, DefaultUnit: "mm"},
+				"marque": {Description: "Marque"},
+			},
+			MinStock: 4,
+		},
+		"roulement_etanche": {
+			Name:    "roulement_etanche",
+			Extends: "roulement",
+			Fields: map[string]FieldDef{
+				"etancheite": {Required: true},
+				"marque":     {Description: "Marque du joint", Required: true},
+			},
+		},
+	}
+
+	resolved, err := ResolveTemplates(raw)
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	child := resolved["roulement_etanche"]
+	if !reflect.DeepEqual(child.Required, []string{"d_int", "etancheite", "marque"}) || len(child.Optional) != 0 {
+		t.Fatalf("unexpected merged fields: required=%v optional=%v", child.Required, child.Optional)
+	}
+	if child.FieldFrom["d_int"] != "roulement" || child.FieldFrom["marque"] != "roulement_etanche" {
+		t.Fatalf("unexpected field origins: %v", child.FieldFrom)
+	}
+	if child.MinStock != 4 || !reflect.DeepEqual(child.Ancestors, []string{"roulement"}) {
+		t.Fatalf("expected min_stock and ancestors inherited, got %d %v", child.MinStock, child.Ancestors)
+	}
+
+	raw["roulement"].Extends = "roulement_etanche"
+	if _, err := ResolveTemplates(raw); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Fatalf("expected cycle error, got %v", err)
+	}
+
+	raw["roulement"].Extends = "inconnu"
+	if _, err := ResolveTemplates(raw); err == nil {
+		t.Fatalf("expected unknown parent error")
+	}
+}
+
+func TestSearchIncludesSubtypes(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+
+	seedTemplates()
+	Templates.Register(&Template{Name: "bearing_sealed", Extends: "bearing", Ancestors: []string{"bearing"}})
+
+	CreatePart(db, "bearing", "6204", "{}", nil, 1)
+	CreatePart(db, "bearing_sealed", "6204-2RS", "{}", nil, 1)
+
+	parts, err := SearchPartsDB(db, SearchFilters{Type: "bearing"})
+	if err != nil || len(parts) != 1 {
+		t.Fatalf("expected only the parent type without subtypes, got %d (%v)", len(parts), err)
+	}
+	parts, err = SearchPartsDB(db, SearchFilters{Type: "bearing", IncludeSubtypes: true})
+	if err != nil || len(parts) != 2 {
+		t.Fatalf("expected parent and subtype parts, got %d (%v)", len(parts), err)
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Désignations de filetage saisies dans le diamètre d'une vis ("M4", "M6x1x25", "1/4-20 UNC",
+// "G1/2"): NormalizeProps les décompose en diamètre, pas et longueur (mm).
+
+// ThreadSpec est un filetage décodé, dimensions en mm (Length à 0 si absente)
+type ThreadSpec struct {
+	System   string // metrique, UNC, UNF, UN ou BSP
+	Diameter float64
+	Pitch    float64 // 0 si inconnu (pas omis hors table ISO)
+	Length   float64
+}
+
+// threadFieldSets associe les champs diamètre, pas et longueur des templates de vis (fr et en)
+var threadFieldSets = []struct {
+	diameter, pitch, length string
+}{
+	{"diametre", "pas", "longueur"},
+	{"diameter", "pitch", "length"},
+}
+
+var (
+	// metricThreadRegex: M4, M4x0.7, M6x1x25, M8x30 (séparateur x, X, × ou *)
+	metricThreadRegex = regexp.MustCompile(`^[Mm]\s*(\d+(?:[.,]\d+)?)(?:\s*[xX×*]\s*(\d+(?:[.,]\d+)?))?(?:\s*[xX×*]\s*(\d+(?:[.,]\d+)?))?$`)
+
+	// unifiedThreadRegex: 1/4-20, #8-32 UNC, 3/8-24 UNF x 1 (longueur en pouces)
+	unifiedThreadRegex = regexp.MustCompile(`^(#\d+|\d+/\d+|\d+(?:\.\d+)?)\s*-\s*(\d+)(?:\s*(UNC|UNF|UNEF|UN))?(?:\s*[xX×*]\s*(\d+(?:\.\d+)?(?:\s+\d+/\d+)?|\d+/\d+)"?)?$`)
+
+	// bspThreadRegex: G1/4, R 1/2, Rp3/4, 1/2 BSP, BSPT 1 1/4 (marqueur obligatoire)
+	bspThreadRegex = regexp.MustCompile(`^(?:(?:G|R|Rp|Rc|BSPP|BSPT|BSP)\s*((?:\d+\s+)?\d+/\d+|\d+)|((?:\d+\s+)?\d+/\d+|\d+)"?\s*(?:BSPP|BSPT|BSP))$`)
+)
+
+// unifiedThreadTPI donne les filets par pouce des séries UNC et UNF par taille nominale
+var unifiedThreadTPI = map[string]struct{ unc, unf int }{
+	"#2": {56, 64}, "#4": {40, 48}, "#5": {40, 44}, "#6": {32, 40}, "#8": {32, 36}, "#10": {24, 32}, "#12": {24, 28},
+	"1/4": {20, 28}, "5/16": {18, 24}, "3/8": {16, 24}, "7/16": {14, 20}, "1/2": {13, 20},
+	"9/16": {12, 18}, "5/8": {11, 18}, "3/4": {10, 16}, "7/8": {9, 14}, "1": {8, 12},
+}
+
+// bspThreads donne le diamètre extérieur (mm) et les filets par pouce des filetages gaz BSP
+var bspThreads = map[string]struct {
+	diameter float64
+	tpi      int
+}{
+	"1/8": {9.728, 28}, "1/4": {13.157, 19}, "3/8": {16.662, 19}, "1/2": {20.955, 14},
+	"5/8": {22.911, 14}, "3/4": {26.441, 14}, "1": {33.249, 11}, "1 1/4": {41.910, 11},
+	"1 1/2": {47.803, 11}, "2": {59.614, 11},
+}
+
+// ParseThread décode une désignation de filetage métrique ISO, unifiée (UNC/UNF) ou gaz (BSP).
+// En métrique, un seul nombre après le diamètre est un pas s'il ne dépasse pas le quart du
+// diamètre (M4x0.7), une longueur sinon (M4x20); le pas omis vient de la table du pas gros ISO.
+func ParseThread(input string) (*ThreadSpec, error) {
+	input = strings.TrimSpace(input)
+
+	if matches := metricThreadRegex.FindStringSubmatch(input); matches != nil {
+		spec := &ThreadSpec{System: "metrique", Diameter: parseDecimal(matches[1])}
+		switch {
+		case matches[3] != "":
+			spec.Pitch = parseDecimal(matches[2])
+			spec.Length = parseDecimal(matches[3])
+		case matches[2] != "" && parseDecimal(matches[2]) <= spec.Diameter/4:
+			spec.Pitch = parseDecimal(matches[2])
+		case matches[2] != "":
+			spec.Length = parseDecimal(matches[2])
+		}
+		if spec.Pitch == 0 {
+			spec.Pitch = isoCoarsePitches[spec.Diameter]
+		}
+		return spec, nil
+	}
+
+	if matches := unifiedThreadRegex.FindStringSubmatch(strings.ToUpper(input)); matches != nil {
+		diameter, err := unifiedDiameter(matches[1])
+		if err != nil {
+			return nil, err
+		}
+		tpi, _ := strconv.Atoi(matches[2])
+		if tpi == 0 {
+			return nil, fmt.Errorf("filetage '%s': nombre de filets par pouce invalide", input)
+		}
+		spec := &ThreadSpec{System: matches[3], Diameter: diameter, Pitch: roundThread(25.4 / float64(tpi))}
+		if spec.System == "" {
+			spec.System = "UN"
+			if series, ok := unifiedThreadTPI[matches[1]]; ok {
+				switch tpi {
+				case series.unc:
+					spec.System = "UNC"
+				case series.unf:
+					spec.System = "UNF"
+				}
+			}
+		}
+		if matches[4] != "" {
+			length, err := parseInches(matches[4])
+			if err != nil {
+				return nil, err
+			}
+			spec.Length = roundThread(length * 25.4)
+		}
+		return spec, nil
+	}
+
+	if matches := bspThreadRegex.FindStringSubmatch(input); matches != nil {
+		size := matches[1] + matches[2]
+		bsp, ok := bspThreads[strings.Join(strings.Fields(size), " ")]
+		if !ok {
+			return nil, fmt.Errorf("filetage BSP inconnu: %s", size)
+		}
+		return &ThreadSpec{System: "BSP", Diameter: bsp.diameter, Pitch: roundThread(25.4 / float64(bsp.tpi))}, nil
+	}
+
+	return nil, fmt.Errorf("désignation de filetage non reconnue: '%s' (ex: M4, M6x1x25, 1/4-20, G1/2)", input)
+}
+
+// expandThreadDesignations remplace une désignation de filetage saisie dans un champ diamètre
+// par ses dimensions: diamètre, et pas / longueur s'ils ne sont pas saisis séparément
+func expandThreadDesignations(props map[string]interface{}) (map[string]interface{}, error) {
+	for _, set := range threadFieldSets {
+		raw, ok := props[set.diameter].(string)
+		if !ok || !looksLikeThread(raw) {
+			continue
+		}
+		spec, err := ParseThread(raw)
+		if err != nil {
+			return nil, fmt.Errorf("champ '%s': %v", set.diameter, err)
+		}
+
+		expanded := make(map[string]interface{}, len(props)+2)
+		for k, v := range props {
+			expanded[k] = v
+		}
+		expanded[set.diameter] = spec.Diameter
+		if _, given := props[set.pitch]; !given && spec.Pitch > 0 {
+			expanded[set.pitch] = spec.Pitch
+		}
+		if _, given := props[set.length]; !given && spec.Length > 0 {
+			expanded[set.length] = spec.Length
+		}
+		props = expanded
+	}
+	return props, nil
+}
+
+// looksLikeThread distingue une désignation de filetage d'une simple valeur ("4", "4mm")
+func looksLikeThread(value string) bool {
+	value = strings.TrimSpace(value)
+	if _, err := ParseValueWithUnit(value); err == nil {
+		return false
+	}
+	return metricThreadRegex.MatchString(value) ||
+		unifiedThreadRegex.MatchString(strings.ToUpper(value)) ||
+		bspThreadRegex.MatchString(value)
+}
+
+// unifiedDiameter convertit une taille unifiée en mm: fraction de pouce ou numéro (#8 = 0.164")
+func unifiedDiameter(size string) (float64, error) {
+	if strings.HasPrefix(size, "#") {
+		n, err := strconv.Atoi(size[1:])
+		if err != nil {
+			return 0, fmt.Errorf("taille de vis invalide: %s", size)
+		}
+		return roundThread((0.060 + 0.013*float64(n)) * 25.4), nil
+	}
+	inches, err := parseInches(size)
+	if err != nil {
+		return 0, err
+	}
+	return roundThread(inches * 25.4), nil
+}
+
+// parseInches lit une longueur en pouces: "1", "0.5", "3/4" ou "1 1/4"
+func parseInches(value string) (float64, error) {
+	total := 0.0
+	for _, part := range strings.Fields(value) {
+		if num, den, ok := strings.Cut(part, "/"); ok {
+			n, errN := strconv.ParseFloat(num, 64)
+			d, errD := strconv.ParseFloat(den, 64)
+			if errN != nil || errD != nil || d == 0 {
+				return 0, fmt.Errorf("fraction invalide: %s", part)
+			}
+			total += n / d
+			continue
+		}
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return 0, fmt.Errorf("nombre invalide: %s", part)
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// parseDecimal lit un nombre validé par une regex, virgule décimale acceptée
+func parseDecimal(value string) float64 {
+	f, _ := strconv.ParseFloat(strings.Replace(value, ",", ".", 1), 64)
+	return f
+}
+
+// roundThread arrondit une dimension convertie des pouces au millième de mm
+func roundThread(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseThread(t *testing.T) {
+	cases := []struct {
+		input string
+		want  ThreadSpec
+	}{
+		{"M4", ThreadSpec{System: "metrique", Diameter: 4, Pitch: 0.7}},
+		{"M4x0.7", ThreadSpec{System: "metrique", Diameter: 4, Pitch: 0.7}},
+		{"M8x1", ThreadSpec{System: "metrique", Diameter: 8, Pitch: 1}},
+		{"M6x1x25", ThreadSpec{System: "metrique", Diameter: 6, Pitch: 1, Length: 25}},
+		{"M3x12", ThreadSpec{System: "metrique", Diameter: 3, Pitch: 0.5, Length: 12}},
+		{"1/4-20", ThreadSpec{System: "UNC", Diameter: 6.35, Pitch: 1.27}},
+		{"1/4-28 UNF", ThreadSpec{System: "UNF", Diameter: 6.35, Pitch: 0.907}},
+		{"#8-32 x 1/2", ThreadSpec{System: "UNC", Diameter: 4.166, Pitch: 0.794, Length: 12.7}},
+		{"G1/2", ThreadSpec{System: "BSP", Diameter: 20.955, Pitch: 1.814}},
+		{"1 1/4 BSP", ThreadSpec{System: "BSP", Diameter: 41.91, Pitch: 2.309}},
+	}
+	for _, c := range cases {
+		spec, err := ParseThread(c.input)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", c.input, err)
+		}
+		if *spec != c.want {
+			t.Fatalf("%s: expected %+v, got %+v", c.input, c.want, *spec)
+		}
+	}
+
+	for _, input := range []string{"4mm", "G3/16", "M"} {
+		if _, err := ParseThread(input); err == nil {
+			t.Fatalf("%s: expected error", input)
+		}
+	}
+}
+
+func TestNormalizePropsExpandsThread(t *testing.T) {
+	units := map[string]string{"diametre": "mm", "pas": "mm", "longueur": "mm"}
+	norm, err := NormalizeProps(map[string]interface{}{"diametre": "M6x1x25"}, units, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if norm["diametre"] != 6.0 || norm["pas"] != 1.0 || norm["longueur"] != 25.0 {
+		t.Fatalf("unexpected normalized props: %v", norm)
+	}
+
+	// Les valeurs saisies séparément priment sur la désignation
+	norm, err = NormalizeProps(map[string]interface{}{"diameter": "M4", "length": "16"}, map[string]string{"length": "mm"}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if norm["diameter"] != 4.0 || norm["pitch"] != 0.7 || norm["length"] != 16.0 {
+		t.Fatalf("unexpected normalized props: %v", norm)
+	}
+
+	if _, err := NormalizeProps(map[string]interface{}{"diametre": "G3/16"}, units, nil); err == nil {
+		t.Fatalf("expected unknown BSP size error")
+	}
+}
+
+func TestSearchFindsThreadDesignation(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+
+	for _, diameter := range []string{"M4x20", "M6x1x25", "1/4-20"} {
+		props, err := NormalizeProps(map[string]interface{}{"diametre": diameter}, nil, nil)
+		if err != nil {
+			t.Fatalf("%s: normalize: %v", diameter, err)
+		}
+		data, _ := json.Marshal(props)
+		if _, err := CreatePart(db, "vis", "Vis "+diameter, string(data), nil, 1); err != nil {
+			t.Fatalf("create part: %v", err)
+		}
+	}
+
+	criteria, _ := ParseSearchProp("diametre:3..6")
+	parts, err := SearchPartsDB(db, SearchFilters{Type: "vis", Criteria: criteria})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(parts) != 2 {
+		t.Fatalf("expected M4 and M6 screws, got %+v", parts)
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// TrashedPart représente une pièce placée dans la corbeille
+type TrashedPart struct {
+	ID        int
+	Type      string
+	Name      string
+	DeletedAt string
+}
+
+// ErrPartLoaned signale une pièce qui a des prêts en cours
+var ErrPartLoaned = errors.New("pièce prêtée")
+
+// TrashPart place une pièce dans la corbeille (suppression réversible).
+// Une pièce prêtée est refusée: ses prêts doivent d'abord être rendus.
+func TrashPart(db *sql.DB, partID int) error {
+	var open int
+	if err := db.QueryRow("SELECT COUNT(*) FROM loans WHERE part_id = ? AND returned_at IS NULL", partID).Scan(&open); err != nil {
+		return err
+	}
+	if open > 0 {
+		return fmt.Errorf("pièce ID %d: %w (%d prêt(s) en cours), enregistrez le retour avant de la mettre à la corbeille", partID, ErrPartLoaned, open)
+	}
+	return updatePartWithHistory(db, partID, "trash",
+		`UPDATE parts SET deleted_at = CURRENT_TIMESTAMP WHERE id = ? AND deleted_at IS NULL
+			AND NOT EXISTS (SELECT 1 FROM loans WHERE loans.part_id = parts.id AND loans.returned_at IS NULL)`,
+		fmt.Errorf("pièce ID %d introuvable (ou déjà dans la corbeille)", partID))
+}
+
+// RestorePart sort une pièce de la corbeille
+func RestorePart(db *sql.DB, partID int) error {
+	return updatePartWithHistory(db, partID, "restore",
+		"UPDATE parts SET deleted_at = NULL WHERE id = ? AND deleted_at IS NOT NULL",
+		fmt.Errorf("pièce ID %d absente de la corbeille", partID))
+}
+
+// updatePartWithHistory exécute une mise à jour (paramètre unique: l'ID) et l'enregistre dans l'historique.
+// notFound est retournée si aucune ligne n'est modifiée.
+func updatePartWithHistory(db *sql.DB, partID int, action, query string, notFound error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := snapshotPart(tx, partID)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.Exec(query, partID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return notFound
+	}
+
+	after, err := snapshotPart(tx, partID)
+	if err != nil {
+		return err
+	}
+	if err := recordHistory(tx, HistoryEntityPart, partID, action, before, after); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ListTrashedParts liste les pièces de la corbeille (plus récentes en premier)
+func ListTrashedParts(db *sql.DB) ([]TrashedPart, error) {
+	rows, err := db.Query(`
+		SELECT id, type, name, deleted_at
+		FROM parts
+		WHERE deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC, id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var parts []TrashedPart
+	for rows.Next() {
+		var p TrashedPart
+		if err := rows.Scan(&p.ID, &p.Type, &p.Name, &p.DeletedAt); err != nil {
+			return nil, err
+		}
+		parts = append(parts, p)
+	}
+
+	return parts, nil
+}
+
+// PurgePart supprime définitivement une pièce de la corbeille,
+// avec ses fichiers attachés (lignes et fichiers sous assets/), son registre de stock, ses liens de nomenclature et ses tags
+func PurgePart(db *sql.DB, partID int) error {
+	var deletedAt sql.NullString
+	err := db.QueryRow("SELECT deleted_at FROM parts WHERE id = ?", partID).Scan(&deletedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("pièce ID %d introuvable", partID)
+	}
+	if err != nil {
+		return err
+	}
+	if !deletedAt.Valid {
+		return fmt.Errorf("pièce ID %d absente de la corbeille (utilisez 'recycle rm' d'abord)", partID)
+	}
+
+	attachments, err := GetAttachments(db, partID)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := snapshotPart(tx, partID)
+	if err != nil {
+		return err
+	}
+	if err := recordHistory(tx, HistoryEntityPart, partID, "purge", before, nil); err != nil {
+		return err
+	}
+
+	statements := []string{
+		"DELETE FROM attachments WHERE part_id = ?",
+		"DELETE FROM stock_movements WHERE part_id = ?",
+		"DELETE FROM part_components WHERE parent_id = ?1 OR child_id = ?1",
+		"DELETE FROM part_tags WHERE part_id = ?",
+		"DELETE FROM part_redirects WHERE new_id = ?",
+		"DELETE FROM loans WHERE part_id = ?",
+		"DELETE FROM parts WHERE id = ?",
+	}
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt, partID); err != nil {
+			return err
+		}
+	}
+	// Supprimer les tags qui ne sont plus utilisés
+	if _, err := tx.Exec("DELETE FROM tags WHERE id NOT IN (SELECT tag_id FROM part_tags)"); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	// Supprimer les fichiers une fois la base à jour
+	for _, a := range attachments {
+		os.Remove(a.Filepath) // Ignorer l'erreur si le fichier n'existe plus
+	}
+
+	return nil
+}
+
+// PurgeTrash vide entièrement la corbeille et retourne le nombre de pièces supprimées
+func PurgeTrash(db *sql.DB) (int, error) {
+	parts, err := ListTrashedParts(db)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, p := range parts {
+		if err := PurgePart(db, p.ID); err != nil {
+			return i, fmt.Errorf("pièce ID %d: %v", p.ID, err)
+		}
+	}
+
+	return len(parts), nil
+}
+
+// PrintTrash affiche le contenu de la corbeille
+func PrintTrash(db *sql.DB) error {
+	parts, err := ListTrashedParts(db)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("\n🗑️  Corbeille:")
+	fmt.Println(strings.Repeat("─", 60))
+
+	if len(parts) == 0 {
+		fmt.Println("  La corbeille est vide")
+		fmt.Println()
+		return nil
+	}
+
+	for _, p := range parts {
+		fmt.Printf("  [%d] %s - %s (supprimée le %s)\n", p.ID, p.Type, p.Name, p.DeletedAt)
+	}
+
+	fmt.Printf("\n%d pièce(s) dans la corbeille\n\n", len(parts))
+	return nil
+}
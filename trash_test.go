@@ -0,0 +1,93 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTrashHidesPartUntilRestored(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+
+	id, err := CreatePart(db, "", "Moteur DVD", "{}", nil, 1)
+	if err != nil {
+		t.Fatalf("create part: %v", err)
+	}
+	if _, err := CreatePart(db, "", "Moteur imprimante", "{}", nil, 1); err != nil {
+		t.Fatalf("create part: %v", err)
+	}
+
+	if err := TrashPart(db, int(id)); err != nil {
+		t.Fatalf("trash part: %v", err)
+	}
+
+	parts, _ := ListAllParts(db)
+	if len(parts) != 1 {
+		t.Fatalf("expected 1 visible part after trash, got %d", len(parts))
+	}
+	found, err := SearchPartsDB(db, SearchFilters{Name: "Moteur"})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(found) != 1 || found[0].ID == int(id) {
+		t.Fatalf("trashed part should not appear in search, got %+v", found)
+	}
+	meta, _ := GetPartMeta(db, int(id))
+	if meta.Found {
+		t.Fatalf("trashed part should not be found by GetPartMeta")
+	}
+	if err := TrashPart(db, int(id)); err == nil {
+		t.Fatalf("expected error when trashing twice")
+	}
+
+	if err := RestorePart(db, int(id)); err != nil {
+		t.Fatalf("restore part: %v", err)
+	}
+	parts, _ = ListAllParts(db)
+	if len(parts) != 2 {
+		t.Fatalf("expected 2 visible parts after restore, got %d", len(parts))
+	}
+}
+
+func TestPurgePartRemovesAttachments(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+
+	id, err := CreatePart(db, "", "Roulement 608", "{}", nil, 1)
+	if err != nil {
+		t.Fatalf("create part: %v", err)
+	}
+
+	file := filepath.Join(t.TempDir(), "1_datasheet.pdf")
+	if err := os.WriteFile(file, []byte("pdf"), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	if _, err := db.Exec("INSERT INTO attachments (part_id, filename, filepath) VALUES (?, ?, ?)", id, "datasheet.pdf", file); err != nil {
+		t.Fatalf("insert attachment: %v", err)
+	}
+
+	if err := PurgePart(db, int(id)); err == nil {
+		t.Fatalf("expected error when purging a part that is not in the trash")
+	}
+
+	if err := TrashPart(db, int(id)); err != nil {
+		t.Fatalf("trash part: %v", err)
+	}
+	if err := PurgePart(db, int(id)); err != nil {
+		t.Fatalf("purge part: %v", err)
+	}
+
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM attachments WHERE part_id = ?", id).Scan(&count)
+	if count != 0 {
+		t.Fatalf("expected attachments rows to be deleted, got %d", count)
+	}
+	db.QueryRow("SELECT COUNT(*) FROM parts WHERE id = ?", id).Scan(&count)
+	if count != 0 {
+		t.Fatalf("expected part row to be deleted")
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Fatalf("expected attachment file to be removed")
+	}
+}
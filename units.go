@@ -0,0 +1,260 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Unités des props: les valeurs numériques sont stockées dans l'unité de base de leur domaine
+// (BaseUnits); la colonne props_units garde, par prop, cette unité et la saisie d'origine.
+// L'affichage convertit dans le système demandé (metric, imperial) ou dans une unité par champ.
+
+// PropUnit accompagne une prop numérique normalisée
+type PropUnit struct {
+	Original string `json:"original,omitempty"` // Saisie d'origine ("1/4 inch")
+	Unit     string `json:"unit,omitempty"`     // Unité de base de la valeur stockée ("mm")
+}
+
+// Systèmes d'unités d'affichage
+const (
+	UnitsMetric   = "metric"
+	UnitsImperial = "imperial"
+)
+
+// displayDigits est le nombre de chiffres significatifs des valeurs converties pour l'affichage
+const displayDigits = 4
+
+// imperialUnits donne l'unité impériale d'un domaine (les autres domaines gardent leur unité de base)
+var imperialUnits = map[UnitDomain]string{
+	DomainDimension: "in",
+	DomainPression:  "psi",
+}
+
+// DisplayUnits décrit les unités d'affichage demandées
+type DisplayUnits struct {
+	System string            // metric (défaut) ou imperial
+	Fields map[string]string // Unité imposée par champ (d_int → cm), prioritaire sur System
+}
+
+// ParseDisplayUnits lit un système d'unités et des unités par champ ("d_int:cm", séparées par des virgules)
+func ParseDisplayUnits(system string, fields []string) (DisplayUnits, error) {
+	display := DisplayUnits{System: strings.ToLower(strings.TrimSpace(system)), Fields: map[string]string{}}
+	switch display.System {
+	case "":
+		display.System = UnitsMetric
+	case UnitsMetric, UnitsImperial:
+	default:
+		return display, fmt.Errorf("système d'unités inconnu: %s (metric, imperial)", system)
+	}
+
+	for _, item := range fields {
+		for _, pair := range strings.Split(item, ",") {
+			if strings.TrimSpace(pair) == "" {
+				continue
+			}
+			field, unit, ok := strings.Cut(pair, ":")
+			field, unit = strings.TrimSpace(field), strings.TrimSpace(unit)
+			if !ok || field == "" || unit == "" {
+				return display, fmt.Errorf("unité de champ invalide: %s (attendu: champ:unité)", pair)
+			}
+			if _, known := LookupUnit(unit); !known {
+				return display, fmt.Errorf("champ '%s': unité '%s' non reconnue", field, unit)
+			}
+			display.Fields[field] = unit
+		}
+	}
+	return display, nil
+}
+
+// UnitFor retourne l'unité d'affichage d'un champ stocké en baseUnit (une unité imposée
+// d'un autre domaine est ignorée)
+func (d DisplayUnits) UnitFor(field, baseUnit string) string {
+	base, ok := LookupUnit(baseUnit)
+	if !ok {
+		return baseUnit
+	}
+	if unit, ok := d.Fields[field]; ok {
+		if info, ok := LookupUnit(unit); ok && info.Domain == base.Domain {
+			return unit
+		}
+	}
+	if d.System == UnitsImperial {
+		if unit, ok := imperialUnits[base.Domain]; ok {
+			return unit
+		}
+	}
+	return baseUnit
+}
+
+// DisplayValue est une prop convertie dans l'unité d'affichage
+type DisplayValue struct {
+	Value    interface{} `json:"value"`              // Nombre ou valeur structurée convertis
+	Unit     string      `json:"unit,omitempty"`     // Unité d'affichage
+	Text     string      `json:"text"`               // Valeur formatée avec son unité ("0.25 in")
+	Original string      `json:"original,omitempty"` // Saisie d'origine
+}
+
+// propBaseUnit retourne l'unité de base d'une prop: celle enregistrée à la normalisation,
+// sinon celle du domaine du champ (template, puis conventions de nommage)
+func propBaseUnit(typeName, field string, units map[string]PropUnit) string {
+	if unit := units[field].Unit; unit != "" {
+		return unit
+	}
+	if domain := GetFieldDomain(typeName, field); domain != DomainNone {
+		return BaseUnits[domain]
+	}
+	if unit := GetDefaultUnitForField(field); unit != "" {
+		if info, ok := LookupUnit(unit); ok {
+			return BaseUnits[info.Domain]
+		}
+	}
+	return ""
+}
+
+// DisplayProps convertit les props d'une pièce dans les unités d'affichage demandées
+func DisplayProps(typeName string, props map[string]interface{}, units map[string]PropUnit, display DisplayUnits) map[string]DisplayValue {
+	result := make(map[string]DisplayValue, len(props))
+	for field, value := range props {
+		result[field] = displayProp(typeName, field, value, units, display)
+	}
+	return result
+}
+
+func displayProp(typeName, field string, value interface{}, units map[string]PropUnit, display DisplayUnits) DisplayValue {
+	dv := DisplayValue{Value: value, Text: formatNameValue(value), Original: units[field].Original}
+	if isTextOnlyField(field) {
+		return dv
+	}
+	base := propBaseUnit(typeName, field, units)
+	if base == "" {
+		return dv
+	}
+	unit := display.UnitFor(field, base)
+	convert := func(v float64) float64 {
+		if unit == base {
+			return v
+		}
+		converted, err := convertUnit(v, base, unit)
+		if err != nil {
+			return v
+		}
+		return roundDigits(converted, displayDigits) // 47 mm → 1.85 in, pas 1.850393701
+	}
+
+	switch v := value.(type) {
+	case float64:
+		dv.Value = convert(v)
+	case int:
+		dv.Value = convert(float64(v))
+	default:
+		rv, ok := AsRangeValue(value)
+		if !ok {
+			return dv
+		}
+		rv.Min, rv.Max = convert(rv.Min), convert(rv.Max)
+		if rv.Nominal != nil {
+			nominal := convert(*rv.Nominal)
+			rv.Nominal = &nominal
+		}
+		values := make([]float64, len(rv.Values))
+		for i, item := range rv.Values {
+			values[i] = convert(item)
+		}
+		if len(values) > 0 {
+			rv.Values = values
+		}
+		if rv.Tolerance != "" && !strings.HasSuffix(rv.Tolerance, "%") {
+			if tol, err := exprNumber(rv.Tolerance); err == nil {
+				rv.Tolerance = formatNameValue(convert(tol))
+			}
+		}
+		dv.Value = rv
+	}
+	dv.Unit = unit
+	dv.Text = formatNameValue(dv.Value) + " " + unit
+	return dv
+}
+
+// FormatProps formate les props pour la CLI, avec leurs unités: "d_ext=47 mm, d_int=20 mm, marque=SKF"
+func FormatProps(typeName string, props map[string]interface{}, units map[string]PropUnit, display DisplayUnits) string {
+	values := DisplayProps(typeName, props, units, display)
+	items := make([]string, 0, len(values))
+	for _, field := range sortedKeys(props) {
+		items = append(items, field+"="+values[field].Text)
+	}
+	return strings.Join(items, ", ")
+}
+
+// FormatPropsJSON formate des props JSON pour la CLI (voir FormatProps); JSON brut si invalide
+func FormatPropsJSON(typeName string, propsJSON, unitsJSON sql.NullString, display DisplayUnits) string {
+	if !propsJSON.Valid || propsJSON.String == "" {
+		return ""
+	}
+	props := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(propsJSON.String), &props); err != nil {
+		return propsJSON.String
+	}
+	return FormatProps(typeName, props, decodePropUnits(unitsJSON), display)
+}
+
+// decodePropUnits lit la colonne props_units (vide si NULL ou invalide)
+func decodePropUnits(value sql.NullString) map[string]PropUnit {
+	units := map[string]PropUnit{}
+	if value.Valid && value.String != "" {
+		json.Unmarshal([]byte(value.String), &units)
+	}
+	return units
+}
+
+// encodePropUnits prépare la colonne props_units (NULL si aucune unité)
+func encodePropUnits(units map[string]PropUnit) (interface{}, error) {
+	if len(units) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(units)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// SetPropUnits enregistre la saisie d'origine et l'unité de base des props d'une pièce
+func SetPropUnits(db *sql.DB, partID int, units map[string]PropUnit) error {
+	value, err := encodePropUnits(units)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec("UPDATE parts SET props_units = ? WHERE id = ?", value, partID)
+	return err
+}
+
+// FieldDisplay est une prop affichée, dans l'ordre des champs (page de détail)
+type FieldDisplay struct {
+	Field string
+	DisplayValue
+}
+
+// DisplayFields retourne les props d'une pièce triées par champ, converties dans les unités d'affichage
+func (p *PartMeta) DisplayFields(display DisplayUnits) []FieldDisplay {
+	props := map[string]interface{}{}
+	if p.PropsJSON != "" {
+		json.Unmarshal([]byte(p.PropsJSON), &props)
+	}
+	values := DisplayProps(p.Type, props, p.PropUnits, display)
+	fields := make([]FieldDisplay, 0, len(values))
+	for _, field := range sortedKeys(props) {
+		fields = append(fields, FieldDisplay{Field: field, DisplayValue: values[field]})
+	}
+	return fields
+}
+
+// FormatProps formate les props d'une pièce pour la CLI (voir FormatProps)
+func (p *PartMeta) FormatProps(display DisplayUnits) string {
+	items := []string{}
+	for _, f := range p.DisplayFields(display) {
+		items = append(items, f.Field+"="+f.Text)
+	}
+	return strings.Join(items, ", ")
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseDisplayUnits(t *testing.T) {
+	display, err := ParseDisplayUnits("", []string{"d_int:cm, d_ext:in", "width:mm"})
+	if err != nil {
+		t.Fatalf("ParseDisplayUnits: %v", err)
+	}
+	if display.System != UnitsMetric {
+		t.Fatalf("expected metric by default, got %s", display.System)
+	}
+	if display.Fields["d_int"] != "cm" || display.Fields["d_ext"] != "in" || display.Fields["width"] != "mm" {
+		t.Fatalf("unexpected field units: %v", display.Fields)
+	}
+
+	for _, bad := range [][]string{{"d_int"}, {"d_int:parsec"}, {":mm"}} {
+		if _, err := ParseDisplayUnits("", bad); err == nil {
+			t.Fatalf("expected error for %v", bad)
+		}
+	}
+	if _, err := ParseDisplayUnits("cubits", nil); err == nil {
+		t.Fatalf("expected error for unknown unit system")
+	}
+}
+
+func TestNormalizePropsKeepsOriginalAndBaseUnit(t *testing.T) {
+	seedTemplates()
+
+	props, units, err := NormalizePartPropsWithUnits("bearing", map[string]interface{}{
+		"d_int": "1/4 inch",
+		"d_ext": "47",
+		"width": 14.0,
+		"brand": "SKF",
+	})
+	if err != nil {
+		t.Fatalf("NormalizePartPropsWithUnits: %v", err)
+	}
+	if props["d_int"] != 6.35 {
+		t.Fatalf("expected d_int=6.35, got %v", props["d_int"])
+	}
+	if units["d_int"] != (PropUnit{Original: "1/4 inch", Unit: "mm"}) {
+		t.Fatalf("unexpected d_int unit: %+v", units["d_int"])
+	}
+	// Saisie identique à la valeur stockée: seule l'unité est gardée
+	if units["d_ext"] != (PropUnit{Unit: "mm"}) || units["width"] != (PropUnit{Unit: "mm"}) {
+		t.Fatalf("unexpected units: %+v", units)
+	}
+	if _, ok := units["brand"]; ok {
+		t.Fatalf("text prop should have no unit: %+v", units["brand"])
+	}
+}
+
+func TestDisplayPropsConvertsUnits(t *testing.T) {
+	seedTemplates()
+
+	props := map[string]interface{}{"d_int": 6.35, "d_ext": 47.0, "brand": "SKF"}
+	units := map[string]PropUnit{"d_int": {Original: "1/4 inch", Unit: "mm"}}
+
+	metric := DisplayProps("bearing", props, units, DisplayUnits{})
+	if metric["d_int"].Text != "6.35 mm" || metric["d_int"].Original != "1/4 inch" {
+		t.Fatalf("unexpected metric d_int: %+v", metric["d_int"])
+	}
+	// Sans unité enregistrée, celle du domaine du champ
+	if metric["d_ext"].Text != "47 mm" {
+		t.Fatalf("unexpected metric d_ext: %+v", metric["d_ext"])
+	}
+	if metric["brand"].Text != "SKF" || metric["brand"].Unit != "" {
+		t.Fatalf("unexpected brand: %+v", metric["brand"])
+	}
+
+	imperial := DisplayProps("bearing", props, units, DisplayUnits{System: UnitsImperial, Fields: map[string]string{"d_ext": "cm", "brand": "V"}})
+	if imperial["d_int"].Value != 0.25 || imperial["d_int"].Text != "0.25 in" {
+		t.Fatalf("unexpected imperial d_int: %+v", imperial["d_int"])
+	}
+	if imperial["d_ext"].Text != "4.7 cm" {
+		t.Fatalf("field unit should override system, got %+v", imperial["d_ext"])
+	}
+	// Valeurs converties arrondies à displayDigits chiffres significatifs
+	if dv := displayProp("bearing", "d_ext", 47.0, nil, DisplayUnits{System: UnitsImperial}); dv.Text != "1.85 in" {
+		t.Fatalf("expected rounded imperial value, got %+v", dv)
+	}
+
+	text := FormatProps("bearing", props, units, DisplayUnits{})
+	if text != "brand=SKF, d_ext=47 mm, d_int=6.35 mm" {
+		t.Fatalf("unexpected FormatProps: %s", text)
+	}
+}
+
+func TestDisplayPropsConvertsRanges(t *testing.T) {
+	seedTemplates()
+
+	rv, ok := AsRangeValue(map[string]interface{}{"min": 25.4, "max": 50.8})
+	if !ok {
+		t.Fatalf("AsRangeValue failed")
+	}
+	dv := displayProp("bearing", "width", rv, nil, DisplayUnits{System: UnitsImperial})
+	if dv.Text != "1-2 in" {
+		t.Fatalf("unexpected range display: %+v", dv)
+	}
+}
+
+func TestPropUnitsStoredAndEdited(t *testing.T) {
+	seedTemplates()
+	db := newTestDB(t)
+	defer db.Close()
+
+	if err := cmdAdd(db, []string{"--type=bearing", "--name=Roulement R4", `--props={"d_int":"1/4 inch","d_ext":"5/8in","width":5}`}); err != nil {
+		t.Fatalf("cmdAdd: %v", err)
+	}
+	meta, err := GetPartMeta(db, 1)
+	if err != nil {
+		t.Fatalf("GetPartMeta: %v", err)
+	}
+	if meta.PropUnits["d_int"].Original != "1/4 inch" || meta.PropUnits["d_ext"].Original != "5/8in" {
+		t.Fatalf("original values not stored: %+v", meta.PropUnits)
+	}
+
+	// Modifier un champ garde la saisie d'origine des autres
+	meta, err = EditPart(db, 1, PartUpdate{Props: map[string]interface{}{"d_ext": "16mm"}})
+	if err != nil {
+		t.Fatalf("EditPart: %v", err)
+	}
+	if meta.PropUnits["d_int"].Original != "1/4 inch" {
+		t.Fatalf("d_int original lost on edit: %+v", meta.PropUnits)
+	}
+	if meta.PropUnits["d_ext"] != (PropUnit{Unit: "mm"}) {
+		t.Fatalf("d_ext unit not updated: %+v", meta.PropUnits["d_ext"])
+	}
+
+	if text := meta.FormatProps(DisplayUnits{System: UnitsImperial}); !strings.Contains(text, "d_int=0.25 in") {
+		t.Fatalf("unexpected imperial props: %s", text)
+	}
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency est la devise des pièces valorisées sans devise explicite
+const DefaultCurrency = "EUR"
+
+// Axes de regroupement du rapport de valorisation
+const (
+	ValueByType     = "type"
+	ValueByLocation = "location"
+	ValueByDonor    = "donor"
+)
+
+// ValueRow est une ligne du rapport de valorisation (un groupe dans une devise)
+type ValueRow struct {
+	Group    string  `json:"group"`
+	Currency string  `json:"currency"`
+	Parts    int     `json:"parts"`    // Pièces valorisées
+	Units    int     `json:"units"`    // Unités en stock des pièces valorisées
+	Total    float64 `json:"total"`    // Somme de unit_value × quantité
+	Unvalued int     `json:"unvalued"` // Pièces du groupe sans valeur unitaire
+}
+
+// ValueReport est le rapport de valorisation du stock
+type ValueReport struct {
+	By     string     `json:"by"`
+	Rows   []ValueRow `json:"rows"`
+	Totals []ValueRow `json:"totals"` // Total général par devise (Group vide)
+}
+
+// NormalizeCurrency met un code devise sous forme canonique (ISO 4217: 3 lettres majuscules)
+func NormalizeCurrency(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return DefaultCurrency, nil
+	}
+	if len(code) != 3 {
+		return "", fmt.Errorf("devise invalide: %s (code à 3 lettres, ex: EUR)", code)
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return "", fmt.Errorf("devise invalide: %s (code à 3 lettres, ex: EUR)", code)
+		}
+	}
+	return code, nil
+}
+
+// SetPartValue définit la valeur unitaire estimée d'une pièce et sa devise
+// (value nil: la pièce n'est plus valorisée)
+func SetPartValue(db *sql.DB, partID int, value *float64, currency string) error {
+	var unitValue, cur interface{}
+	if value != nil {
+		if *value < 0 {
+			return fmt.Errorf("valeur invalide: %g", *value)
+		}
+		code, err := NormalizeCurrency(currency)
+		if err != nil {
+			return err
+		}
+		unitValue, cur = *value, code
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := snapshotPart(tx, partID)
+	if err != nil {
+		return err
+	}
+	if before == nil || before.DeletedAt != nil {
+		return fmt.Errorf("pièce ID %d introuvable", partID)
+	}
+
+	if _, err := tx.Exec("UPDATE parts SET unit_value = ?, currency = ? WHERE id = ?", unitValue, cur, partID); err != nil {
+		return err
+	}
+
+	after, err := snapshotPart(tx, partID)
+	if err != nil {
+		return err
+	}
+	if err := recordHistory(tx, HistoryEntityPart, partID, "value", before, after); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// FormatValue affiche un montant avec sa devise (ex: "12.50 EUR")
+func FormatValue(amount float64, currency string) string {
+	return fmt.Sprintf("%.2f %s", amount, currency)
+}
+
+// valueAggregates est le fragment SELECT commun aux regroupements du rapport
+const valueAggregates = `
+	COALESCE(p.currency, '` + DefaultCurrency + `'),
+	SUM(CASE WHEN p.unit_value IS NOT NULL THEN 1 ELSE 0 END),
+	COALESCE(SUM(CASE WHEN p.unit_value IS NOT NULL THEN p.quantity ELSE 0 END), 0),
+	COALESCE(SUM(p.unit_value * p.quantity), 0),
+	SUM(CASE WHEN p.unit_value IS NULL THEN 1 ELSE 0 END)`
+
+// BuildValueReport calcule la valeur du stock (hors corbeille) regroupée par type,
+// par sous-arbre de localisation ou par appareil donneur.
+// Par localisation, chaque ligne totalise la localisation et toutes ses sous-localisations.
+func BuildValueReport(db *sql.DB, by string) (*ValueReport, error) {
+	var query string
+	switch by {
+	case ValueByType:
+		query = `
+			SELECT CASE WHEN p.type = '' THEN '(sans type)' ELSE p.type END,` + valueAggregates + `
+			FROM parts p
+			WHERE p.deleted_at IS NULL
+			GROUP BY 1, 2
+			ORDER BY 1, 2`
+	case ValueByLocation:
+		// subtree associe chaque localisation (root_id) à elle-même et à tous ses descendants
+		query = `
+			WITH RECURSIVE subtree(root_id, id, level) AS (
+				SELECT id, id, 0 FROM locations
+				UNION ALL
+				SELECT s.root_id, l.id, s.level + 1
+				FROM locations l
+				JOIN subtree s ON l.parent_id = s.id AND l.id != s.id AND s.level < 100
+			)
+			SELECT CAST(s.root_id AS TEXT),` + valueAggregates + `
+			FROM subtree s
+			JOIN parts p ON p.location_id = s.id
+			WHERE p.deleted_at IS NULL
+			GROUP BY 1, 2
+			UNION ALL
+			SELECT '',` + valueAggregates + `
+			FROM parts p
+			WHERE p.deleted_at IS NULL AND p.location_id IS NULL
+			GROUP BY 1, 2`
+	case ValueByDonor:
+		query = `
+			SELECT CASE WHEN d.id IS NULL THEN '(sans appareil donneur)'
+				ELSE TRIM(d.category || ' ' || d.brand || ' ' || d.model) || ' #' || d.id END,` + valueAggregates + `
+			FROM parts p
+			LEFT JOIN donors d ON d.id = p.donor_id
+			WHERE p.deleted_at IS NULL
+			GROUP BY 1, 2
+			ORDER BY d.id IS NULL, 1, 2`
+	default:
+		return nil, fmt.Errorf("regroupement inconnu: %s (type|location|donor)", by)
+	}
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ValueReport{By: by}
+	for rows.Next() {
+		var r ValueRow
+		if err := rows.Scan(&r.Group, &r.Currency, &r.Parts, &r.Units, &r.Total, &r.Unvalued); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		report.Rows = append(report.Rows, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Par localisation, remplacer les IDs par les chemins complets (Atelier > Meuble > Boîte)
+	if by == ValueByLocation {
+		for i := range report.Rows {
+			if report.Rows[i].Group == "" {
+				report.Rows[i].Group = noLocationLabel
+				continue
+			}
+			id, _ := strconv.Atoi(report.Rows[i].Group)
+			if path, err := GetFullPath(db, id); err == nil {
+				report.Rows[i].Group = path
+			}
+		}
+		sort.SliceStable(report.Rows, func(i, j int) bool {
+			a, b := report.Rows[i], report.Rows[j]
+			if (a.Group == noLocationLabel) != (b.Group == noLocationLabel) {
+				return b.Group == noLocationLabel
+			}
+			if a.Group != b.Group {
+				return a.Group < b.Group
+			}
+			return a.Currency < b.Currency
+		})
+	}
+
+	totals, err := valueTotals(db)
+	if err != nil {
+		return nil, err
+	}
+	report.Totals = totals
+
+	return report, nil
+}
+
+// valueTotals calcule le total général par devise
+func valueTotals(db *sql.DB) ([]ValueRow, error) {
+	rows, err := db.Query(`
+		SELECT` + valueAggregates + `
+		FROM parts p
+		WHERE p.deleted_at IS NULL
+		GROUP BY 1
+		ORDER BY 1`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var totals []ValueRow
+	for rows.Next() {
+		var r ValueRow
+		if err := rows.Scan(&r.Currency, &r.Parts, &r.Units, &r.Total, &r.Unvalued); err != nil {
+			return nil, err
+		}
+		totals = append(totals, r)
+	}
+
+	return totals, nil
+}
+
+// WriteValueReport écrit le rapport au format text, csv ou json
+func WriteValueReport(w io.Writer, report *ValueReport, format string) error {
+	switch format {
+	case "", "text":
+		return writeValueReportText(w, report)
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write([]string{report.By, "currency", "parts", "units", "total", "unvalued"})
+		for _, r := range report.Rows {
+			cw.Write(valueRowCSV(r.Group, r))
+		}
+		for _, t := range report.Totals {
+			cw.Write(valueRowCSV("TOTAL", t))
+		}
+		cw.Flush()
+		return cw.Error()
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	default:
+		return fmt.Errorf("format inconnu: %s (text|csv|json)", format)
+	}
+}
+
+func valueRowCSV(group string, r ValueRow) []string {
+	return []string{
+		group,
+		r.Currency,
+		strconv.Itoa(r.Parts),
+		strconv.Itoa(r.Units),
+		strconv.FormatFloat(r.Total, 'f', 2, 64),
+		strconv.Itoa(r.Unvalued),
+	}
+}
+
+func writeValueReportText(w io.Writer, report *ValueReport) error {
+	titles := map[string]string{
+		ValueByType:     "par type",
+		ValueByLocation: "par localisation (sous-localisations incluses)",
+		ValueByDonor:    "par appareil donneur",
+	}
+	fmt.Fprintf(w, "\n💶 Valeur estimée du stock %s:\n", titles[report.By])
+	fmt.Fprintln(w, strings.Repeat("─", 80))
+
+	if len(report.Rows) == 0 {
+		fmt.Fprintln(w, "  Aucune pièce")
+		fmt.Fprintln(w)
+		return nil
+	}
+
+	fmt.Fprintf(w, "  %-40s %8s %8s %16s %10s\n", "Groupe", "Pièces", "Unités", "Valeur", "Non valor.")
+	for _, r := range report.Rows {
+		fmt.Fprintf(w, "  %-40s %8d %8d %16s %10d\n", truncate(r.Group, 40), r.Parts, r.Units, FormatValue(r.Total, r.Currency), r.Unvalued)
+	}
+	fmt.Fprintln(w, strings.Repeat("─", 80))
+	for _, t := range report.Totals {
+		fmt.Fprintf(w, "  %-40s %8d %8d %16s %10d\n", "TOTAL", t.Parts, t.Units, FormatValue(t.Total, t.Currency), t.Unvalued)
+	}
+	fmt.Fprintln(w)
+	return nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeCurrency(t *testing.T) {
+	if c, err := NormalizeCurrency(" chf "); err != nil || c != "CHF" {
+		t.Fatalf("NormalizeCurrency = %q, %v", c, err)
+	}
+	if c, _ := NormalizeCurrency(""); c != DefaultCurrency {
+		t.Fatalf("expected default currency, got %q", c)
+	}
+	if _, err := NormalizeCurrency("euro"); err == nil {
+		t.Fatalf("expected invalid currency to fail")
+	}
+}
+
+func TestValueReport(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+
+	atelier, _ := CreateLocation(db, "Atelier", nil, "ZONE", "")
+	etabli, _ := CreateLocation(db, "Etabli", &atelier.ID, "FURNITURE", "")
+	if path, _ := GetFullPath(db, etabli.ID); path != "Atelier > Etabli" {
+		t.Fatalf("unexpected full path %q", path)
+	}
+
+	donor, _ := CreateDonor(db, "imprimante", "HP", "DeskJet", "")
+
+	motor, _ := CreatePart(db, "moteur", "Moteur 550", `{"volts":12}`, &etabli.ID, 4)
+	psu, _ := CreatePart(db, "", "Alimentation", "{}", &atelier.ID, 1)
+	CreatePart(db, "", "Carton de vis", "{}", nil, 1)
+
+	price := 2.5
+	if err := SetPartValue(db, int(motor), &price, "eur"); err != nil {
+		t.Fatalf("set value: %v", err)
+	}
+	psuPrice := 20.0
+	if err := SetPartValue(db, int(psu), &psuPrice, ""); err != nil {
+		t.Fatalf("set value: %v", err)
+	}
+	if err := SetPartDonor(db, int(motor), donor.ID); err != nil {
+		t.Fatalf("set donor: %v", err)
+	}
+
+	report, err := BuildValueReport(db, ValueByLocation)
+	if err != nil {
+		t.Fatalf("report: %v", err)
+	}
+	totals := map[string]float64{}
+	for _, r := range report.Rows {
+		totals[r.Group] = r.Total
+	}
+	if totals["Atelier"] != 30 || totals["Atelier > Etabli"] != 10 || totals[noLocationLabel] != 0 {
+		t.Fatalf("unexpected subtree totals: %+v", report.Rows)
+	}
+	if len(report.Totals) != 1 || report.Totals[0].Total != 30 || report.Totals[0].Unvalued != 1 {
+		t.Fatalf("unexpected grand total: %+v", report.Totals)
+	}
+
+	report, err = BuildValueReport(db, ValueByDonor)
+	if err != nil {
+		t.Fatalf("report: %v", err)
+	}
+	if report.Rows[0].Group != "imprimante HP DeskJet #1" || report.Rows[0].Total != 10 {
+		t.Fatalf("unexpected donor rows: %+v", report.Rows)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteValueReport(&buf, report, "csv"); err != nil {
+		t.Fatalf("csv: %v", err)
+	}
+	if !strings.Contains(buf.String(), "TOTAL,EUR,2,5,30.00,1") {
+		t.Fatalf("unexpected csv output:\n%s", buf.String())
+	}
+}
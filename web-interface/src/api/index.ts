@@ -7,10 +7,41 @@ export interface PartAPIResponse {
   type: string;
   name: string;
   props: any;
+  quantity?: number;
+  available?: number; // Quantité non prêtée (0: pièce sortie)
+  state?: 'untested' | 'working' | 'broken' | 'spare';
+  tags?: string[];
   location?: string;
   source?: string;
 }
 
+export interface StockAlert {
+  part_id: number;
+  type: string;
+  name: string;
+  quantity: number;
+  min_stock: number;
+  missing: number; // Quantité à réapprovisionner
+}
+
+export interface AlertGroup {
+  location: string;
+  alerts: StockAlert[];
+}
+
+export interface Loan {
+  id: number;
+  part_id: number;
+  part_name?: string;
+  borrower: string;
+  quantity: number;
+  due_date: string; // AAAA-MM-JJ
+  notes?: string;
+  out_at: string;
+  returned_at?: string;
+  overdue: boolean;
+}
+
 export interface LocationAPIResponse {
   id: number;
   name: string;
@@ -32,9 +63,28 @@ export interface AddPartRequest {
   name: string;
   loc?: string;
   props?: any;
+  quantity?: number;
+  unit_value?: number; // Valeur unitaire estimée
+  currency?: string; // Code ISO 4217 (EUR par défaut)
   // Les photos sont envoyées comme des fichiers séparés
 }
 
+export interface UpdatePartRequest {
+  type?: string;
+  name?: string;
+  props?: any;
+}
+
+export interface BOMNode {
+  id: number;
+  type: string;
+  name: string;
+  state: string;
+  stock: number;
+  quantity: number; // Nombre d'unités dans le parent
+  children: BOMNode[];
+}
+
 export interface AddPartResponse {
   id?: number;
   error?: string;
@@ -46,6 +96,21 @@ export interface PartTypeInfo {
   description?: string;
 }
 
+export interface TemplateInfo {
+  name: string;
+  label: string; // Libellé dans la langue du navigateur (Accept-Language) ou ?lang=
+  description: string;
+  version: number;
+  min_stock: number;
+  source: 'embedded' | 'file' | 'db';
+  fields: any[];
+  name_pattern?: string; // Nom automatique si le nom est omis
+  aliases?: string[]; // Types équivalents (ex: bearing pour roulement)
+  extends?: string;
+  ancestors?: string[];
+  definition?: string; // YAML d'origine (GET /api/templates/{name})
+}
+
 
 // Types pour les réponses API avec union discriminée
 // Pattern: [error, null] | [null, T]
@@ -199,6 +264,9 @@ export const api = {
       formData.append('name', partData.name);
       if (partData.loc) formData.append('loc', partData.loc);
       if (partData.props) formData.append('props', JSON.stringify(partData.props));
+      if (partData.quantity !== undefined) formData.append('quantity', partData.quantity.toString());
+      if (partData.unit_value !== undefined) formData.append('unit_value', partData.unit_value.toString());
+      if (partData.currency) formData.append('currency', partData.currency);
 
       // Ajouter les photos
       if (photos) {
@@ -222,6 +290,97 @@ export const api = {
     }
   },
 
+  // Modification d'une pièce - PATCH fusionne les props, PUT les remplace
+  updatePart: async (id: number, changes: UpdatePartRequest, replace = false): Promise<APIResult<PartAPIResponse>> => {
+    try {
+      const response = await fetch(`${API_BASE_URL}/api/parts/${id}`, {
+        method: replace ? 'PUT' : 'PATCH',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify(changes),
+      });
+
+      if (response.ok) {
+        const data = await response.json();
+        return [null, data];
+      }
+      return [await response.text() || `HTTP ${response.status}`, null];
+    } catch (error) {
+      return [error instanceof Error ? error.message : 'Unknown error', null];
+    }
+  },
+
+  // Liste des templates (embarqués, fichiers, base) - retourne [null, TemplateInfo[]] | [string, null]
+  getTemplates: async (): Promise<APIResult<TemplateInfo[]>> => {
+    try {
+      const response = await fetch(`${API_BASE_URL}/api/templates`);
+      if (response.ok) {
+        const data = await response.json();
+        return [null, data.templates || []];
+      }
+      return [`HTTP ${response.status}`, null];
+    } catch (error) {
+      return [error instanceof Error ? error.message : 'Unknown error', null];
+    }
+  },
+
+  // Création (name absent) ou modification d'un template - définition YAML ou JSON
+  saveTemplate: async (definition: string, name?: string): Promise<APIResult<TemplateInfo>> => {
+    try {
+      const response = await fetch(`${API_BASE_URL}/api/templates${name ? `/${encodeURIComponent(name)}` : ''}`, {
+        method: name ? 'PUT' : 'POST',
+        headers: { 'Content-Type': 'application/yaml' },
+        body: definition,
+      });
+      if (response.ok) {
+        const data = await response.json();
+        return [null, data];
+      }
+      return [await response.text() || `HTTP ${response.status}`, null];
+    } catch (error) {
+      return [error instanceof Error ? error.message : 'Unknown error', null];
+    }
+  },
+
+  // Suppression d'un template enregistré en base - retourne [null, true] | [string, null]
+  deleteTemplate: async (name: string): Promise<APIResult<boolean>> => {
+    try {
+      const response = await fetch(`${API_BASE_URL}/api/templates/${encodeURIComponent(name)}`, { method: 'DELETE' });
+      if (response.ok) {
+        return [null, true];
+      }
+      return [await response.text() || `HTTP ${response.status}`, null];
+    } catch (error) {
+      return [error instanceof Error ? error.message : 'Unknown error', null];
+    }
+  },
+
+  // Mise à la corbeille d'une pièce - retourne [null, true] | [string, null]
+  deletePart: async (id: number): Promise<APIResult<boolean>> => {
+    try {
+      const response = await fetch(`${API_BASE_URL}/api/parts/${id}`, { method: 'DELETE' });
+      if (response.ok) {
+        return [null, true];
+      }
+      return [`HTTP ${response.status}`, null];
+    } catch (error) {
+      return [error instanceof Error ? error.message : 'Unknown error', null];
+    }
+  },
+
+  // Sous-composants d'une pièce (arbre de nomenclature)
+  getPartChildren: async (id: number): Promise<APIResult<BOMNode[]>> => {
+    try {
+      const response = await fetch(`${API_BASE_URL}/api/parts/${id}/children`);
+      if (response.ok) {
+        const data = await response.json();
+        return [null, data];
+      }
+      return [`HTTP ${response.status}`, null];
+    } catch (error) {
+      return [error instanceof Error ? error.message : 'Unknown error', null];
+    }
+  },
+
   // Recherche de pièces (partial HTML) - retourne [null, string] | [string, null]
   searchPartsPartial: async (query: string): Promise<APIResult<string>> => {
     try {
@@ -8,15 +8,27 @@ import { api } from "../api"
 
 interface TemplateField {
   name: string
-  type: string
-  label: string
+  type: string // text, number, checkbox, select, list
+  field_type?: string // text, number, integer, boolean, enum, list
+  label?: string
+  description?: string
   required?: boolean
   unit?: string
   options?: string[]
+  min?: number
+  max?: number
+  step?: string
+  pattern?: string
+  compute?: string // Expression d'un champ calculé par le serveur
+  override?: boolean // Champ calculé modifiable (calculé si vide)
 }
 
+// Les champs calculés ne sont saisis que s'ils acceptent une valeur manuelle
+const isEditable = (field: TemplateField) => !field.compute || field.override
+
 interface TemplateData {
   fields: TemplateField[]
+  name_pattern?: string // Nom généré par le serveur si le nom est laissé vide
 }
 
 export const prerender = false
@@ -93,7 +105,7 @@ export default function AddPartForm() {
       }
 
       if (templateData && templateData.fields) {
-        setTemplate({ fields: templateData.fields })
+        setTemplate({ fields: templateData.fields, name_pattern: templateData.name_pattern })
       } else {
         setTemplate(null)
       }
@@ -192,9 +204,16 @@ export default function AddPartForm() {
     try {
       const props: Record<string, any> = {}
       if (template?.fields) {
-        template.fields.forEach(field => {
+        template.fields.filter(isEditable).forEach(field => {
           const value = dynamicFields[field.name]
-          if (value) {
+          if (field.type === 'checkbox') {
+            props[field.name] = value === 'true'
+          } else if (field.type === 'list') {
+            const items = (value || '').split(',').map(v => v.trim()).filter(v => v)
+            if (items.length > 0) {
+              props[field.name] = items
+            }
+          } else if (value) {
             props[field.name] = value
           }
         })
@@ -288,15 +307,15 @@ export default function AddPartForm() {
           {/* Nom */}
           <div>
             <label className="block text-sm font-medium mb-2">
-              Nom de la pièce <span className="text-destructive">*</span>
+              Nom de la pièce {!template?.name_pattern && <span className="text-destructive">*</span>}
             </label>
             <Input
               type="text"
               value={formData.name}
               onChange={(e) => handleFieldChange('name', e.target.value)}
-              placeholder="Ex: Moteur 12V, Roulement SKF..."
+              placeholder={template?.name_pattern ? `Automatique : ${template.name_pattern}` : "Ex: Moteur 12V, Roulement SKF..."}
               className="h-12 text-base"
-              required
+              required={!template?.name_pattern}
             />
           </div>
         </div>
@@ -309,7 +328,7 @@ export default function AddPartForm() {
               Pour "{availableTypes.find(t => t.value === formData.type)?.label}"
             </p>
 
-            {template.fields.map(field => (
+            {template.fields.filter(isEditable).map(field => (
               <div key={field.name}>
                 <label className="block text-sm font-medium mb-2">
                   {field.label || field.name}
@@ -317,7 +336,14 @@ export default function AddPartForm() {
                   {field.required && <span className="text-destructive"> *</span>}
                 </label>
 
-                {field.type === 'select' && field.options ? (
+                {field.type === 'checkbox' ? (
+                  <input
+                    type="checkbox"
+                    checked={dynamicFields[field.name] === 'true'}
+                    onChange={(e) => handleDynamicFieldChange(field.name, e.target.checked ? 'true' : '')}
+                    className="h-5 w-5"
+                  />
+                ) : field.type === 'select' && field.options ? (
                   <select
                     value={dynamicFields[field.name] || ''}
                     onChange={(e) => handleDynamicFieldChange(field.name, e.target.value)}
@@ -334,11 +360,18 @@ export default function AddPartForm() {
                     type={field.type === 'number' ? 'number' : 'text'}
                     value={dynamicFields[field.name] || ''}
                     onChange={(e) => handleDynamicFieldChange(field.name, e.target.value)}
-                    placeholder={field.label || field.name}
+                    placeholder={field.type === 'list' ? (field.options?.join(', ') || 'valeur1, valeur2') : (field.label || field.name)}
+                    min={field.min}
+                    max={field.max}
+                    step={field.step}
+                    pattern={field.pattern}
                     className="h-12 text-base"
                     required={field.required}
                   />
                 )}
+                {field.compute && (
+                  <p className="text-xs text-muted-foreground mt-1">Calculé si vide : {field.compute}</p>
+                )}
               </div>
             ))}
           </div>
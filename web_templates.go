@@ -15,6 +15,8 @@ var (
 	tplScan     *template.Template
 	tplLocation *template.Template
 	tplAdd      *template.Template
+
+	tplTemplateFields *template.Template
 )
 
 func mustLoadWebTemplates() {
@@ -24,4 +26,5 @@ func mustLoadWebTemplates() {
 	tplScan = template.Must(template.ParseFS(webFS, "web/scan.html"))
 	tplLocation = template.Must(template.ParseFS(webFS, "web/location.html"))
 	tplAdd = template.Must(template.ParseFS(webFS, "web/add.html"))
+	tplTemplateFields = template.Must(template.ParseFS(webFS, "web/partials_template_fields.html"))
 }